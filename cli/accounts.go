@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const accountsUsage = `usage: gallery accounts <subcommand> [flags]
+
+subcommands:
+  register -username NAME [-password PASSWORD]  create an account
+  get ID                                        show an account
+  me                                            show the account of the profile
+`
+
+func (c *CLI) accounts(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(c.Stderr, accountsUsage)
+		return errors.New("missing subcommand")
+	}
+
+	switch args[0] {
+	case "register":
+		flags, common := c.newFlagSet("accounts register", "accounts register [flags] -username NAME")
+		username := flags.String("username", "", "username of the new account")
+		password := flags.String("password", "", "password of the new account, read from stdin by default")
+		if _, err := parse(flags, args[1:]); err != nil {
+			return err
+		} else if *username == "" {
+			flags.Usage()
+			return errors.New("-username is required")
+		}
+
+		pass, err := c.readPassword(*password)
+		if err != nil {
+			return err
+		}
+		cl, err := c.client(common)
+		if err != nil {
+			return err
+		}
+		if account, err := cl.Accounts().Create(ctx, *username, pass); err != nil {
+			return err
+		} else {
+			return writeAccount(c.Stdout, common.output, *account)
+		}
+	case "get":
+		flags, common := c.newFlagSet("accounts get", "accounts get [flags] ID")
+		positional, err := parse(flags, args[1:])
+		if err != nil {
+			return err
+		}
+		id, err := parseId(flags, positional)
+		if err != nil {
+			return err
+		}
+
+		cl, err := c.client(common)
+		if err != nil {
+			return err
+		}
+		if account, err := cl.Accounts().Get(ctx, id); err != nil {
+			return err
+		} else {
+			return writeAccount(c.Stdout, common.output, *account)
+		}
+	case "me":
+		flags, common := c.newFlagSet("accounts me", "accounts me [flags]")
+		if _, err := parse(flags, args[1:]); err != nil {
+			return err
+		}
+
+		cl, err := c.client(common)
+		if err != nil {
+			return err
+		}
+		if account, err := cl.Accounts().Me(ctx); err != nil {
+			return err
+		} else {
+			return writeAccount(c.Stdout, common.output, *account)
+		}
+	default:
+		fmt.Fprint(c.Stderr, accountsUsage)
+		return fmt.Errorf("unknown subcommand '%s'", args[0])
+	}
+}
@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/nafiz1001/gallery-go/client"
+	"github.com/nafiz1001/gallery-go/dto"
+)
+
+const artsUsage = `usage: gallery arts <subcommand> [flags]
+
+subcommands:
+  list                                    list every art
+  get ID                                  show an art
+  create -title TITLE [-quantity N]       create an art authored by the profile's account
+  update ID [-title TITLE] [-quantity N]  change an art of the profile's account
+  delete ID                               delete an art of the profile's account
+`
+
+func (c *CLI) arts(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(c.Stderr, artsUsage)
+		return errors.New("missing subcommand")
+	}
+
+	switch args[0] {
+	case "list":
+		flags, common := c.newFlagSet("arts list", "arts list [flags]")
+		pageSize := flags.Int("page-size", client.DefaultPageSize, "number of arts fetched per request")
+		if _, err := parse(flags, args[1:]); err != nil {
+			return err
+		}
+
+		cl, err := c.client(common)
+		if err != nil {
+			return err
+		}
+		arts := []dto.ArtDto{}
+		it := cl.Arts().Iter(ctx, *pageSize)
+		for it.Next() {
+			arts = append(arts, it.Art())
+		}
+		if err := it.Err(); err != nil {
+			return err
+		}
+		return writeArts(c.Stdout, common.output, arts, arts...)
+	case "get", "delete":
+		flags, common := c.newFlagSet("arts "+args[0], "arts "+args[0]+" [flags] ID")
+		positional, err := parse(flags, args[1:])
+		if err != nil {
+			return err
+		}
+		id, err := parseId(flags, positional)
+		if err != nil {
+			return err
+		}
+
+		cl, err := c.client(common)
+		if err != nil {
+			return err
+		}
+		var art *dto.ArtDto
+		if args[0] == "get" {
+			art, err = cl.Arts().Get(ctx, id)
+		} else {
+			art, err = cl.Arts().Delete(ctx, id)
+		}
+		if err != nil {
+			return err
+		}
+		return writeArts(c.Stdout, common.output, *art, *art)
+	case "create":
+		flags, common := c.newFlagSet("arts create", "arts create [flags] -title TITLE")
+		title, quantity := artFlags(flags)
+		if _, err := parse(flags, args[1:]); err != nil {
+			return err
+		} else if *title == "" {
+			flags.Usage()
+			return errors.New("-title is required")
+		}
+
+		cl, err := c.client(common)
+		if err != nil {
+			return err
+		}
+		if art, err := cl.Arts().Create(ctx, dto.ArtDto{Title: *title, Quantity: *quantity}); err != nil {
+			return err
+		} else {
+			return writeArts(c.Stdout, common.output, *art, *art)
+		}
+	case "update":
+		flags, common := c.newFlagSet("arts update", "arts update [flags] ID")
+		title, quantity := artFlags(flags)
+		positional, err := parse(flags, args[1:])
+		if err != nil {
+			return err
+		}
+		id, err := parseId(flags, positional)
+		if err != nil {
+			return err
+		}
+
+		cl, err := c.client(common)
+		if err != nil {
+			return err
+		}
+		// the response only has the fields that were sent, so the art is fetched again
+		if _, err := cl.Arts().Update(ctx, dto.ArtDto{Id: id, Title: *title, Quantity: *quantity}); err != nil {
+			return err
+		} else if art, err := cl.Arts().Get(ctx, id); err != nil {
+			return err
+		} else {
+			return writeArts(c.Stdout, common.output, *art, *art)
+		}
+	default:
+		fmt.Fprint(c.Stderr, artsUsage)
+		return fmt.Errorf("unknown subcommand '%s'", args[0])
+	}
+}
+
+func artFlags(flags *flag.FlagSet) (*string, *int) {
+	title := flags.String("title", "", "title of the art")
+	quantity := flags.Int("quantity", 0, "quantity of the art")
+	return title, quantity
+}
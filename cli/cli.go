@@ -0,0 +1,167 @@
+// Package cli implements the commands of the gallery binary that call a running gallery server,
+// such as 'gallery arts list', through the client package.
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/nafiz1001/gallery-go/client"
+)
+
+// Server used when neither -server, $GALLERY_SERVER nor the profile name one.
+const DefaultServer = "http://localhost:8080"
+
+type CLI struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Path of the configuration file with the profiles, see DefaultConfigPath.
+	ConfigPath string
+}
+
+func (c *CLI) Init() error {
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+
+	if path, err := DefaultConfigPath(); err != nil {
+		return err
+	} else {
+		c.ConfigPath = path
+		return nil
+	}
+}
+
+// Runs the login, accounts or arts command with its arguments.
+// Asking for help with -h returns flag.ErrHelp.
+func (c *CLI) Run(ctx context.Context, command string, args []string) error {
+	switch command {
+	case "login":
+		return c.login(ctx, args)
+	case "accounts":
+		return c.accounts(ctx, args)
+	case "arts":
+		return c.arts(ctx, args)
+	default:
+		return fmt.Errorf("unknown command '%s'", command)
+	}
+}
+
+// Flags shared by every command.
+type commonFlags struct {
+	server  string
+	profile string
+	output  string
+}
+
+func (c *CLI) newFlagSet(name string, usage string) (*flag.FlagSet, *commonFlags) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(c.Stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: gallery %s\n", usage)
+		flags.PrintDefaults()
+	}
+
+	common := &commonFlags{}
+	flags.StringVar(&common.server, "server", "", "URL of the gallery server, $GALLERY_SERVER or the one of the profile by default")
+	flags.StringVar(&common.profile, "profile", "", "profile saved by 'gallery login', the last one logged in by default")
+	flags.StringVar(&common.output, "output", "table", "output format: "+strings.Join(formats, ", "))
+	return flags, common
+}
+
+// Parses args with flags, which may come before, between or after the positional arguments.
+// Returns the positional arguments.
+func parse(flags *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		} else if flags.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+// Parses the id given as the only positional argument.
+func parseId(flags *flag.FlagSet, positional []string) (uint, error) {
+	if len(positional) != 1 {
+		flags.Usage()
+		return 0, errors.New("expected exactly one id")
+	} else if id, err := strconv.ParseUint(positional[0], 10, 32); err != nil {
+		return 0, fmt.Errorf("'%s' is not an id", positional[0])
+	} else {
+		return uint(id), nil
+	}
+}
+
+// Gets the profile named by the flags along with its name.
+// $GALLERY_SERVER, $GALLERY_USERNAME and $GALLERY_PASSWORD override the saved one.
+func (c *CLI) profile(common *commonFlags) (string, Profile, error) {
+	config, err := LoadConfig(c.ConfigPath)
+	if err != nil {
+		return "", Profile{}, err
+	}
+
+	name := common.profile
+	if name == "" {
+		name = config.Current
+	}
+	if name == "" {
+		name = "default"
+	}
+
+	profile := config.Profiles[name]
+	if server := os.Getenv("GALLERY_SERVER"); server != "" {
+		profile.Server = server
+	}
+	if username := os.Getenv("GALLERY_USERNAME"); username != "" {
+		profile.Username = username
+		profile.Password = os.Getenv("GALLERY_PASSWORD")
+	}
+	if common.server != "" {
+		profile.Server = common.server
+	}
+	if profile.Server == "" {
+		profile.Server = DefaultServer
+	}
+	return name, profile, nil
+}
+
+// Creates a client of the server of the profile named by the flags, authenticated as its account.
+func (c *CLI) client(common *commonFlags) (*client.Client, error) {
+	if _, profile, err := c.profile(common); err != nil {
+		return nil, err
+	} else {
+		return newClient(profile), nil
+	}
+}
+
+func newClient(profile Profile) *client.Client {
+	return client.New(profile.Server, client.BasicAuth{Username: profile.Username, Password: profile.Password})
+}
+
+// Reads a password from stdin when it isn't given with -password.
+// The input is not hidden, pipe the password in to keep it off the screen.
+func (c *CLI) readPassword(password string) (string, error) {
+	if password != "" {
+		return password, nil
+	}
+
+	fmt.Fprint(c.Stderr, "Password: ")
+	line, err := bufio.NewReader(c.Stdin).ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", errors.New("no password given")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
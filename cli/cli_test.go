@@ -0,0 +1,115 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nafiz1001/gallery-go/cli"
+	"github.com/nafiz1001/gallery-go/client"
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/handler/handlertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// Runs a command and gets what it wrote to stdout.
+func Run(t *testing.T, c *cli.CLI, stdin string, args ...string) (string, error) {
+	var stdout bytes.Buffer
+	c.Stdin = strings.NewReader(stdin)
+	c.Stdout = &stdout
+	err := c.Run(context.Background(), args[0], args[1:])
+	return stdout.String(), err
+}
+
+func TestCLI(t *testing.T) {
+	server := handlertest.NewServer(t)
+
+	c := &cli.CLI{Stderr: io.Discard, ConfigPath: filepath.Join(t.TempDir(), "gallery", "config.yaml")}
+
+	// register an account without a profile
+	out, err := Run(t, c, "", "accounts", "register", "-server", server.URL, "-username", "username", "-password", "password", "-output", "json")
+	require.NoError(t, err)
+	var account dto.AccountDto
+	require.NoError(t, json.Unmarshal([]byte(out), &account))
+	assert.Equal(t, "username", account.Username)
+	assert.Empty(t, account.Password)
+
+	// wrong credentials aren't saved
+	_, err = Run(t, c, "wrong\n", "login", "-server", server.URL, "-username", "username")
+	assert.ErrorIs(t, err, client.ErrUnauthorized)
+	_, err = os.Stat(c.ConfigPath)
+	assert.True(t, os.IsNotExist(err))
+
+	// the password is read from stdin
+	_, err = Run(t, c, "password\n", "login", "-server", server.URL, "-username", "username")
+	require.NoError(t, err)
+	config, err := cli.LoadConfig(c.ConfigPath)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "default", config.Current)
+		assert.Equal(t, cli.Profile{Server: server.URL, Username: "username", Password: "password"}, config.Profiles["default"])
+	}
+	if info, err := os.Stat(c.ConfigPath); assert.NoError(t, err) {
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+
+	// the commands below use the saved profile
+	out, err = Run(t, c, "", "accounts", "me")
+	if assert.NoError(t, err) {
+		assert.Contains(t, out, "USERNAME")
+		assert.Contains(t, out, "username")
+	}
+
+	out, err = Run(t, c, "", "arts", "create", "-title", "title", "-quantity", "2", "-output", "json")
+	require.NoError(t, err)
+	var art dto.ArtDto
+	require.NoError(t, json.Unmarshal([]byte(out), &art))
+	assert.Equal(t, account.Id, art.AuthorId)
+
+	// flags may follow the id
+	out, err = Run(t, c, "", "arts", "update", fmt.Sprint(art.Id), "-title", "new title", "-output", "json")
+	if assert.NoError(t, err) {
+		var updated dto.ArtDto
+		require.NoError(t, json.Unmarshal([]byte(out), &updated))
+		assert.Equal(t, dto.ArtDto{Id: art.Id, Title: "new title", Quantity: 2, AuthorId: account.Id}, updated)
+	}
+
+	out, err = Run(t, c, "", "arts", "list", "-output", "yaml", "-page-size", "1")
+	if assert.NoError(t, err) {
+		var arts []map[string]interface{}
+		require.NoError(t, yaml.Unmarshal([]byte(out), &arts))
+		if assert.Len(t, arts, 1) {
+			assert.Equal(t, "new title", arts[0]["title"])
+			assert.Equal(t, account.Id, uint(arts[0]["author_id"].(int)))
+		}
+	}
+
+	out, err = Run(t, c, "", "arts", "list")
+	if assert.NoError(t, err) {
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		if assert.Len(t, lines, 2) {
+			assert.Equal(t, []string{"ID", "TITLE", "QUANTITY", "AUTHOR"}, strings.Fields(lines[0]))
+			assert.Contains(t, lines[1], "new title")
+		}
+	}
+
+	_, err = Run(t, c, "", "arts", "delete", fmt.Sprint(art.Id))
+	assert.NoError(t, err)
+	_, err = Run(t, c, "", "arts", "get", fmt.Sprint(art.Id))
+	assert.ErrorIs(t, err, client.ErrNotFound)
+
+	// usage errors
+	_, err = Run(t, c, "", "arts", "list", "-output", "xml")
+	assert.Error(t, err)
+	_, err = Run(t, c, "", "arts", "get")
+	assert.Error(t, err)
+	_, err = Run(t, c, "", "arts", "create")
+	assert.Error(t, err)
+}
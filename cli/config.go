@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Server and credentials the commands use.
+// The password is stored in clear text because the API only supports basic authentication,
+// which is why the configuration file is only readable by its owner.
+type Profile struct {
+	Server   string `yaml:"server"`
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+}
+
+// Profiles saved by 'gallery login'.
+type Config struct {
+	// Profile used when -profile isn't given.
+	Current  string             `yaml:"current"`
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// Gets the path of the configuration file: $GALLERY_CONFIG, or gallery/config.yaml in the user configuration directory.
+func DefaultConfigPath() (string, error) {
+	if path := os.Getenv("GALLERY_CONFIG"); path != "" {
+		return path, nil
+	} else if dir, err := os.UserConfigDir(); err != nil {
+		return "", err
+	} else {
+		return filepath.Join(dir, "gallery", "config.yaml"), nil
+	}
+}
+
+// Reads the configuration at path. A missing file is an empty configuration.
+func LoadConfig(path string) (Config, error) {
+	config := Config{Profiles: map[string]Profile{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return config, nil
+	} else if err != nil {
+		return config, err
+	} else if err := yaml.Unmarshal(data, &config); err != nil {
+		return config, err
+	}
+
+	if config.Profiles == nil {
+		config.Profiles = map[string]Profile{}
+	}
+	return config, nil
+}
+
+// Writes the configuration to path, creating its directory if needed.
+func (config Config) Save(path string) error {
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	} else if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	} else {
+		return os.WriteFile(path, data, 0600)
+	}
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Checks credentials against the server and saves them as a profile, which becomes the current one.
+func (c *CLI) login(ctx context.Context, args []string) error {
+	flags, common := c.newFlagSet("login", "login [flags] -username NAME")
+	username := flags.String("username", "", "username of the account")
+	password := flags.String("password", "", "password of the account, read from stdin by default")
+	if _, err := parse(flags, args); err != nil {
+		return err
+	} else if *username == "" {
+		flags.Usage()
+		return errors.New("-username is required")
+	}
+
+	name, profile, err := c.profile(common)
+	if err != nil {
+		return err
+	}
+	profile.Username = *username
+	if profile.Password, err = c.readPassword(*password); err != nil {
+		return err
+	}
+
+	account, err := newClient(profile).Accounts().Me(ctx)
+	if err != nil {
+		return err
+	}
+
+	config, err := LoadConfig(c.ConfigPath)
+	if err != nil {
+		return err
+	}
+	config.Profiles[name] = profile
+	config.Current = name
+	if err := config.Save(c.ConfigPath); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.Stderr, "Logged in to %s as '%s' (profile '%s')\n", profile.Server, account.Username, name)
+	return nil
+}
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats of the output of the commands, chosen with -output.
+var formats = []string{"table", "json", "yaml"}
+
+// Writes v in format. Tables have the header and one line per row,
+// while JSON and YAML documents have the fields of the DTOs in v.
+func writeOutput(w io.Writer, format string, v interface{}, header []string, rows [][]string) error {
+	switch format {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, row := range rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case "yaml":
+		// going through JSON keeps the field names of the DTOs
+		var doc interface{}
+		if data, err := json.Marshal(v); err != nil {
+			return err
+		} else if err := json.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(doc); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		return fmt.Errorf("unknown output format '%s', use one of %s", format, strings.Join(formats, ", "))
+	}
+}
+
+func writeArts(w io.Writer, format string, v interface{}, arts ...dto.ArtDto) error {
+	rows := [][]string{}
+	for _, art := range arts {
+		rows = append(rows, []string{
+			strconv.FormatUint(uint64(art.Id), 10),
+			art.Title,
+			strconv.Itoa(art.Quantity),
+			strconv.FormatUint(uint64(art.AuthorId), 10),
+		})
+	}
+	return writeOutput(w, format, v, []string{"ID", "TITLE", "QUANTITY", "AUTHOR"}, rows)
+}
+
+func writeAccount(w io.Writer, format string, account dto.AccountDto) error {
+	account.Password = ""
+	rows := [][]string{{
+		strconv.FormatUint(uint64(account.Id), 10),
+		account.Username,
+		strconv.FormatBool(account.Admin),
+	}}
+	return writeOutput(w, format, account, []string{"ID", "USERNAME", "ADMIN"}, rows)
+}
@@ -0,0 +1,40 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/nafiz1001/gallery-go/dto"
+)
+
+// Calls the /accounts endpoints, which need no authentication.
+type AccountsClient struct {
+	client *Client
+}
+
+// Signs up a new account. A taken username fails with ErrConflict.
+func (c *AccountsClient) Create(ctx context.Context, username string, password string) (*dto.AccountDto, error) {
+	var account dto.AccountDto
+	if err := c.client.do(ctx, http.MethodPost, "/accounts", nil, dto.AccountDto{Username: username, Password: password}, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (c *AccountsClient) Get(ctx context.Context, id uint) (*dto.AccountDto, error) {
+	var account dto.AccountDto
+	if err := c.client.do(ctx, http.MethodGet, fmt.Sprintf("/accounts/%d", id), nil, nil, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// Gets the account the client authenticates as. Wrong credentials fail with ErrUnauthorized.
+func (c *AccountsClient) Me(ctx context.Context) (*dto.AccountDto, error) {
+	var account dto.AccountDto
+	if err := c.client.do(ctx, http.MethodGet, "/accounts/me", nil, nil, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
@@ -0,0 +1,137 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/nafiz1001/gallery-go/dto"
+)
+
+// Number of arts fetched per request by List.
+const DefaultPageSize = 100
+
+// Calls the /arts endpoints. Mutations need the client to authenticate as the author.
+type ArtsClient struct {
+	client *Client
+}
+
+// Lists every art by id, fetching them page by page.
+func (c *ArtsClient) List(ctx context.Context) ([]dto.ArtDto, error) {
+	arts := []dto.ArtDto{}
+	it := c.Iter(ctx, DefaultPageSize)
+	for it.Next() {
+		arts = append(arts, it.Art())
+	}
+	return arts, it.Err()
+}
+
+// Iterates over every art by id, fetching pageSize of them per request.
+// A pageSize of 0 stands for DefaultPageSize.
+func (c *ArtsClient) Iter(ctx context.Context, pageSize int) *ArtIterator {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	return &ArtIterator{ctx: ctx, arts: c, pageSize: pageSize}
+}
+
+func (c *ArtsClient) Get(ctx context.Context, id uint) (*dto.ArtDto, error) {
+	var art dto.ArtDto
+	if err := c.client.do(ctx, http.MethodGet, fmt.Sprintf("/arts/%d", id), nil, nil, &art); err != nil {
+		return nil, err
+	}
+	return &art, nil
+}
+
+// Creates an art authored by the authenticated account.
+func (c *ArtsClient) Create(ctx context.Context, art dto.ArtDto) (*dto.ArtDto, error) {
+	var created dto.ArtDto
+	if err := c.client.do(ctx, http.MethodPost, "/arts", nil, art, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// Updates the art with the id of art. Zero fields are left untouched.
+func (c *ArtsClient) Update(ctx context.Context, art dto.ArtDto) (*dto.ArtDto, error) {
+	var updated dto.ArtDto
+	if err := c.client.do(ctx, http.MethodPut, fmt.Sprintf("/arts/%d", art.Id), nil, art, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (c *ArtsClient) Delete(ctx context.Context, id uint) (*dto.ArtDto, error) {
+	var deleted dto.ArtDto
+	if err := c.client.do(ctx, http.MethodDelete, fmt.Sprintf("/arts/%d", id), nil, nil, &deleted); err != nil {
+		return nil, err
+	}
+	return &deleted, nil
+}
+
+// Iterates over arts page by page:
+//
+//	it := c.Arts().Iter(ctx, 100)
+//	for it.Next() {
+//		art := it.Art()
+//	}
+//	if err := it.Err(); err != nil { ... }
+type ArtIterator struct {
+	ctx      context.Context
+	arts     *ArtsClient
+	pageSize int
+
+	page []dto.ArtDto
+	art  dto.ArtDto
+	// Id of the last art fetched.
+	after uint
+	done  bool
+	err   error
+}
+
+// Moves to the next art, fetching the next page if needed.
+// Returns false once every art is visited or a request failed, see Err.
+func (it *ArtIterator) Next() bool {
+	if len(it.page) == 0 && !it.done {
+		it.fetch()
+	}
+	if len(it.page) == 0 {
+		return false
+	}
+
+	it.art, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Gets the art Next moved to.
+func (it *ArtIterator) Art() dto.ArtDto {
+	return it.art
+}
+
+// Gets the error that stopped the iteration, if any.
+func (it *ArtIterator) Err() error {
+	return it.err
+}
+
+func (it *ArtIterator) fetch() {
+	query := url.Values{}
+	query.Set("after", strconv.FormatUint(uint64(it.after), 10))
+	query.Set("limit", strconv.Itoa(it.pageSize))
+
+	var page []dto.ArtDto
+	if err := it.arts.client.do(it.ctx, http.MethodGet, "/arts", query, nil, &page); err != nil {
+		it.err = err
+		it.done = true
+		return
+	}
+
+	it.page = page
+	if len(page) < it.pageSize {
+		it.done = true
+	}
+	if len(page) > 0 {
+		it.after = page[len(page)-1].Id
+	}
+}
@@ -0,0 +1,156 @@
+// Package client is a typed client of the gallery API.
+//
+//	c := client.New("http://localhost:8080", client.BasicAuth{Username: "user", Password: "secret"})
+//	art, err := c.Arts().Create(ctx, dto.ArtDto{Title: "title", Quantity: 1})
+//	if errors.Is(err, client.ErrUnauthorized) { ... }
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Credentials of an account, sent with HTTP basic authentication.
+// The zero value sends none, which is enough for reading.
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+// Client of a gallery server. Its exported fields can be changed before it is used.
+type Client struct {
+	baseURL string
+	auth    BasicAuth
+
+	// Sends the requests.
+	HTTPClient *http.Client
+	// Most times a request is retried. Requests are retried when they are rate limited,
+	// and idempotent requests also when the server is unavailable or can't be reached.
+	MaxRetries int
+	// Wait before the first retry, doubled for every following one unless the server asks for a wait with Retry-After.
+	RetryDelay time.Duration
+	// Longest wait before a retry. A request whose Retry-After asks for longer fails instead.
+	MaxRetryDelay time.Duration
+}
+
+// Creates a client of the gallery served at baseURL, e.g. http://localhost:8080.
+func New(baseURL string, auth BasicAuth) *Client {
+	return &Client{
+		baseURL:       strings.TrimSuffix(baseURL, "/"),
+		auth:          auth,
+		HTTPClient:    http.DefaultClient,
+		MaxRetries:    3,
+		RetryDelay:    100 * time.Millisecond,
+		MaxRetryDelay: 10 * time.Second,
+	}
+}
+
+func (c *Client) Arts() *ArtsClient {
+	return &ArtsClient{client: c}
+}
+
+func (c *Client) Accounts() *AccountsClient {
+	return &AccountsClient{client: c}
+}
+
+// Sends a request with body encoded as JSON, if not nil, and decodes the response into out.
+// Failed requests are retried as described by MaxRetries.
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, body interface{}, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, path, query, payload)
+		if err == nil {
+			defer resp.Body.Close()
+			return json.NewDecoder(resp.Body).Decode(out)
+		}
+
+		wait, retry := c.retry(ctx, method, err, attempt)
+		if !retry {
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Sends a single request. A response with an error status is returned as an *Error.
+func (c *Client) send(ctx context.Context, method string, path string, query url.Values, payload []byte) (*http.Response, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.auth.Username != "" {
+		req.SetBasicAuth(c.auth.Username, c.auth.Password)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	} else if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, newError(resp)
+	} else {
+		return resp, nil
+	}
+}
+
+// Decides whether the attempt-th try of a request that failed with err is retried, and after how long.
+func (c *Client) retry(ctx context.Context, method string, err error, attempt int) (time.Duration, bool) {
+	if attempt >= c.MaxRetries || ctx.Err() != nil {
+		return 0, false
+	}
+
+	idempotent := method != http.MethodPost
+	wait := c.RetryDelay << attempt
+	if wait > c.MaxRetryDelay {
+		wait = c.MaxRetryDelay
+	}
+
+	if apiErr, ok := err.(*Error); !ok {
+		// the server couldn't be reached, so the request may or may not have been handled
+		return wait, idempotent
+	} else if apiErr.StatusCode == http.StatusTooManyRequests {
+		// rate limited requests are rejected before they are handled
+		if apiErr.RetryAfter > 0 {
+			wait = apiErr.RetryAfter
+		}
+		return wait, wait <= c.MaxRetryDelay
+	} else if apiErr.StatusCode == http.StatusBadGateway || apiErr.StatusCode == http.StatusServiceUnavailable || apiErr.StatusCode == http.StatusGatewayTimeout {
+		return wait, idempotent
+	} else {
+		return 0, false
+	}
+}
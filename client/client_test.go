@@ -0,0 +1,161 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/client"
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/handler/handlertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	server := handlertest.NewServer(t)
+	ctx := context.Background()
+
+	anonymous := client.New(server.URL, client.BasicAuth{})
+	account, err := anonymous.Accounts().Create(ctx, "username", "password")
+	require.NoError(t, err)
+	assert.Equal(t, "username", account.Username)
+
+	got, err := anonymous.Accounts().Get(ctx, account.Id)
+	if assert.NoError(t, err) {
+		assert.Equal(t, account.Id, got.Id)
+	}
+
+	_, err = anonymous.Accounts().Create(ctx, "username", "other")
+	assert.ErrorIs(t, err, client.ErrConflict)
+
+	// mutations need credentials
+	_, err = anonymous.Arts().Create(ctx, dto.ArtDto{Title: "title", Quantity: 1})
+	assert.ErrorIs(t, err, client.ErrUnauthorized)
+	var apiErr *client.Error
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+	}
+
+	_, err = client.New(server.URL, client.BasicAuth{Username: "username", Password: "wrong"}).Accounts().Me(ctx)
+	assert.ErrorIs(t, err, client.ErrUnauthorized)
+
+	c := client.New(server.URL, client.BasicAuth{Username: "username", Password: "password"})
+	me, err := c.Accounts().Me(ctx)
+	if assert.NoError(t, err) {
+		assert.Equal(t, account.Id, me.Id)
+		assert.Empty(t, me.Password)
+	}
+
+	created := []dto.ArtDto{}
+	for _, title := range []string{"a", "b", "c", "d", "e"} {
+		art, err := c.Arts().Create(ctx, dto.ArtDto{Title: title, Quantity: 1})
+		require.NoError(t, err)
+		assert.Equal(t, account.Id, art.AuthorId)
+		created = append(created, *art)
+	}
+
+	// pages shorter than the number of arts
+	it := c.Arts().Iter(ctx, 2)
+	visited := []dto.ArtDto{}
+	for it.Next() {
+		visited = append(visited, it.Art())
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, created, visited)
+
+	arts, err := anonymous.Arts().List(ctx)
+	if assert.NoError(t, err) {
+		assert.Equal(t, created, arts)
+	}
+
+	updated, err := c.Arts().Update(ctx, dto.ArtDto{Id: created[0].Id, Title: "new title"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "new title", updated.Title)
+	}
+	_, err = c.Arts().Update(ctx, dto.ArtDto{Id: created[0].Id, Quantity: -1})
+	assert.ErrorIs(t, err, client.ErrInvalid)
+
+	_, err = c.Arts().Delete(ctx, created[0].Id)
+	assert.NoError(t, err)
+	_, err = c.Arts().Get(ctx, created[0].Id)
+	assert.ErrorIs(t, err, client.ErrNotFound)
+}
+
+func TestRetry(t *testing.T) {
+	var attempts int32
+	var failures int32
+	var status int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) <= atomic.LoadInt32(&failures) {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "try again", status)
+		} else {
+			w.Write([]byte(`{"id":1,"title":"title"}`))
+		}
+	}))
+	defer server.Close()
+
+	c := client.New(server.URL, client.BasicAuth{Username: "username", Password: "password"})
+	c.RetryDelay = time.Millisecond
+	ctx := context.Background()
+
+	reset := func(s int, f int32) {
+		status = s
+		atomic.StoreInt32(&attempts, 0)
+		atomic.StoreInt32(&failures, f)
+	}
+
+	// rate limited requests are retried, even when they aren't idempotent
+	reset(http.StatusTooManyRequests, 2)
+	_, err := c.Arts().Create(ctx, dto.ArtDto{Title: "title"})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+
+	// unavailable servers are only retried for idempotent requests
+	reset(http.StatusServiceUnavailable, 1)
+	_, err = c.Arts().Get(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+
+	reset(http.StatusServiceUnavailable, 1)
+	_, err = c.Arts().Create(ctx, dto.ArtDto{Title: "title"})
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+
+	// retries run out
+	reset(http.StatusTooManyRequests, 10)
+	_, err = c.Arts().Get(ctx, 1)
+	assert.ErrorIs(t, err, client.ErrRateLimited)
+	assert.Equal(t, int32(c.MaxRetries+1), atomic.LoadInt32(&attempts))
+
+	// other errors aren't retried
+	reset(http.StatusNotFound, 1)
+	_, err = c.Arts().Get(ctx, 1)
+	assert.ErrorIs(t, err, client.ErrNotFound)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+}
+
+func TestRetryAfter(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, "too many requests", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	// the server asks for a longer wait than the client accepts
+	c := client.New(server.URL, client.BasicAuth{})
+	_, err := c.Arts().Get(context.Background(), 1)
+
+	var apiErr *client.Error
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, time.Minute, apiErr.RetryAfter)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+}
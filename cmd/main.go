@@ -1,38 +1,326 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/nafiz1001/gallery-go/cli"
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/metrics"
 	"github.com/nafiz1001/gallery-go/model"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 
 	"github.com/nafiz1001/gallery-go/handler"
 )
 
+const usage = `usage: gallery [command] [flags]
+
+commands:
+  serve     run the gallery API (default)
+  migrate   apply or revert schema migrations
+  backup    copy the database to a SQLite file or a portable JSON dump
+  restore   replace the database with a backup
+  login     save the server and credentials of a profile
+  accounts  register and look up accounts
+  arts      list, create, update and delete arts
+
+Run 'gallery <command> -h' for the flags of a command.
+`
+
 func main() {
-	gormDB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "serve":
+		serve(args)
+	case "migrate":
+		migrate(args)
+	case "backup":
+		backup(args)
+	case "restore":
+		restore(args)
+	case "login", "accounts", "arts":
+		run(command, args)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func openDB(dsn string) *model.DB {
+	gormDB, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: &model.Logger{
+			Writer:        os.Stderr,
+			Level:         logger.Warn,
+			SlowThreshold: 200 * time.Millisecond,
+		},
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	h := handler.GalleryHandler{}
-	err = h.Init(&model.DB{
+	return &model.DB{
 		GormDB: gormDB,
-	})
+	}
+}
+
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	dsn := flags.String("db", "file:gallery.db?_foreign_keys=true", "SQLite data source name")
+	addr := flags.String("addr", "localhost:8080", "address of the gallery API")
+	adminAddr := flags.String("admin-addr", "localhost:9090", "address of the admin server exposing /metrics")
+	backupDir := flags.String("backup-dir", "", "directory POST /admin/backups writes to, backups are disabled if empty")
+	flags.Parse(args)
+
+	db := openDB(*dsn)
+	if err := db.CheckSchema(context.Background()); err != nil {
+		log.Fatalf("%s: run 'gallery migrate up' first", err)
+	} else if err := db.CheckForeignKeys(context.Background()); err != nil {
+		log.Fatalf("%s: add _foreign_keys=true to -db", err)
+	}
+
+	repos, err := db.Repositories()
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	registry := &metrics.Registry{}
+	h := handler.GalleryHandler{
+		Metrics:        registry,
+		RequestTimeout: 10 * time.Second,
+		BackupDir:      *backupDir,
+	}
+	err = h.Init(repos)
+	if err != nil {
+		log.Fatal(err)
+	}
+	go h.Webhooks.Run(context.Background())
+
+	if username, ok := os.LookupEnv("GALLERY_ADMIN_USERNAME"); ok {
+		if err := bootstrapAdmin(context.Background(), repos.Accounts, username, os.Getenv("GALLERY_ADMIN_PASSWORD")); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	adminMux := http.NewServeMux()
+	adminMux.Handle("/metrics", registry)
+	adminSrv := &http.Server{
+		Handler:      adminMux,
+		Addr:         *adminAddr,
+		WriteTimeout: 15 * time.Second,
+		ReadTimeout:  15 * time.Second,
+	}
+	go func() {
+		log.Printf("Admin server listening to %s", *adminAddr)
+		log.Fatal(adminSrv.ListenAndServe())
+	}()
+
 	srv := &http.Server{
 		Handler: h,
-		Addr:    "localhost:8080",
+		Addr:    *addr,
 		// Good practice: enforce timeouts for servers you create!
 		WriteTimeout: 15 * time.Second,
 		ReadTimeout:  15 * time.Second,
+		// lets the streams of /events outlive the timeouts
+		ConnContext: handler.ConnContext,
 	}
-	log.Print("Listening to localhost:8080")
+	log.Printf("Listening to %s", *addr)
 	log.Fatal(srv.ListenAndServe())
 }
+
+func migrate(args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gallery migrate [flags] up|down|status")
+		flags.PrintDefaults()
+	}
+	dsn := flags.String("db", "file:gallery.db?_foreign_keys=true", "SQLite data source name")
+	dryRun := flags.Bool("dry-run", false, "print the migrations instead of running them")
+	to := flags.Int("to", 0, "with up, the version to migrate to instead of the latest")
+	steps := flags.Int("steps", 1, "with down, the number of migrations to revert")
+	flags.Parse(args)
+
+	migrator := model.Migrator{}
+	if err := migrator.Init(openDB(*dsn)); err != nil {
+		log.Fatal(err)
+	}
+
+	// the migrations are only described on a dry run
+	var out io.Writer
+	if *dryRun {
+		out = os.Stdout
+	}
+
+	ctx := context.Background()
+	switch flags.Arg(0) {
+	case "up":
+		applied, err := migrator.Up(ctx, *to, *dryRun, out)
+		for _, migration := range applied {
+			if !*dryRun {
+				log.Printf("Applied %04d_%s", migration.Version, migration.Name)
+			}
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "down":
+		reverted, err := migrator.Down(ctx, *steps, *dryRun, out)
+		for _, migration := range reverted {
+			if !*dryRun {
+				log.Printf("Reverted %04d_%s", migration.Version, migration.Name)
+			}
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "status":
+		if statuses, err := migrator.Status(ctx); err != nil {
+			log.Fatal(err)
+		} else {
+			for _, status := range statuses {
+				applied := "pending"
+				if status.AppliedAt != nil {
+					applied = "applied " + status.AppliedAt.Format(time.RFC3339)
+				}
+				fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, applied)
+			}
+		}
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+}
+
+// The format of a backup file: json for a .json file and sqlite otherwise, unless format says otherwise.
+func backupFormat(format string, path string) string {
+	if format != "" {
+		return format
+	} else if filepath.Ext(path) == ".json" {
+		return "json"
+	} else {
+		return "sqlite"
+	}
+}
+
+func backup(args []string) {
+	flags := flag.NewFlagSet("backup", flag.ExitOnError)
+	dsn := flags.String("db", "file:gallery.db?_foreign_keys=true", "SQLite data source name")
+	out := flags.String("out", "", "file to write, which must not exist yet")
+	format := flags.String("format", "", "sqlite or json, guessed from the extension of -out if empty")
+	flags.Parse(args)
+
+	if *out == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	db := openDB(*dsn)
+	ctx := context.Background()
+	switch backupFormat(*format, *out) {
+	case "sqlite":
+		if err := db.Backup(ctx, *out); err != nil {
+			log.Fatal(err)
+		}
+	case "json":
+		file, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := db.Dump(ctx, file); err != nil {
+			file.Close()
+			os.Remove(*out)
+			log.Fatal(err)
+		} else if err := file.Close(); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		log.Fatalf("unknown format '%s', expected sqlite or json", *format)
+	}
+	log.Printf("Backed up to %s", *out)
+}
+
+func restore(args []string) {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gallery restore [flags]\n\nReplaces the content of the database, stop the server first.")
+		flags.PrintDefaults()
+	}
+	dsn := flags.String("db", "file:gallery.db?_foreign_keys=true", "SQLite data source name")
+	in := flags.String("in", "", "backup to restore")
+	format := flags.String("format", "", "sqlite or json, guessed from the extension of -in if empty")
+	flags.Parse(args)
+
+	if *in == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	db := openDB(*dsn)
+	ctx := context.Background()
+	switch backupFormat(*format, *in) {
+	case "sqlite":
+		if err := db.RestoreBackup(ctx, *in); err != nil {
+			log.Fatal(err)
+		} else if err := db.CheckSchema(ctx); err != nil {
+			log.Printf("%s: run 'gallery migrate up' before serving", err)
+		}
+	case "json":
+		// a dump only holds rows, the schema must already be there
+		if err := db.CheckSchema(ctx); err != nil {
+			log.Fatalf("%s: run 'gallery migrate up' first", err)
+		}
+		file, err := os.Open(*in)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		if err := db.Load(ctx, file); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		log.Fatalf("unknown format '%s', expected sqlite or json", *format)
+	}
+	log.Printf("Restored %s", *in)
+}
+
+// Runs a command of the command-line client.
+func run(command string, args []string) {
+	c := cli.CLI{}
+	if err := c.Init(); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := c.Run(context.Background(), command, args); errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "gallery %s: %s\n", command, err)
+		os.Exit(1)
+	}
+}
+
+// Makes sure an administrator account exists so that admin-only endpoints such as /audit are reachable.
+func bootstrapAdmin(ctx context.Context, accountDB model.AccountRepository, username string, password string) error {
+	account, err := accountDB.GetAccountByUsername(ctx, username)
+	if err != nil {
+		if account, err = accountDB.CreateAccount(ctx, dto.AccountDto{Username: username, Password: password}); err != nil {
+			return err
+		}
+	}
+
+	_, err = accountDB.SetAdmin(ctx, account.Id, true)
+	return err
+}
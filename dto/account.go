@@ -9,6 +9,7 @@ type AccountDto struct {
 	Id       uint   `json:"id"`
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Admin    bool   `json:"admin"`
 }
 
 func DecodeAccount(r io.Reader) (*AccountDto, error) {
@@ -10,6 +10,10 @@ type ArtDto struct {
 	Quantity int    `json:"quantity"`
 	Title    string `json:"title"`
 	AuthorId uint   `json:"author_id"`
+	// Filled in by the handlers that respond with arts, the repositories leave them zero.
+	// Events leave them out, see PublishedArtDto.
+	LikeCount int64 `json:"like_count"`
+	LikedByMe bool  `json:"liked_by_me"`
 }
 
 func DecodeArt(r io.Reader) (*ArtDto, error) {
@@ -20,3 +24,27 @@ func DecodeArt(r io.Reader) (*ArtDto, error) {
 		return &art, err
 	}
 }
+
+// An art as published in events: webhook payloads and the art event stream. Its likes are left out, since they change
+// without the art and whether it is liked depends on who asks.
+type PublishedArtDto struct {
+	Id       uint   `json:"id"`
+	Quantity int    `json:"quantity"`
+	Title    string `json:"title"`
+	AuthorId uint   `json:"author_id"`
+}
+
+func PublishedArt(art ArtDto) PublishedArtDto {
+	return PublishedArtDto{Id: art.Id, Quantity: art.Quantity, Title: art.Title, AuthorId: art.AuthorId}
+}
+
+// An art as exported, along with the username of its author.
+type ArtExportDto struct {
+	ArtDto
+	AuthorUsername string `json:"author_username"`
+}
+
+// A sale of copies of an art, such as an order of a limited release.
+type SaleDto struct {
+	Quantity int `json:"quantity"`
+}
@@ -0,0 +1,17 @@
+package dto
+
+import "time"
+
+// Type of the art event logged along with art.updated when the quantity of the art changed.
+const ArtQuantityChanged = "art.quantity_changed"
+
+// A change to the catalog, as streamed by GET /events.
+type ArtEventDto struct {
+	Id   uint   `json:"id"`
+	Type string `json:"type"`
+	// The art as it is after the change, or as it was before it was deleted.
+	Art PublishedArtDto `json:"art"`
+	// Quantity of the art before the change, only set for art.quantity_changed.
+	PreviousQuantity *int      `json:"previous_quantity,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
@@ -0,0 +1,19 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditEntryDto struct {
+	Id        uint                   `json:"id"`
+	ActorId   uint                   `json:"actor_id"`
+	Action    string                 `json:"action"`
+	Entity    string                 `json:"entity"`
+	EntityId  uint                   `json:"entity_id"`
+	Before    json.RawMessage        `json:"before"`
+	After     json.RawMessage        `json:"after"`
+	Diff      map[string]FieldChange `json:"diff"`
+	Timestamp time.Time              `json:"timestamp"`
+	RequestId string                 `json:"request_id"`
+}
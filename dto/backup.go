@@ -0,0 +1,13 @@
+package dto
+
+import "time"
+
+// A backup written on the server.
+type BackupDto struct {
+	// Name of the file in the backup directory of the server.
+	File string `json:"file"`
+	// sqlite for a copy of the SQLite database, json for a portable dump.
+	Format    string    `json:"format"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,26 @@
+package dto
+
+// An operation of a batch: create Art, update the art with Id to Art or delete the art with Id.
+type BatchOperationDto struct {
+	// create, update or delete.
+	Op  string  `json:"op"`
+	Id  uint    `json:"id,omitempty"`
+	Art *ArtDto `json:"art,omitempty"`
+}
+
+type BatchDto struct {
+	Operations []BatchOperationDto `json:"operations"`
+}
+
+// Outcome of one operation of a batch.
+type BatchResultDto struct {
+	Op string `json:"op"`
+	// created, updated, deleted, failed, rolled_back (after a later operation failed) or skipped (after an earlier operation failed).
+	Status string  `json:"status"`
+	Art    *ArtDto `json:"art,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+type BatchReportDto struct {
+	Results []BatchResultDto `json:"results"`
+}
@@ -0,0 +1,37 @@
+package dto
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Moderation states of a comment.
+const (
+	CommentVisible = "visible"
+	// Reported by an account and waiting for a moderator, still visible meanwhile.
+	CommentReported = "reported"
+	// Hidden by a moderator, only moderators see it.
+	CommentHidden = "hidden"
+)
+
+// A comment on an art, or a reply to another comment on it when ParentId isn't zero.
+type CommentDto struct {
+	Id        uint      `json:"id"`
+	ArtId     uint      `json:"art_id"`
+	AuthorId  uint      `json:"author_id"`
+	ParentId  uint      `json:"parent_id"`
+	Body      string    `json:"body"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func DecodeComment(r io.Reader) (*CommentDto, error) {
+	var comment CommentDto
+	if err := json.NewDecoder(r).Decode(&comment); err != nil {
+		return nil, err
+	} else {
+		return &comment, err
+	}
+}
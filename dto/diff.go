@@ -0,0 +1,51 @@
+package dto
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Compares two JSON objects field by field.
+// Only the fields whose values differ are returned. A null or empty document is treated as an object without fields.
+func DiffJSON(before json.RawMessage, after json.RawMessage) (map[string]FieldChange, error) {
+	beforeFields, err := decodeFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := decodeFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := map[string]FieldChange{}
+	for key, value := range beforeFields {
+		if other, ok := afterFields[key]; !ok || !reflect.DeepEqual(value, other) {
+			diff[key] = FieldChange{Before: value, After: other}
+		}
+	}
+	for key, value := range afterFields {
+		if _, ok := beforeFields[key]; !ok {
+			diff[key] = FieldChange{Before: nil, After: value}
+		}
+	}
+
+	return diff, nil
+}
+
+func decodeFields(data json.RawMessage) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if len(data) == 0 {
+		return fields, nil
+	} else if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	} else if fields == nil {
+		return map[string]interface{}{}, nil
+	} else {
+		return fields, nil
+	}
+}
@@ -0,0 +1,8 @@
+package dto
+
+// A page of the feed of an account.
+type FeedDto struct {
+	Arts []ArtDto `json:"arts"`
+	// Cursor of the next page, empty on the last page.
+	NextCursor string `json:"next_cursor"`
+}
@@ -0,0 +1,11 @@
+package dto
+
+type CheckDto struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type HealthDto struct {
+	Status string              `json:"status"`
+	Checks map[string]CheckDto `json:"checks,omitempty"`
+}
@@ -0,0 +1,23 @@
+package dto
+
+// Outcome of one row of an import.
+type ImportRowDto struct {
+	// Line of the row in the imported file.
+	Line int `json:"line"`
+	// created, valid (on a dry run), failed, rolled_back (by an atomic import or a batch that failed) or skipped (after
+	// an atomic import failed, or once the import stopped).
+	Status string  `json:"status"`
+	Art    *ArtDto `json:"art,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+type ImportReportDto struct {
+	DryRun bool `json:"dry_run"`
+	Atomic bool `json:"atomic"`
+	// Rows created, or that would be on a dry run.
+	Succeeded int            `json:"succeeded"`
+	Failed    int            `json:"failed"`
+	Rows      []ImportRowDto `json:"rows"`
+	// Why the import stopped before its last row, if it did. The rows created before then are kept unless it is atomic.
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,30 @@
+package dto
+
+import "time"
+
+// Statuses of the inventory of an art.
+const (
+	InventoryAvailable = "available"
+	InventorySoldOut   = "sold_out"
+	// The art was deleted.
+	InventoryWithdrawn = "withdrawn"
+)
+
+// A message a client sends on the /inventory WebSocket.
+type InventoryRequestDto struct {
+	// subscribe or unsubscribe.
+	Type   string `json:"type"`
+	ArtIds []uint `json:"art_ids"`
+}
+
+// A message pushed on the /inventory WebSocket: the quantity and status of a subscribed art, or an error.
+type InventoryDto struct {
+	// inventory, or error when a request of the client was refused.
+	Type     string `json:"type"`
+	ArtId    uint   `json:"art_id,omitempty"`
+	Quantity int    `json:"quantity"`
+	Status   string `json:"status,omitempty"`
+	Error    string `json:"error,omitempty"`
+	// When the art got this quantity and status.
+	Time time.Time `json:"time"`
+}
@@ -0,0 +1,27 @@
+package dto
+
+import "time"
+
+// Types of notifications.
+const (
+	// Someone liked an art of the account.
+	NotificationLike = "like"
+	// Someone commented on an art of the account.
+	NotificationComment = "comment"
+	// Someone replied to a comment of the account.
+	NotificationReply = "reply"
+)
+
+// Tells an account that another account did something concerning it.
+type NotificationDto struct {
+	Id uint `json:"id"`
+	// Account the notification is for.
+	AccountId uint   `json:"account_id"`
+	ActorId   uint   `json:"actor_id"`
+	Type      string `json:"type"`
+	ArtId     uint   `json:"art_id"`
+	// Comment or reply the notification is about, 0 for likes.
+	CommentId uint      `json:"comment_id"`
+	Read      bool      `json:"read"`
+	CreatedAt time.Time `json:"created_at"`
+}
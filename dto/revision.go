@@ -0,0 +1,9 @@
+package dto
+
+import "time"
+
+type ArtRevisionDto struct {
+	Number    uint      `json:"number"`
+	CreatedAt time.Time `json:"created_at"`
+	Art       ArtDto    `json:"art"`
+}
@@ -0,0 +1,61 @@
+package dto
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// States of a webhook delivery.
+const (
+	// Waiting for its first attempt or for a retry.
+	DeliveryPending = "pending"
+	// Acknowledged by the receiver with a 2xx response.
+	DeliveryDelivered = "delivered"
+	// Given up after too many failed attempts.
+	DeliveryFailed = "failed"
+)
+
+// A URL the events of the gallery are posted to.
+type WebhookDto struct {
+	Id  uint   `json:"id"`
+	URL string `json:"url"`
+	// Types of the events posted to the URL, such as art.created.
+	Events []string `json:"events"`
+	// Key the deliveries are signed with. It is only sent back when the webhook is created.
+	Secret string `json:"secret,omitempty"`
+	// Events are not queued for a disabled webhook.
+	Disabled  bool      `json:"disabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func DecodeWebhook(r io.Reader) (*WebhookDto, error) {
+	var webhook WebhookDto
+	if err := json.NewDecoder(r).Decode(&webhook); err != nil {
+		return nil, err
+	} else {
+		return &webhook, err
+	}
+}
+
+// An event queued for a webhook along with the outcome of its attempts.
+type WebhookDeliveryDto struct {
+	Id        uint   `json:"id"`
+	WebhookId uint   `json:"webhook_id"`
+	Event     string `json:"event"`
+	// Body posted to the webhook.
+	Payload  json.RawMessage `json:"payload"`
+	Status   string          `json:"status"`
+	Attempts int             `json:"attempts"`
+	// When the delivery is next attempted while it is pending.
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	// Status code of the response to the last attempt, 0 if there was none.
+	ResponseStatus int `json:"response_status"`
+	// Why the last attempt failed.
+	Error       string     `json:"error,omitempty"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+	// Delivery this one replays, 0 if it isn't a replay.
+	ReplayOf  uint      `json:"replay_of"`
+	CreatedAt time.Time `json:"created_at"`
+}
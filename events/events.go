@@ -0,0 +1,79 @@
+// Package events carries the domain events of the gallery, such as an art being liked,
+// from the operations that cause them to the parts of the gallery that react to them.
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/dto"
+)
+
+// Types of events.
+const (
+	ArtCreated     = "art.created"
+	ArtUpdated     = "art.updated"
+	ArtDeleted     = "art.deleted"
+	ArtLiked       = "art.liked"
+	ArtCommented   = "art.commented"
+	AccountCreated = "account.created"
+)
+
+// Whether t is one of the types of events.
+func IsType(t string) bool {
+	switch t {
+	case ArtCreated, ArtUpdated, ArtDeleted, ArtLiked, ArtCommented, AccountCreated:
+		return true
+	default:
+		return false
+	}
+}
+
+// Something that happened in the gallery.
+type Event struct {
+	Type string
+	// Account that made it happen.
+	ActorId uint
+	// Art the event is about, as it is after the event or as it was before it was deleted.
+	Art *dto.ArtDto
+	// Art as it was before an update, nil for other events.
+	Before *dto.ArtDto
+	// Comment the event is about, nil if none.
+	Comment *dto.CommentDto
+	// Account the event is about, without its password, nil if none.
+	Account *dto.AccountDto
+	Time    time.Time
+}
+
+// Reacts to an event. Subscribers handle their own errors, there is nobody to return them to.
+type Subscriber func(ctx context.Context, event Event)
+
+// Delivers every event published on it to every subscriber, in the order they subscribed.
+// The zero value is ready to use.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers []Subscriber
+}
+
+func (b *Bus) Subscribe(subscriber Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, subscriber)
+}
+
+// Calls every subscriber with event and returns once they all have.
+// Events are published once their operation succeeded, so subscribers never hear of rolled back changes.
+func (b *Bus) Publish(ctx context.Context, event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+
+	for _, subscriber := range subscribers {
+		subscriber(ctx, event)
+	}
+}
@@ -1,57 +1,207 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"strconv"
 
 	"github.com/gorilla/mux"
 	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/events"
 	"github.com/nafiz1001/gallery-go/model"
 )
 
 type AccountsHandler struct {
-	db *model.AccountDB
+	db       model.AccountRepository
+	likeDB   model.LikeRepository
+	followDB model.FollowRepository
+	repos    model.Repositories
+	auth     Authenticator
+	outbox   *Outbox
 }
 
-func (h *AccountsHandler) Init(db *model.AccountDB) error {
-	h.db = db
+// Events of the accounts are recorded and published through outbox.
+func (h *AccountsHandler) Init(repos model.Repositories, auth Authenticator, outbox *Outbox) error {
+	h.db = repos.Accounts
+	h.likeDB = repos.Likes
+	h.followDB = repos.Follows
+	h.repos = repos
+	h.auth = auth
+	h.outbox = outbox
 	return nil
 }
 
 func (h AccountsHandler) PostAccount(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	if account, err := dto.DecodeAccount(r.Body); err != nil {
+	account, err := dto.DecodeAccount(r.Body)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-	} else if acc, err := h.db.CreateAccount(*account); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var redacted dto.AccountDto
+	err = h.outbox.Transaction(r.Context(), h.repos, func(tx model.Repositories) ([]events.Event, error) {
+		var err error
+		if account, err = tx.Accounts.CreateAccount(r.Context(), *account); err != nil {
+			return nil, err
+		}
+		redacted = *account
+		redacted.Password = ""
+		if err := recordAudit(tx.Audit, r, account.Id, "create", "account", account.Id, nil, redacted); err != nil {
+			return nil, err
+		}
+		return []events.Event{{Type: events.AccountCreated, ActorId: account.Id, Account: &redacted}}, nil
+	})
+	if err != nil {
+		modelError(w, err)
 	} else {
-		json.NewEncoder(w).Encode(acc)
+		json.NewEncoder(w).Encode(account)
 	}
 }
 
+// Gets the account the request is authenticated as, which lets clients check their credentials.
+func (h AccountsHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	h.auth.AccountAuth(w, r, func(account dto.AccountDto) {
+		w.Header().Set("Content-Type", "application/json")
+
+		account.Password = ""
+		json.NewEncoder(w).Encode(account)
+	})
+}
+
 func (h AccountsHandler) GetAccountById(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	vars := mux.Vars(r)
 	id, _ := strconv.ParseInt(vars["id"], 10, 32)
 
-	if account, err := h.db.GetAccountById(uint(id)); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if account, err := h.db.GetAccountById(r.Context(), uint(id)); err != nil {
+		modelError(w, err)
 	} else {
 		json.NewEncoder(w).Encode(account)
 	}
 }
 
-func (h AccountsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	router := mux.NewRouter()
+// Lists the arts an account likes, most recently liked first.
+// Whether the viewer likes them too is only known if the request has credentials.
+func (h AccountsHandler) GetLikes(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 10, 32)
+
+	h.auth.OptionalAuth(w, r, func(viewer *dto.AccountDto) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if _, err := h.db.GetAccountById(r.Context(), uint(id)); err != nil {
+			modelError(w, err)
+		} else if arts, err := h.likeDB.GetLikedArts(r.Context(), uint(id)); err != nil {
+			modelError(w, err)
+		} else if err := withLikes(r.Context(), h.likeDB, viewer, arts); err != nil {
+			modelError(w, err)
+		} else {
+			json.NewEncoder(w).Encode(arts)
+		}
+	})
+}
+
+// Makes account follow the account by id and responds with the followed account. Following an account again changes nothing.
+func (h AccountsHandler) PutFollow(w http.ResponseWriter, r *http.Request, id uint, account dto.AccountDto) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := h.followDB.Follow(r.Context(), account.Id, id); err != nil {
+		modelError(w, err)
+	} else if followed, err := h.db.GetAccountById(r.Context(), id); err != nil {
+		modelError(w, err)
+	} else {
+		followed.Password = ""
+		json.NewEncoder(w).Encode(followed)
+	}
+}
+
+// Makes account stop following the account by id and responds with that account.
+func (h AccountsHandler) DeleteFollow(w http.ResponseWriter, r *http.Request, id uint, account dto.AccountDto) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if followed, err := h.db.GetAccountById(r.Context(), id); err != nil {
+		modelError(w, err)
+	} else if err := h.followDB.Unfollow(r.Context(), account.Id, id); err != nil {
+		modelError(w, err)
+	} else {
+		followed.Password = ""
+		json.NewEncoder(w).Encode(followed)
+	}
+}
 
+func (h AccountsHandler) FollowFuncHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 10, 32)
+
+	h.auth.AccountAuth(w, r, func(account dto.AccountDto) {
+		switch r.Method {
+		case http.MethodPut:
+			h.PutFollow(w, r, uint(id), account)
+		case http.MethodDelete:
+			h.DeleteFollow(w, r, uint(id), account)
+		}
+	})
+}
+
+// Lists the followers of an account, most recent first.
+func (h AccountsHandler) GetFollowers(w http.ResponseWriter, r *http.Request) {
+	h.getFollows(w, r, h.followDB.GetFollowers)
+}
+
+// Lists the accounts an account follows, most recently followed first.
+func (h AccountsHandler) GetFollowing(w http.ResponseWriter, r *http.Request) {
+	h.getFollows(w, r, h.followDB.GetFollowing)
+}
+
+func (h AccountsHandler) getFollows(w http.ResponseWriter, r *http.Request, get func(ctx context.Context, accountId uint) ([]dto.AccountDto, error)) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 10, 32)
+
+	if _, err := h.db.GetAccountById(r.Context(), uint(id)); err != nil {
+		modelError(w, err)
+	} else if accounts, err := get(r.Context(), uint(id)); err != nil {
+		modelError(w, err)
+	} else {
+		for i := range accounts {
+			accounts[i].Password = ""
+		}
+		json.NewEncoder(w).Encode(accounts)
+	}
+}
+
+// Registers the routes served by the handler on router.
+func (h AccountsHandler) Routes(router *mux.Router) {
 	router.HandleFunc("/accounts", h.PostAccount).Methods(http.MethodPost)
 	router.HandleFunc("/accounts/", h.PostAccount).Methods(http.MethodPost)
 
+	router.HandleFunc("/accounts/me", h.GetMe).Methods(http.MethodGet)
+	router.HandleFunc("/accounts/me/", h.GetMe).Methods(http.MethodGet)
+
 	router.HandleFunc("/accounts/{id:[0-9]+}", h.GetAccountById).Methods(http.MethodGet)
 	router.HandleFunc("/accounts/{id:[0-9]+}/", h.GetAccountById).Methods(http.MethodGet)
 
+	router.HandleFunc("/accounts/{id:[0-9]+}/follow", h.FollowFuncHandler).Methods(http.MethodPut, http.MethodDelete)
+	router.HandleFunc("/accounts/{id:[0-9]+}/follow/", h.FollowFuncHandler).Methods(http.MethodPut, http.MethodDelete)
+
+	router.HandleFunc("/accounts/{id:[0-9]+}/followers", h.GetFollowers).Methods(http.MethodGet)
+	router.HandleFunc("/accounts/{id:[0-9]+}/followers/", h.GetFollowers).Methods(http.MethodGet)
+
+	router.HandleFunc("/accounts/{id:[0-9]+}/following", h.GetFollowing).Methods(http.MethodGet)
+	router.HandleFunc("/accounts/{id:[0-9]+}/following/", h.GetFollowing).Methods(http.MethodGet)
+
+	router.HandleFunc("/accounts/{id:[0-9]+}/likes", h.GetLikes).Methods(http.MethodGet)
+	router.HandleFunc("/accounts/{id:[0-9]+}/likes/", h.GetLikes).Methods(http.MethodGet)
+}
+
+func (h AccountsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	router := mux.NewRouter()
+	router.Use(recordRoute)
+	h.Routes(router)
 	router.ServeHTTP(w, r)
 }
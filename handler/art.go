@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -8,17 +9,32 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/events"
 	"github.com/nafiz1001/gallery-go/model"
 )
 
+// Largest page of arts a client can ask for.
+const maxArtsLimit = 1000
+
 type ArtsHandler struct {
-	artDB     *model.ArtDB
-	accountDB *model.AccountDB
+	artDB     model.ArtRepository
+	likeDB    model.LikeRepository
+	commentDB model.CommentRepository
+	followDB  model.FollowRepository
+	repos     model.Repositories
+	auth      Authenticator
+	outbox    *Outbox
 }
 
-func (h *ArtsHandler) Init(artDB *model.ArtDB, accountDB *model.AccountDB) error {
-	h.artDB = artDB
-	h.accountDB = accountDB
+// Events of the arts are recorded and published through outbox.
+func (h *ArtsHandler) Init(repos model.Repositories, auth Authenticator, outbox *Outbox) error {
+	h.artDB = repos.Arts
+	h.likeDB = repos.Likes
+	h.commentDB = repos.Comments
+	h.followDB = repos.Follows
+	h.repos = repos
+	h.auth = auth
+	h.outbox = outbox
 
 	return nil
 }
@@ -30,73 +46,122 @@ func (h ArtsHandler) PostArt(w http.ResponseWriter, r *http.Request, account dto
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 	} else {
 		art.AuthorId = account.Id
-		if art, err := h.artDB.CreateArt(*art); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		err := h.outbox.Transaction(r.Context(), h.repos, func(tx model.Repositories) ([]events.Event, error) {
+			var err error
+			if art, err = tx.Arts.CreateArt(r.Context(), *art); err != nil {
+				return nil, err
+			}
+			if err := recordAudit(tx.Audit, r, account.Id, "create", "art", art.Id, nil, art); err != nil {
+				return nil, err
+			}
+			if err := withArtLikes(r.Context(), tx.Likes, &account, art); err != nil {
+				return nil, err
+			}
+			return []events.Event{{Type: events.ArtCreated, ActorId: account.Id, Art: art}}, nil
+		})
+		if err != nil {
+			modelError(w, err)
 		} else {
 			json.NewEncoder(w).Encode(art)
 		}
 	}
 }
 
-func (h ArtsHandler) GetArts(w http.ResponseWriter, r *http.Request) {
+// Lists arts by id. The optional after and limit query parameters page through them:
+// a page has at most limit arts, whose ids are greater than after.
+// Whether the arts are liked is only known if the request has credentials.
+func (h ArtsHandler) GetArts(w http.ResponseWriter, r *http.Request, viewer *dto.AccountDto) {
 	w.Header().Set("Content-Type", "application/json")
 
-	if arts, err := h.artDB.GetArts(); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	var after, limit uint64
+	if s := r.URL.Query().Get("after"); s != "" {
+		var err error
+		if after, err = strconv.ParseUint(s, 10, 32); err != nil {
+			http.Error(w, "after must be an art id", http.StatusBadRequest)
+			return
+		}
+	}
+	if s := r.URL.Query().Get("limit"); s != "" {
+		var err error
+		if limit, err = strconv.ParseUint(s, 10, 32); err != nil || limit == 0 || limit > maxArtsLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxArtsLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if arts, err := h.artDB.GetArtsAfter(r.Context(), uint(after), int(limit)); err != nil {
+		modelError(w, err)
+	} else if err := withLikes(r.Context(), h.likeDB, viewer, arts); err != nil {
+		modelError(w, err)
 	} else {
 		json.NewEncoder(w).Encode(arts)
 	}
 }
 
-func (h ArtsHandler) GetArt(w http.ResponseWriter, r *http.Request, id uint) {
+// Gets an art along with its likes. Whether it is liked is only known if the request has credentials.
+func (h ArtsHandler) GetArt(w http.ResponseWriter, r *http.Request, id uint, viewer *dto.AccountDto) {
 	w.Header().Set("Content-Type", "application/json")
 
-	if art, err := h.artDB.GetArt(id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if art, err := likedArt(r.Context(), h.artDB, h.likeDB, id, viewer); err != nil {
+		modelError(w, err)
 	} else {
 		json.NewEncoder(w).Encode(art)
 	}
 }
 
-func (h ArtsHandler) PutArt(w http.ResponseWriter, r *http.Request, art *dto.ArtDto, account dto.AccountDto) {
+func (h ArtsHandler) PutArt(w http.ResponseWriter, r *http.Request, art *dto.ArtDto, account dto.AccountDto, before dto.ArtDto) {
 	w.Header().Set("Content-Type", "application/json")
 
 	art.AuthorId = account.Id
-	if art, err := h.artDB.UpdateArt(*art); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	err := h.outbox.Transaction(r.Context(), h.repos, func(tx model.Repositories) ([]events.Event, error) {
+		var err error
+		if art, err = tx.Arts.UpdateArt(r.Context(), *art); err != nil {
+			return nil, err
+		}
+		if err := recordAudit(tx.Audit, r, account.Id, "update", "art", art.Id, before, art); err != nil {
+			return nil, err
+		}
+		if err := withArtLikes(r.Context(), tx.Likes, &account, art); err != nil {
+			return nil, err
+		}
+		return []events.Event{{Type: events.ArtUpdated, ActorId: account.Id, Art: art, Before: &before}}, nil
+	})
+	if err != nil {
+		modelError(w, err)
 	} else {
 		json.NewEncoder(w).Encode(art)
 	}
 }
 
-func (h ArtsHandler) DeleteArt(w http.ResponseWriter, r *http.Request, id uint) {
+func (h ArtsHandler) DeleteArt(w http.ResponseWriter, r *http.Request, id uint, account dto.AccountDto) {
 	w.Header().Set("Content-Type", "application/json")
-	if art, err := h.artDB.DeleteArt(id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	var art *dto.ArtDto
+	err := h.outbox.Transaction(r.Context(), h.repos, func(tx model.Repositories) ([]events.Event, error) {
+		var err error
+		if art, err = tx.Arts.DeleteArt(r.Context(), id); err != nil {
+			return nil, err
+		}
+		if err := recordAudit(tx.Audit, r, account.Id, "delete", "art", art.Id, art, nil); err != nil {
+			return nil, err
+		}
+		return []events.Event{{Type: events.ArtDeleted, ActorId: account.Id, Art: art}}, nil
+	})
+	if err != nil {
+		modelError(w, err)
 	} else {
 		json.NewEncoder(w).Encode(art)
 	}
 }
 
 func (h ArtsHandler) AccountAuth(w http.ResponseWriter, r *http.Request, f func(dto.AccountDto)) {
-	if username, password, ok := r.BasicAuth(); !ok {
-		http.Error(w, "missing or malformed Authorization header", http.StatusUnauthorized)
-	} else if account, err := h.accountDB.GetAccountByUsername(username); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	} else if password != account.Password {
-		http.Error(w, "password is incorrect", http.StatusUnauthorized)
-	} else {
-		f(*account)
-	}
+	h.auth.AccountAuth(w, r, f)
 }
 
 func (h ArtsHandler) AuthorAuth(w http.ResponseWriter, r *http.Request, id uint, f func(dto.AccountDto, dto.ArtDto)) {
 	h.AccountAuth(w, r, func(account dto.AccountDto) {
 
-		if art, err := h.artDB.GetArt(id); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		} else if art.AuthorId != account.Id {
-			http.Error(w, fmt.Sprintf("art #%d does not belong to '%s'", art.Id, account.Username), http.StatusUnauthorized)
+		if art, err := authorArt(r.Context(), h.artDB, account, id); err != nil {
+			modelError(w, err)
 		} else {
 			f(account, *art)
 		}
@@ -104,6 +169,17 @@ func (h ArtsHandler) AuthorAuth(w http.ResponseWriter, r *http.Request, id uint,
 	})
 }
 
+// Returns the art with id if account is its author, and a notAuthorError otherwise.
+func authorArt(ctx context.Context, artDB model.ArtRepository, account dto.AccountDto, id uint) (*dto.ArtDto, error) {
+	if art, err := artDB.GetArt(ctx, id); err != nil {
+		return nil, err
+	} else if art.AuthorId != account.Id {
+		return nil, notAuthorError{artId: art.Id, username: account.Username}
+	} else {
+		return art, nil
+	}
+}
+
 func (h ArtsHandler) ArtsFuncHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodPost:
@@ -111,41 +187,106 @@ func (h ArtsHandler) ArtsFuncHandler(w http.ResponseWriter, r *http.Request) {
 			h.PostArt(w, r, account)
 		})
 	case http.MethodGet:
-		h.GetArts(w, r)
+		h.auth.OptionalAuth(w, r, func(viewer *dto.AccountDto) {
+			h.GetArts(w, r, viewer)
+		})
 	}
 }
 
+func (h ArtsHandler) ImportFuncHandler(w http.ResponseWriter, r *http.Request) {
+	h.AccountAuth(w, r, func(account dto.AccountDto) {
+		h.PostImport(w, r, account)
+	})
+}
+
+func (h ArtsHandler) BatchFuncHandler(w http.ResponseWriter, r *http.Request) {
+	h.AccountAuth(w, r, func(account dto.AccountDto) {
+		h.PostBatch(w, r, account)
+	})
+}
+
 func (h ArtsHandler) ArtByIdFuncHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, _ := strconv.ParseInt(vars["id"], 10, 32)
 
 	switch r.Method {
 	case http.MethodGet:
-		h.GetArt(w, r, uint(id))
+		h.auth.OptionalAuth(w, r, func(viewer *dto.AccountDto) {
+			h.GetArt(w, r, uint(id), viewer)
+		})
 	case http.MethodPut:
-		h.AuthorAuth(w, r, uint(id), func(account dto.AccountDto, _ dto.ArtDto) {
+		h.AuthorAuth(w, r, uint(id), func(account dto.AccountDto, before dto.ArtDto) {
 			if art, err := dto.DecodeArt(r.Body); err != nil {
 				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 			} else {
 				art.Id = uint(id)
-				h.PutArt(w, r, art, account)
+				h.PutArt(w, r, art, account, before)
 			}
 		})
 	case http.MethodDelete:
 		h.AuthorAuth(w, r, uint(id), func(account dto.AccountDto, _ dto.ArtDto) {
-			h.DeleteArt(w, r, uint(id))
+			h.DeleteArt(w, r, uint(id), account)
 		})
 	}
 }
 
-func (h ArtsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	router := mux.NewRouter()
+// Registers the routes served by the handler on router.
+func (h ArtsHandler) Routes(router *mux.Router) {
+	router.HandleFunc("/comments", h.ModerationQueueFuncHandler).Methods(http.MethodGet)
+	router.HandleFunc("/comments/", h.ModerationQueueFuncHandler).Methods(http.MethodGet)
+
+	router.HandleFunc("/feed", h.FeedFuncHandler).Methods(http.MethodGet)
+	router.HandleFunc("/feed/", h.FeedFuncHandler).Methods(http.MethodGet)
 
 	router.HandleFunc("/arts", h.ArtsFuncHandler).Methods(http.MethodPost, http.MethodGet)
 	router.HandleFunc("/arts/", h.ArtsFuncHandler).Methods(http.MethodPost, http.MethodGet)
 
+	router.HandleFunc("/arts/batch", h.BatchFuncHandler).Methods(http.MethodPost)
+	router.HandleFunc("/arts/batch/", h.BatchFuncHandler).Methods(http.MethodPost)
+
+	router.HandleFunc("/arts/export", h.GetExport).Methods(http.MethodGet)
+	router.HandleFunc("/arts/export/", h.GetExport).Methods(http.MethodGet)
+
+	router.HandleFunc("/arts/import", h.ImportFuncHandler).Methods(http.MethodPost)
+	router.HandleFunc("/arts/import/", h.ImportFuncHandler).Methods(http.MethodPost)
+
 	router.HandleFunc("/arts/{id:[0-9]+}", h.ArtByIdFuncHandler).Methods(http.MethodGet, http.MethodPut, http.MethodDelete)
 	router.HandleFunc("/arts/{id:[0-9]+}/", h.ArtByIdFuncHandler).Methods(http.MethodGet, http.MethodPut, http.MethodDelete)
 
+	router.HandleFunc("/arts/{id:[0-9]+}/comments", h.CommentsFuncHandler).Methods(http.MethodPost, http.MethodGet)
+	router.HandleFunc("/arts/{id:[0-9]+}/comments/", h.CommentsFuncHandler).Methods(http.MethodPost, http.MethodGet)
+
+	router.HandleFunc("/arts/{id:[0-9]+}/comments/{comment:[0-9]+}", h.CommentByIdFuncHandler).Methods(http.MethodGet, http.MethodPut, http.MethodDelete)
+	router.HandleFunc("/arts/{id:[0-9]+}/comments/{comment:[0-9]+}/", h.CommentByIdFuncHandler).Methods(http.MethodGet, http.MethodPut, http.MethodDelete)
+
+	router.HandleFunc("/arts/{id:[0-9]+}/comments/{comment:[0-9]+}/report", h.ReportFuncHandler).Methods(http.MethodPost)
+	router.HandleFunc("/arts/{id:[0-9]+}/comments/{comment:[0-9]+}/report/", h.ReportFuncHandler).Methods(http.MethodPost)
+
+	router.HandleFunc("/arts/{id:[0-9]+}/comments/{comment:[0-9]+}/status", h.ModerationFuncHandler).Methods(http.MethodPut)
+	router.HandleFunc("/arts/{id:[0-9]+}/comments/{comment:[0-9]+}/status/", h.ModerationFuncHandler).Methods(http.MethodPut)
+
+	router.HandleFunc("/arts/{id:[0-9]+}/like", h.LikeFuncHandler).Methods(http.MethodPut, http.MethodDelete)
+	router.HandleFunc("/arts/{id:[0-9]+}/like/", h.LikeFuncHandler).Methods(http.MethodPut, http.MethodDelete)
+
+	router.HandleFunc("/arts/{id:[0-9]+}/sales", h.SaleFuncHandler).Methods(http.MethodPost)
+	router.HandleFunc("/arts/{id:[0-9]+}/sales/", h.SaleFuncHandler).Methods(http.MethodPost)
+
+	router.HandleFunc("/arts/{id:[0-9]+}/revisions", h.GetArtRevisions).Methods(http.MethodGet)
+	router.HandleFunc("/arts/{id:[0-9]+}/revisions/", h.GetArtRevisions).Methods(http.MethodGet)
+
+	router.HandleFunc("/arts/{id:[0-9]+}/revisions/{n:[0-9]+}", h.GetArtRevision).Methods(http.MethodGet)
+	router.HandleFunc("/arts/{id:[0-9]+}/revisions/{n:[0-9]+}/", h.GetArtRevision).Methods(http.MethodGet)
+
+	router.HandleFunc("/arts/{id:[0-9]+}/revisions/{n:[0-9]+}/diff", h.GetArtRevisionDiff).Methods(http.MethodGet)
+	router.HandleFunc("/arts/{id:[0-9]+}/revisions/{n:[0-9]+}/diff/", h.GetArtRevisionDiff).Methods(http.MethodGet)
+
+	router.HandleFunc("/arts/{id:[0-9]+}/revisions/{n:[0-9]+}/restore", h.RestoreArtRevision).Methods(http.MethodPost)
+	router.HandleFunc("/arts/{id:[0-9]+}/revisions/{n:[0-9]+}/restore/", h.RestoreArtRevision).Methods(http.MethodPost)
+}
+
+func (h ArtsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	router := mux.NewRouter()
+	router.Use(recordRoute)
+	h.Routes(router)
 	router.ServeHTTP(w, r)
 }
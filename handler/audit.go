@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+type AuditHandler struct {
+	auditDB model.AuditRepository
+	auth    Authenticator
+}
+
+func (h *AuditHandler) Init(auditDB model.AuditRepository, auth Authenticator) error {
+	h.auditDB = auditDB
+	h.auth = auth
+
+	return nil
+}
+
+func (h AuditHandler) GetEntries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var id uint64
+	if s := r.URL.Query().Get("id"); s != "" {
+		var err error
+		if id, err = strconv.ParseUint(s, 10, 32); err != nil {
+			http.Error(w, "id must be a positive integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if entries, err := h.auditDB.GetEntries(r.Context(), r.URL.Query().Get("entity"), uint(id)); err != nil {
+		modelError(w, err)
+	} else {
+		json.NewEncoder(w).Encode(entries)
+	}
+}
+
+func (h AuditHandler) AuditFuncHandler(w http.ResponseWriter, r *http.Request) {
+	h.auth.AdminAuth(w, r, func(_ dto.AccountDto) {
+		h.GetEntries(w, r)
+	})
+}
+
+// Registers the routes served by the handler on router.
+func (h AuditHandler) Routes(router *mux.Router) {
+	router.HandleFunc("/audit", h.AuditFuncHandler).Methods(http.MethodGet)
+	router.HandleFunc("/audit/", h.AuditFuncHandler).Methods(http.MethodGet)
+}
+
+func (h AuditHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	router := mux.NewRouter()
+	router.Use(recordRoute)
+	h.Routes(router)
+	router.ServeHTTP(w, r)
+}
+
+// Appends a mutation to the audit trail of auditDB, which is meant to be in the transaction of the mutation so that
+// the trail has every mutation that was committed. An error should roll the mutation back.
+func recordAudit(auditDB model.AuditRepository, r *http.Request, actorId uint, action string, entity string, entityId uint, before interface{}, after interface{}) error {
+	entry := dto.AuditEntryDto{
+		ActorId:   actorId,
+		Action:    action,
+		Entity:    entity,
+		EntityId:  entityId,
+		RequestId: model.RequestID(r.Context()),
+	}
+
+	var err error
+	if entry.Before, err = json.Marshal(before); err != nil {
+		return err
+	} else if entry.After, err = json.Marshal(after); err != nil {
+		return err
+	} else {
+		_, err = auditDB.Record(r.Context(), entry)
+		return err
+	}
+}
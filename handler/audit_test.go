@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+	"github.com/nafiz1001/gallery-go/model/memory"
+)
+
+// An audit trail that can't be appended to.
+type failingAudit struct {
+	model.AuditRepository
+}
+
+func (failingAudit) Record(ctx context.Context, entry dto.AuditEntryDto) (*dto.AuditEntryDto, error) {
+	return nil, errors.New("audit trail is gone")
+}
+
+// Runs transactions whose audit trail can't be appended to.
+type auditlessTransactor struct {
+	model.Transactor
+}
+
+func (a auditlessTransactor) Transaction(ctx context.Context, f func(tx model.Repositories) error) error {
+	return a.Transactor.Transaction(ctx, func(tx model.Repositories) error {
+		tx.Audit = failingAudit{tx.Audit}
+		tx.Transactor = auditlessTransactor{tx.Transactor}
+		return f(tx)
+	})
+}
+
+func TestAuditTrail(t *testing.T) {
+	memoryDB := &memory.DB{}
+	CheckError(t, memoryDB.Init())
+	repos, err := memoryDB.Repositories()
+	CheckError(t, err)
+	repos.Transactor = auditlessTransactor{repos.Transactor}
+	server := newTestServer(t, testServerOptions{Repos: repos})
+
+	ctx := context.Background()
+	account, err := repos.Accounts.CreateAccount(ctx, dto.AccountDto{Username: "artist", Password: "password"})
+	CheckError(t, err)
+	art, err := repos.Arts.CreateArt(ctx, dto.ArtDto{Title: "title", Quantity: 1, AuthorId: account.Id})
+	CheckError(t, err)
+
+	// changes that can't be audited are rolled back
+	if status := SendAs(t, http.MethodPost, server.URL+"/arts", "artist", dto.ArtDto{Title: "title", Quantity: 1}, nil); status != http.StatusInternalServerError {
+		t.Fatalf("%d is not equal to %d", status, http.StatusInternalServerError)
+	} else if arts, err := repos.Arts.GetArts(ctx); err != nil || len(arts) != 1 {
+		t.Fatalf("unexpected arts %v: %v", arts, err)
+	}
+
+	if status := SendAs(t, http.MethodPost, server.URL+"/arts/1/comments", "artist", dto.CommentDto{Body: "nice"}, nil); status != http.StatusInternalServerError {
+		t.Fatalf("%d is not equal to %d", status, http.StatusInternalServerError)
+	} else if comments, err := repos.Comments.GetComments(ctx, model.CommentFilter{ArtId: art.Id}, 0, 0); err != nil || len(comments) != 0 {
+		t.Fatalf("unexpected comments %v: %v", comments, err)
+	}
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+// Authenticates requests with Basic credentials.
+// Failed logins are counted per username and per client address, and both get locked out after too many of them.
+// Logins as usernames that don't exist are only counted per client address, so that nobody can lock out a
+// username before it is taken, and they fail like wrong passwords so that they don't tell which accounts exist.
+type Authenticator struct {
+	accountDB model.AccountRepository
+	guard     *LoginGuard
+	metrics   *Metrics
+}
+
+// The metrics may be nil.
+func (a *Authenticator) Init(accountDB model.AccountRepository, guard *LoginGuard, metrics *Metrics) error {
+	a.accountDB = accountDB
+	a.guard = guard
+	a.metrics = metrics
+	return nil
+}
+
+// The same for unknown usernames and wrong passwords.
+const badCredentials = "username or password is incorrect"
+
+// Calls f with the account the request is authenticated as.
+func (a Authenticator) AccountAuth(w http.ResponseWriter, r *http.Request, f func(dto.AccountDto)) {
+	if username, password, ok := r.BasicAuth(); !ok {
+		http.Error(w, "missing or malformed Authorization header", http.StatusUnauthorized)
+	} else if wait := a.lockout(r, username); wait > 0 {
+		tooManyRequests(w, wait, "too many failed logins, try again later")
+	} else if account, err := a.accountDB.GetAccountByUsername(r.Context(), username); errors.Is(err, model.ErrNotFound) {
+		a.fail(r, "")
+		http.Error(w, badCredentials, http.StatusUnauthorized)
+	} else if err != nil {
+		modelError(w, err)
+	} else if password != account.Password {
+		a.fail(r, username)
+		http.Error(w, badCredentials, http.StatusUnauthorized)
+	} else {
+		a.guard.Succeed("username:" + username)
+		a.metrics.authAttempt(true)
+		recordAccount(r, account.Id)
+		f(*account)
+	}
+}
+
+// Like AccountAuth but also lets requests without credentials through, calling f with nil for them.
+func (a Authenticator) OptionalAuth(w http.ResponseWriter, r *http.Request, f func(*dto.AccountDto)) {
+	if r.Header.Get("Authorization") == "" {
+		f(nil)
+	} else {
+		a.AccountAuth(w, r, func(account dto.AccountDto) {
+			f(&account)
+		})
+	}
+}
+
+// Like AccountAuth but only lets administrators through.
+func (a Authenticator) AdminAuth(w http.ResponseWriter, r *http.Request, f func(dto.AccountDto)) {
+	a.AccountAuth(w, r, func(account dto.AccountDto) {
+		if !account.Admin {
+			http.Error(w, "'"+account.Username+"' is not an administrator", http.StatusForbidden)
+		} else {
+			f(account)
+		}
+	})
+}
+
+func (a Authenticator) lockout(r *http.Request, username string) time.Duration {
+	byUsername := a.guard.Check("username:" + username)
+	byIP := a.guard.Check("ip:" + clientIP(r))
+	if byUsername > byIP {
+		return byUsername
+	}
+	return byIP
+}
+
+// Records a failed login from the address of r, and as username unless it is empty.
+func (a Authenticator) fail(r *http.Request, username string) {
+	a.metrics.authAttempt(false)
+	if username != "" {
+		a.guard.Fail("username:" + username)
+	}
+	a.guard.Fail("ip:" + clientIP(r))
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model/memory"
+)
+
+func TestAuthenticator(t *testing.T) {
+	memoryDB := &memory.DB{}
+	CheckError(t, memoryDB.Init())
+	repos, err := memoryDB.Repositories()
+	CheckError(t, err)
+	_, err = repos.Accounts.CreateAccount(context.Background(), dto.AccountDto{Username: "artist", Password: "password"})
+	CheckError(t, err)
+
+	guard := &LoginGuard{MaxFailures: 1}
+	CheckError(t, guard.Init())
+	auth := Authenticator{}
+	CheckError(t, auth.Init(repos.Accounts, guard, nil))
+
+	login := func(address string, username string, password string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/feed", nil)
+		r.RemoteAddr = address + ":1234"
+		r.SetBasicAuth(username, password)
+		w := httptest.NewRecorder()
+		auth.AccountAuth(w, r, func(dto.AccountDto) {})
+		return w
+	}
+
+	// unknown usernames and wrong passwords can't be told apart
+	unknown := login("192.0.2.1", "nobody", "password")
+	wrong := login("192.0.2.2", "artist", "wrong")
+	if unknown.Code != http.StatusUnauthorized || wrong.Code != http.StatusUnauthorized {
+		t.Fatalf("%d and %d are not equal to %d", unknown.Code, wrong.Code, http.StatusUnauthorized)
+	} else if unknown.Body.String() != wrong.Body.String() {
+		t.Fatalf("%q is not equal to %q", unknown.Body.String(), wrong.Body.String())
+	}
+
+	// failures on a username that doesn't exist only lock out the address they come from
+	if w := login("192.0.2.1", "nobody", "password"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("%d is not equal to %d", w.Code, http.StatusTooManyRequests)
+	} else if w := login("192.0.2.3", "nobody", "password"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("%d is not equal to %d", w.Code, http.StatusUnauthorized)
+	}
+
+	// failures on an existing username lock it out from everywhere
+	if w := login("192.0.2.4", "artist", "password"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("%d is not equal to %d", w.Code, http.StatusTooManyRequests)
+	}
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+// Lets administrators back the database up into a directory of the server.
+type BackupHandler struct {
+	db      *model.DB
+	dir     string
+	timeout time.Duration
+	auditDB model.AuditRepository
+	auth    Authenticator
+}
+
+// Backups are disabled if db is nil or dir is empty. A backup that takes longer than timeout is abandoned.
+func (h *BackupHandler) Init(db *model.DB, dir string, timeout time.Duration, auditDB model.AuditRepository, auth Authenticator) error {
+	h.db = db
+	h.dir = dir
+	h.timeout = timeout
+	h.auditDB = auditDB
+	h.auth = auth
+
+	return nil
+}
+
+// Writes a backup in the format named by the format query parameter: sqlite (the default) for a copy of the
+// database made with SQLite's online backup API, or json for a portable dump.
+// The backup outlives the request timeout and the timeouts of the server, up to the timeout of the handler.
+func (h BackupHandler) PostBackup(w http.ResponseWriter, r *http.Request, account dto.AccountDto) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if h.db == nil || h.dir == "" {
+		http.Error(w, "backups are not configured on this server", http.StatusServiceUnavailable)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "sqlite"
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+	clearReadDeadline(r)
+
+	backup := dto.BackupDto{Format: format, CreatedAt: time.Now().UTC()}
+	stamp := backup.CreatedAt.Format("20060102T150405.000000000Z")
+	var err error
+	switch format {
+	case "sqlite":
+		backup.File = "gallery-" + stamp + ".db"
+		err = h.db.Backup(ctx, filepath.Join(h.dir, backup.File))
+	case "json":
+		backup.File = "gallery-" + stamp + ".json"
+		err = dumpFile(ctx, h.db, filepath.Join(h.dir, backup.File))
+	default:
+		http.Error(w, fmt.Sprintf("unknown format '%s', expected sqlite or json", format), http.StatusBadRequest)
+		return
+	}
+	extendWriteDeadline(r)
+	if err != nil {
+		modelError(w, err)
+		return
+	}
+
+	path := filepath.Join(h.dir, backup.File)
+	info, err := os.Stat(path)
+	if err != nil {
+		modelError(w, err)
+		return
+	}
+	backup.Size = info.Size()
+
+	if err := recordAudit(h.auditDB, r, account.Id, "backup", "database", 0, nil, backup); err != nil {
+		// a backup that isn't in the audit trail isn't kept
+		os.Remove(path)
+		modelError(w, err)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(backup)
+	}
+}
+
+// Writes a dump of db to a new file at path, which only appears once complete.
+func dumpFile(ctx context.Context, db *model.DB, path string) error {
+	tmp := path + ".tmp"
+	defer os.Remove(tmp)
+
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	if err := db.Dump(ctx, file); err != nil {
+		file.Close()
+		return err
+	} else if err := file.Close(); err != nil {
+		return err
+	} else {
+		return os.Rename(tmp, path)
+	}
+}
+
+func (h BackupHandler) BackupFuncHandler(w http.ResponseWriter, r *http.Request) {
+	h.auth.AdminAuth(w, r, func(account dto.AccountDto) {
+		h.PostBackup(w, r, account)
+	})
+}
+
+// Registers the routes served by the handler on router.
+func (h BackupHandler) Routes(router *mux.Router) {
+	router.HandleFunc("/admin/backups", h.BackupFuncHandler).Methods(http.MethodPost)
+	router.HandleFunc("/admin/backups/", h.BackupFuncHandler).Methods(http.MethodPost)
+}
+
+func (h BackupHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	router := mux.NewRouter()
+	router.Use(recordRoute)
+	h.Routes(router)
+	router.ServeHTTP(w, r)
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestBackup(t *testing.T) {
+	dir := t.TempDir()
+	gormDB, err := gorm.Open(sqlite.Open("file:"+filepath.Join(dir, "gallery.db")+"?_foreign_keys=true"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	CheckError(t, err)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+
+	db := &model.DB{GormDB: gormDB}
+	migrator := model.Migrator{}
+	CheckError(t, migrator.Init(db))
+	_, err = migrator.Up(context.Background(), 0, false, nil)
+	CheckError(t, err)
+	repos, err := db.Repositories()
+	CheckError(t, err)
+
+	backupDir := filepath.Join(dir, "backups")
+	CheckError(t, os.Mkdir(backupDir, 0700))
+	server := newTestServer(t, testServerOptions{Gallery: GalleryHandler{BackupDir: backupDir}, Repos: repos})
+
+	ctx := context.Background()
+	admin, err := repos.Accounts.CreateAccount(ctx, dto.AccountDto{Username: "admin", Password: "password"})
+	CheckError(t, err)
+	_, err = repos.Accounts.SetAdmin(ctx, admin.Id, true)
+	CheckError(t, err)
+	_, err = repos.Accounts.CreateAccount(ctx, dto.AccountDto{Username: "user", Password: "password"})
+	CheckError(t, err)
+
+	for _, format := range []string{"sqlite", "json"} {
+		t.Run(format, func(t *testing.T) {
+			var backup dto.BackupDto
+			status := SendAs(t, http.MethodPost, server.URL+"/admin/backups?format="+format, "admin", nil, &backup)
+			if status != http.StatusCreated {
+				t.Fatalf("%d is not equal to %d", status, http.StatusCreated)
+			} else if backup.Format != format || backup.Size == 0 {
+				t.Fatalf("unexpected backup %v", backup)
+			} else if info, err := os.Stat(filepath.Join(backupDir, backup.File)); err != nil || info.Size() != backup.Size {
+				t.Fatalf("unexpected file %v: %v", info, err)
+			}
+		})
+	}
+
+	entries, err := repos.Audit.GetEntries(ctx, "database", 0)
+	CheckError(t, err)
+	if len(entries) != 2 || entries[0].Action != "backup" {
+		t.Fatalf("unexpected audit entries %v", entries)
+	}
+
+	t.Run("Administrators only", func(t *testing.T) {
+		if status := SendAs(t, http.MethodPost, server.URL+"/admin/backups", "user", nil, nil); status != http.StatusForbidden {
+			t.Fatalf("%d is not equal to %d", status, http.StatusForbidden)
+		}
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		if status := SendAs(t, http.MethodPost, server.URL+"/admin/backups?format=tar", "admin", nil, nil); status != http.StatusBadRequest {
+			t.Fatalf("%d is not equal to %d", status, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("Timeouts", func(t *testing.T) {
+		// the request timeout is too short for the authentication alone, let alone the backup
+		server := newTestServer(t, testServerOptions{Gallery: GalleryHandler{BackupDir: backupDir, RequestTimeout: time.Nanosecond}, Repos: repos})
+		if status := SendAs(t, http.MethodPost, server.URL+"/admin/backups", "admin", nil, nil); status != http.StatusCreated {
+			t.Fatalf("%d is not equal to %d", status, http.StatusCreated)
+		}
+
+		server = newTestServer(t, testServerOptions{Gallery: GalleryHandler{BackupDir: backupDir, BackupTimeout: time.Nanosecond}, Repos: repos})
+		if status := SendAs(t, http.MethodPost, server.URL+"/admin/backups", "admin", nil, nil); status != http.StatusInternalServerError {
+			t.Fatalf("%d is not equal to %d", status, http.StatusInternalServerError)
+		}
+	})
+
+	t.Run("Not configured", func(t *testing.T) {
+		server := newTestServer(t, testServerOptions{Gallery: GalleryHandler{BackupDir: backupDir}})
+		admin, err := server.Repos.Accounts.CreateAccount(ctx, dto.AccountDto{Username: "admin", Password: "password"})
+		CheckError(t, err)
+		_, err = server.Repos.Accounts.SetAdmin(ctx, admin.Id, true)
+		CheckError(t, err)
+
+		if status := SendAs(t, http.MethodPost, server.URL+"/admin/backups", "admin", nil, nil); status != http.StatusServiceUnavailable {
+			t.Fatalf("%d is not equal to %d", status, http.StatusServiceUnavailable)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/events"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+const (
+	// Largest batch body accepted.
+	maxBatchBytes = 1 << 20
+	// Most operations a single batch may have.
+	maxBatchOperations = 1000
+)
+
+var errBatchOperation = errors.New("invalid operation")
+
+// Events published for the operations of a batch, by their status in the report.
+var batchEvents = map[string]string{
+	"created": events.ArtCreated,
+	"updated": events.ArtUpdated,
+	"deleted": events.ArtDeleted,
+}
+
+// Creates, updates and deletes arts on behalf of account in one transaction and reports the outcome of each operation.
+//
+// Updated and deleted arts must belong to account, as with PUT and DELETE on /arts/{id}. Operations run in order and
+// the first one that fails rolls back the whole batch; the response then has the status code of its error.
+func (h ArtsHandler) PostBatch(w http.ResponseWriter, r *http.Request, account dto.AccountDto) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var batch dto.BatchDto
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&batch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if len(batch.Operations) == 0 || len(batch.Operations) > maxBatchOperations {
+		http.Error(w, fmt.Sprintf("a batch must have between 1 and %d operations", maxBatchOperations), http.StatusBadRequest)
+		return
+	}
+
+	report := dto.BatchReportDto{Results: []dto.BatchResultDto{}}
+	for _, op := range batch.Operations {
+		report.Results = append(report.Results, dto.BatchResultDto{Op: op.Op, Status: "skipped"})
+	}
+
+	recorded := []events.Event{}
+	var failure error
+	err := h.repos.Transaction(r.Context(), func(tx model.Repositories) error {
+		for i, op := range batch.Operations {
+			result := &report.Results[i]
+			art, before, status, err := runBatchOperation(r, tx, account, op)
+			if err != nil {
+				result.Status = "failed"
+				result.Error = err.Error()
+				failure = err
+				return err
+			}
+			if art != nil && status != "deleted" {
+				if err := withArtLikes(r.Context(), tx.Likes, &account, art); err != nil {
+					return err
+				}
+			}
+			result.Status = status
+			result.Art = art
+
+			if eventType, ok := batchEvents[result.Status]; ok {
+				event := events.Event{Type: eventType, ActorId: account.Id, Art: result.Art, Before: before}
+				if err := h.outbox.Record(r.Context(), tx, &event); err != nil {
+					return err
+				}
+				recorded = append(recorded, event)
+			}
+		}
+		return nil
+	})
+
+	if err == nil {
+		// the events are only published once the batch is committed
+		for _, event := range recorded {
+			h.outbox.Publish(r.Context(), event)
+		}
+		json.NewEncoder(w).Encode(report)
+	} else if failure == nil {
+		modelError(w, err)
+	} else {
+		for i := range report.Results {
+			if result := &report.Results[i]; result.Status != "failed" && result.Status != "skipped" {
+				result.Status = "rolled_back"
+				result.Art = nil
+			}
+		}
+
+		status := modelStatus(failure)
+		if errors.Is(failure, errBatchOperation) {
+			status = http.StatusUnprocessableEntity
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+// Runs op in tx on behalf of account and returns the art it touched, the art as it was before if op is an update,
+// and the status of op in the report.
+func runBatchOperation(r *http.Request, tx model.Repositories, account dto.AccountDto, op dto.BatchOperationDto) (*dto.ArtDto, *dto.ArtDto, string, error) {
+	ctx := r.Context()
+
+	switch op.Op {
+	case "create":
+		if op.Art == nil {
+			return nil, nil, "", fmt.Errorf("%w: create needs an art", errBatchOperation)
+		}
+
+		art := *op.Art
+		art.Id = 0
+		art.AuthorId = account.Id
+		created, err := tx.Arts.CreateArt(ctx, art)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		if err := recordAudit(tx.Audit, r, account.Id, "create", "art", created.Id, nil, created); err != nil {
+			return nil, nil, "", err
+		}
+		return created, nil, "created", nil
+	case "update":
+		if op.Id == 0 || op.Art == nil {
+			return nil, nil, "", fmt.Errorf("%w: update needs an id and an art", errBatchOperation)
+		}
+
+		before, err := authorArt(ctx, tx.Arts, account, op.Id)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		art := *op.Art
+		art.Id = op.Id
+		art.AuthorId = account.Id
+		updated, err := tx.Arts.UpdateArt(ctx, art)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		if err := recordAudit(tx.Audit, r, account.Id, "update", "art", updated.Id, before, updated); err != nil {
+			return nil, nil, "", err
+		}
+		return updated, before, "updated", nil
+	case "delete":
+		if op.Id == 0 {
+			return nil, nil, "", fmt.Errorf("%w: delete needs an id", errBatchOperation)
+		}
+
+		if _, err := authorArt(ctx, tx.Arts, account, op.Id); err != nil {
+			return nil, nil, "", err
+		}
+		deleted, err := tx.Arts.DeleteArt(ctx, op.Id)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		if err := recordAudit(tx.Audit, r, account.Id, "delete", "art", deleted.Id, deleted, nil); err != nil {
+			return nil, nil, "", err
+		}
+		return deleted, nil, "deleted", nil
+	default:
+		return nil, nil, "", fmt.Errorf("%w: unknown op '%s', expected create, update or delete", errBatchOperation, op.Op)
+	}
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/nafiz1001/gallery-go/dto"
+)
+
+func batchStatuses(report dto.BatchReportDto) string {
+	s := []string{}
+	for _, result := range report.Results {
+		s = append(s, result.Op+":"+result.Status)
+	}
+	return fmt.Sprint(s)
+}
+
+func TestBatch(t *testing.T) {
+	server := newTestServer(t, testServerOptions{})
+	repos := server.Repos
+	url := server.URL + "/arts/batch"
+
+	ctx := context.Background()
+	artist, err := repos.Accounts.CreateAccount(ctx, dto.AccountDto{Username: "artist", Password: "password"})
+	CheckError(t, err)
+	other, err := repos.Accounts.CreateAccount(ctx, dto.AccountDto{Username: "other", Password: "password"})
+	CheckError(t, err)
+
+	createArt := func(author *dto.AccountDto, title string) *dto.ArtDto {
+		art, err := repos.Arts.CreateArt(ctx, dto.ArtDto{Title: title, Quantity: 1, AuthorId: author.Id})
+		CheckError(t, err)
+		return art
+	}
+	countArts := func() int64 {
+		count, err := repos.Arts.CountArts(ctx)
+		CheckError(t, err)
+		return count
+	}
+
+	kept := createArt(artist, "kept")
+	removed := createArt(artist, "removed")
+	othersArt := createArt(other, "other's")
+
+	t.Run("Every operation is applied", func(t *testing.T) {
+		var report dto.BatchReportDto
+		status := SendAs(t, http.MethodPost, url, "artist", dto.BatchDto{Operations: []dto.BatchOperationDto{
+			{Op: "create", Art: &dto.ArtDto{Title: "new", Quantity: 2, AuthorId: other.Id}},
+			{Op: "update", Id: kept.Id, Art: &dto.ArtDto{Title: "renamed", Quantity: 3}},
+			{Op: "delete", Id: removed.Id},
+		}}, &report)
+
+		expected := "[create:created update:updated delete:deleted]"
+		if status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if s := batchStatuses(report); s != expected {
+			t.Fatalf("%s is not equal to %s", s, expected)
+		} else if art := report.Results[0].Art; art == nil || art.Title != "new" || art.AuthorId != artist.Id {
+			t.Fatalf("unexpected art %v", art)
+		} else if art, err := repos.Arts.GetArt(ctx, kept.Id); err != nil || art.Title != "renamed" || art.Quantity != 3 {
+			t.Fatalf("unexpected art %v: %v", art, err)
+		} else if _, err := repos.Arts.GetArt(ctx, removed.Id); err == nil {
+			t.Fatalf("art #%d was not deleted", removed.Id)
+		}
+
+		entries, err := repos.Audit.GetEntries(ctx, "art", kept.Id)
+		CheckError(t, err)
+		if len(entries) != 1 || entries[0].Action != "update" {
+			t.Fatalf("unexpected audit entries %v", entries)
+		}
+	})
+
+	for _, test := range []struct {
+		name       string
+		operations []dto.BatchOperationDto
+		status     int
+		expected   string
+	}{
+		{
+			"Arts of other accounts can't be changed",
+			[]dto.BatchOperationDto{
+				{Op: "create", Art: &dto.ArtDto{Title: "rolled back", Quantity: 1}},
+				{Op: "delete", Id: othersArt.Id},
+				{Op: "update", Id: kept.Id, Art: &dto.ArtDto{Title: "skipped"}},
+			},
+			http.StatusUnauthorized,
+			"[create:rolled_back delete:failed update:skipped]",
+		},
+		{
+			"Missing arts",
+			[]dto.BatchOperationDto{
+				{Op: "update", Id: kept.Id, Art: &dto.ArtDto{Title: "rolled back"}},
+				{Op: "update", Id: 1000, Art: &dto.ArtDto{Title: "missing"}},
+			},
+			http.StatusNotFound,
+			"[update:rolled_back update:failed]",
+		},
+		{
+			"Invalid quantity",
+			[]dto.BatchOperationDto{{Op: "create", Art: &dto.ArtDto{Title: "negative", Quantity: -1}}},
+			http.StatusUnprocessableEntity,
+			"[create:failed]",
+		},
+		{
+			"Invalid operations",
+			[]dto.BatchOperationDto{{Op: "delete", Id: kept.Id}, {Op: "move", Id: kept.Id}, {Op: "create"}},
+			http.StatusUnprocessableEntity,
+			"[delete:rolled_back move:failed create:skipped]",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			before := countArts()
+			var report dto.BatchReportDto
+			status := SendAs(t, http.MethodPost, url, "artist", dto.BatchDto{Operations: test.operations}, &report)
+			if status != test.status {
+				t.Fatalf("%d is not equal to %d", status, test.status)
+			} else if s := batchStatuses(report); s != test.expected {
+				t.Fatalf("%s is not equal to %s", s, test.expected)
+			} else if after := countArts(); after != before {
+				t.Fatalf("%d arts instead of %d", after, before)
+			} else if art, err := repos.Arts.GetArt(ctx, kept.Id); err != nil || art.Title != "renamed" {
+				t.Fatalf("unexpected art %v: %v", art, err)
+			}
+		})
+	}
+
+	t.Run("Bad requests", func(t *testing.T) {
+		if status := SendAs(t, http.MethodPost, url, "artist", dto.BatchDto{}, nil); status != http.StatusBadRequest {
+			t.Fatalf("%d is not equal to %d", status, http.StatusBadRequest)
+		}
+
+		batch := dto.BatchDto{Operations: []dto.BatchOperationDto{{Op: "delete", Id: 1}}}
+		if status := SendAs(t, http.MethodPost, url, "", batch, nil); status != http.StatusUnauthorized {
+			t.Fatalf("%d is not equal to %d", status, http.StatusUnauthorized)
+		}
+	})
+}
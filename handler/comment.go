@@ -0,0 +1,336 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/events"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+// Largest page of comments a client can ask for.
+const maxCommentsLimit = 1000
+
+// Administrators moderate the comments, and are the only ones to see hidden comments.
+func isModerator(viewer *dto.AccountDto) bool {
+	return viewer != nil && viewer.Admin
+}
+
+// Parses the after and limit query parameters paging through comments, responding with an error if they are invalid.
+func commentsPage(w http.ResponseWriter, r *http.Request) (uint, int, bool) {
+	var after, limit uint64
+	if s := r.URL.Query().Get("after"); s != "" {
+		var err error
+		if after, err = strconv.ParseUint(s, 10, 32); err != nil {
+			http.Error(w, "after must be a comment id", http.StatusBadRequest)
+			return 0, 0, false
+		}
+	}
+	if s := r.URL.Query().Get("limit"); s != "" {
+		var err error
+		if limit, err = strconv.ParseUint(s, 10, 32); err != nil || limit == 0 || limit > maxCommentsLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxCommentsLimit), http.StatusBadRequest)
+			return 0, 0, false
+		}
+	}
+	return uint(after), int(limit), true
+}
+
+func validCommentStatus(status string) bool {
+	return status == dto.CommentVisible || status == dto.CommentReported || status == dto.CommentHidden
+}
+
+// Gets the comment with id on the art with artId. Comments viewer may not see are reported as missing.
+func (h ArtsHandler) artComment(ctx context.Context, artId uint, id uint, viewer *dto.AccountDto) (*dto.CommentDto, error) {
+	if _, err := h.artDB.GetArt(ctx, artId); err != nil {
+		return nil, err
+	} else if comment, err := h.commentDB.GetComment(ctx, id); err != nil {
+		return nil, err
+	} else if comment.ArtId != artId || (comment.Status == dto.CommentHidden && !isModerator(viewer)) {
+		return nil, model.ErrNotFound
+	} else {
+		return comment, nil
+	}
+}
+
+// Comments on the art on behalf of account, or replies to the comment given by parent_id.
+func (h ArtsHandler) PostComment(w http.ResponseWriter, r *http.Request, artId uint, account dto.AccountDto) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if comment, err := dto.DecodeComment(r.Body); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	} else {
+		comment.ArtId = artId
+		comment.AuthorId = account.Id
+		err := h.outbox.Transaction(r.Context(), h.repos, func(tx model.Repositories) ([]events.Event, error) {
+			var err error
+			if comment, err = tx.Comments.CreateComment(r.Context(), *comment); err != nil {
+				return nil, err
+			} else if err := recordAudit(tx.Audit, r, account.Id, "create", "comment", comment.Id, nil, comment); err != nil {
+				return nil, err
+			} else if art, err := tx.Arts.GetArt(r.Context(), artId); err != nil {
+				return nil, err
+			} else {
+				return []events.Event{{Type: events.ArtCommented, ActorId: account.Id, Art: art, Comment: comment}}, nil
+			}
+		})
+		if err != nil {
+			modelError(w, err)
+		} else {
+			json.NewEncoder(w).Encode(comment)
+		}
+	}
+}
+
+// Lists the comments on the art by id, replies included, paged like the arts.
+// Hidden comments are left out unless viewer is a moderator. The optional status query parameter only lists comments in that state.
+func (h ArtsHandler) GetComments(w http.ResponseWriter, r *http.Request, artId uint, viewer *dto.AccountDto) {
+	w.Header().Set("Content-Type", "application/json")
+
+	after, limit, ok := commentsPage(w, r)
+	if !ok {
+		return
+	}
+
+	filter := model.CommentFilter{ArtId: artId}
+	if status := r.URL.Query().Get("status"); status != "" {
+		if !validCommentStatus(status) {
+			http.Error(w, "status must be visible, reported or hidden", http.StatusBadRequest)
+			return
+		} else if status == dto.CommentHidden && !isModerator(viewer) {
+			http.Error(w, "only moderators see hidden comments", http.StatusForbidden)
+			return
+		}
+		filter.Statuses = []string{status}
+	} else if !isModerator(viewer) {
+		filter.Statuses = []string{dto.CommentVisible, dto.CommentReported}
+	}
+
+	if _, err := h.artDB.GetArt(r.Context(), artId); err != nil {
+		modelError(w, err)
+	} else if comments, err := h.commentDB.GetComments(r.Context(), filter, after, limit); err != nil {
+		modelError(w, err)
+	} else {
+		json.NewEncoder(w).Encode(comments)
+	}
+}
+
+func (h ArtsHandler) GetComment(w http.ResponseWriter, r *http.Request, artId uint, id uint, viewer *dto.AccountDto) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if comment, err := h.artComment(r.Context(), artId, id, viewer); err != nil {
+		modelError(w, err)
+	} else {
+		json.NewEncoder(w).Encode(comment)
+	}
+}
+
+// Replaces the body of a comment. Only its author may edit it.
+func (h ArtsHandler) PutComment(w http.ResponseWriter, r *http.Request, artId uint, id uint, account dto.AccountDto) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if before, err := h.artComment(r.Context(), artId, id, &account); err != nil {
+		modelError(w, err)
+	} else if before.AuthorId != account.Id {
+		modelError(w, notCommenterError{commentId: id, username: account.Username})
+	} else if edit, err := dto.DecodeComment(r.Body); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	} else {
+		var comment *dto.CommentDto
+		err := h.repos.Transaction(r.Context(), func(tx model.Repositories) error {
+			var err error
+			if comment, err = tx.Comments.UpdateComment(r.Context(), id, edit.Body); err != nil {
+				return err
+			}
+			return recordAudit(tx.Audit, r, account.Id, "update", "comment", id, before, comment)
+		})
+		if err != nil {
+			modelError(w, err)
+		} else {
+			json.NewEncoder(w).Encode(comment)
+		}
+	}
+}
+
+// Returns a notCommenterError unless account is the author of comment or of the art with artId.
+func (h ArtsHandler) canDeleteComment(ctx context.Context, artId uint, comment dto.CommentDto, account dto.AccountDto) error {
+	if comment.AuthorId == account.Id {
+		return nil
+	} else if _, err := authorArt(ctx, h.artDB, account, artId); errors.As(err, &notAuthorError{}) {
+		return notCommenterError{commentId: comment.Id, username: account.Username}
+	} else {
+		return err
+	}
+}
+
+// Deletes a comment along with the replies to it. Its author may delete it, and so may the author of the art.
+func (h ArtsHandler) DeleteComment(w http.ResponseWriter, r *http.Request, artId uint, id uint, account dto.AccountDto) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if before, err := h.artComment(r.Context(), artId, id, &account); err != nil {
+		modelError(w, err)
+	} else if err := h.canDeleteComment(r.Context(), artId, *before, account); err != nil {
+		modelError(w, err)
+	} else {
+		var comment *dto.CommentDto
+		err := h.repos.Transaction(r.Context(), func(tx model.Repositories) error {
+			var err error
+			if comment, err = tx.Comments.DeleteComment(r.Context(), id); err != nil {
+				return err
+			}
+			return recordAudit(tx.Audit, r, account.Id, "delete", "comment", id, comment, nil)
+		})
+		if err != nil {
+			modelError(w, err)
+		} else {
+			json.NewEncoder(w).Encode(comment)
+		}
+	}
+}
+
+// Reports a comment to the moderators on behalf of account.
+func (h ArtsHandler) PostReport(w http.ResponseWriter, r *http.Request, artId uint, id uint, account dto.AccountDto) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if before, err := h.artComment(r.Context(), artId, id, &account); err != nil {
+		modelError(w, err)
+	} else {
+		var comment *dto.CommentDto
+		err := h.repos.Transaction(r.Context(), func(tx model.Repositories) error {
+			var err error
+			if comment, err = tx.Comments.ReportComment(r.Context(), id); err != nil {
+				return err
+			} else if comment.Status != before.Status {
+				return recordAudit(tx.Audit, r, account.Id, "report", "comment", id, before, comment)
+			} else {
+				return nil
+			}
+		})
+		if err != nil {
+			modelError(w, err)
+		} else {
+			json.NewEncoder(w).Encode(comment)
+		}
+	}
+}
+
+// Sets the moderation state of a comment, hiding it or dismissing the reports against it.
+func (h ArtsHandler) PutCommentStatus(w http.ResponseWriter, r *http.Request, artId uint, id uint, moderator dto.AccountDto) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if update, err := dto.DecodeComment(r.Body); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	} else if !validCommentStatus(update.Status) {
+		http.Error(w, "status must be visible, reported or hidden", http.StatusUnprocessableEntity)
+	} else if before, err := h.artComment(r.Context(), artId, id, &moderator); err != nil {
+		modelError(w, err)
+	} else {
+		var comment *dto.CommentDto
+		err := h.repos.Transaction(r.Context(), func(tx model.Repositories) error {
+			var err error
+			if comment, err = tx.Comments.SetCommentStatus(r.Context(), id, update.Status); err != nil {
+				return err
+			}
+			return recordAudit(tx.Audit, r, moderator.Id, "moderate", "comment", id, before, comment)
+		})
+		if err != nil {
+			modelError(w, err)
+		} else {
+			json.NewEncoder(w).Encode(comment)
+		}
+	}
+}
+
+// Lists the comments of every art in the state given by the status query parameter, reported ones by default,
+// which is where moderators find the comments waiting for them.
+func (h ArtsHandler) GetModerationQueue(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = dto.CommentReported
+	} else if !validCommentStatus(status) {
+		http.Error(w, "status must be visible, reported or hidden", http.StatusBadRequest)
+		return
+	}
+
+	after, limit, ok := commentsPage(w, r)
+	if !ok {
+		return
+	}
+
+	if comments, err := h.commentDB.GetComments(r.Context(), model.CommentFilter{Statuses: []string{status}}, after, limit); err != nil {
+		modelError(w, err)
+	} else {
+		json.NewEncoder(w).Encode(comments)
+	}
+}
+
+func (h ArtsHandler) CommentsFuncHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 10, 32)
+
+	switch r.Method {
+	case http.MethodPost:
+		h.AccountAuth(w, r, func(account dto.AccountDto) {
+			h.PostComment(w, r, uint(id), account)
+		})
+	case http.MethodGet:
+		h.auth.OptionalAuth(w, r, func(viewer *dto.AccountDto) {
+			h.GetComments(w, r, uint(id), viewer)
+		})
+	}
+}
+
+func (h ArtsHandler) CommentByIdFuncHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 10, 32)
+	commentId, _ := strconv.ParseInt(vars["comment"], 10, 32)
+
+	switch r.Method {
+	case http.MethodGet:
+		h.auth.OptionalAuth(w, r, func(viewer *dto.AccountDto) {
+			h.GetComment(w, r, uint(id), uint(commentId), viewer)
+		})
+	case http.MethodPut:
+		h.AccountAuth(w, r, func(account dto.AccountDto) {
+			h.PutComment(w, r, uint(id), uint(commentId), account)
+		})
+	case http.MethodDelete:
+		h.AccountAuth(w, r, func(account dto.AccountDto) {
+			h.DeleteComment(w, r, uint(id), uint(commentId), account)
+		})
+	}
+}
+
+func (h ArtsHandler) ReportFuncHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 10, 32)
+	commentId, _ := strconv.ParseInt(vars["comment"], 10, 32)
+
+	h.AccountAuth(w, r, func(account dto.AccountDto) {
+		h.PostReport(w, r, uint(id), uint(commentId), account)
+	})
+}
+
+func (h ArtsHandler) ModerationFuncHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 10, 32)
+	commentId, _ := strconv.ParseInt(vars["comment"], 10, 32)
+
+	h.auth.AdminAuth(w, r, func(moderator dto.AccountDto) {
+		h.PutCommentStatus(w, r, uint(id), uint(commentId), moderator)
+	})
+}
+
+func (h ArtsHandler) ModerationQueueFuncHandler(w http.ResponseWriter, r *http.Request) {
+	h.auth.AdminAuth(w, r, func(_ dto.AccountDto) {
+		h.GetModerationQueue(w, r)
+	})
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+// Arts that can't be read.
+type unreadableArts struct {
+	model.ArtRepository
+}
+
+func (unreadableArts) GetArt(ctx context.Context, id uint) (*dto.ArtDto, error) {
+	return nil, errors.New("database is gone")
+}
+
+func TestComments(t *testing.T) {
+	server := newTestServer(t, testServerOptions{})
+	repos := server.Repos
+
+	ctx := context.Background()
+	for _, username := range []string{"artist", "commenter", "other", "moderator"} {
+		account, err := repos.Accounts.CreateAccount(ctx, dto.AccountDto{Username: username, Password: "password"})
+		CheckError(t, err)
+		if username == "moderator" {
+			_, err = repos.Accounts.SetAdmin(ctx, account.Id, true)
+			CheckError(t, err)
+		}
+	}
+	_, err := repos.Arts.CreateArt(ctx, dto.ArtDto{Title: "title", Quantity: 1, AuthorId: 1})
+	CheckError(t, err)
+	commentsURL := server.URL + "/arts/1/comments"
+
+	var comment, reply dto.CommentDto
+	t.Run("Comment and reply", func(t *testing.T) {
+		if status := SendAs(t, http.MethodPost, commentsURL, "commenter", dto.CommentDto{Body: "nice"}, &comment); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if comment.AuthorId != 2 || comment.ArtId != 1 || comment.Status != dto.CommentVisible {
+			t.Fatalf("unexpected comment %v", comment)
+		} else if status := SendAs(t, http.MethodPost, commentsURL, "artist", dto.CommentDto{Body: "thanks", ParentId: comment.Id}, &reply); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if reply.ParentId != comment.Id {
+			t.Fatalf("unexpected reply %v", reply)
+		}
+
+		var comments []dto.CommentDto
+		if status := SendAs(t, http.MethodGet, commentsURL+"?limit=1", "", nil, &comments); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if len(comments) != 1 || comments[0].Id != comment.Id {
+			t.Fatalf("unexpected comments %v", comments)
+		} else if status := SendAs(t, http.MethodGet, commentsURL+"?after=1", "", nil, &comments); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if len(comments) != 1 || comments[0].Id != reply.Id {
+			t.Fatalf("unexpected comments %v", comments)
+		}
+	})
+
+	t.Run("Only the author edits", func(t *testing.T) {
+		var edited dto.CommentDto
+		if status := SendAs(t, http.MethodPut, commentsURL+"/1", "artist", dto.CommentDto{Body: "mine now"}, nil); status != http.StatusUnauthorized {
+			t.Fatalf("%d is not equal to %d", status, http.StatusUnauthorized)
+		} else if status := SendAs(t, http.MethodPut, commentsURL+"/1", "commenter", dto.CommentDto{Body: "very nice"}, &edited); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if edited.Body != "very nice" {
+			t.Fatalf("unexpected comment %v", edited)
+		}
+	})
+
+	t.Run("Moderation", func(t *testing.T) {
+		var reported, hidden dto.CommentDto
+		var queue []dto.CommentDto
+		if status := SendAs(t, http.MethodPost, commentsURL+"/1/report", "other", nil, &reported); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if reported.Status != dto.CommentReported {
+			t.Fatalf("unexpected comment %v", reported)
+		} else if status := SendAs(t, http.MethodGet, server.URL+"/comments", "other", nil, nil); status != http.StatusForbidden {
+			t.Fatalf("%d is not equal to %d", status, http.StatusForbidden)
+		} else if status := SendAs(t, http.MethodGet, server.URL+"/comments", "moderator", nil, &queue); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if len(queue) != 1 || queue[0].Id != comment.Id {
+			t.Fatalf("unexpected queue %v", queue)
+		}
+
+		if status := SendAs(t, http.MethodPut, commentsURL+"/1/status", "artist", dto.CommentDto{Status: dto.CommentHidden}, nil); status != http.StatusForbidden {
+			t.Fatalf("%d is not equal to %d", status, http.StatusForbidden)
+		} else if status := SendAs(t, http.MethodPut, commentsURL+"/1/status", "moderator", dto.CommentDto{Status: "gone"}, nil); status != http.StatusUnprocessableEntity {
+			t.Fatalf("%d is not equal to %d", status, http.StatusUnprocessableEntity)
+		} else if status := SendAs(t, http.MethodPut, commentsURL+"/1/status", "moderator", dto.CommentDto{Status: dto.CommentHidden}, &hidden); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if hidden.Status != dto.CommentHidden {
+			t.Fatalf("unexpected comment %v", hidden)
+		}
+
+		// hidden comments are only seen by moderators
+		for _, test := range []struct {
+			username string
+			count    int
+			status   int
+		}{{"", 1, http.StatusNotFound}, {"commenter", 1, http.StatusNotFound}, {"moderator", 2, http.StatusOK}} {
+			var comments []dto.CommentDto
+			if status := SendAs(t, http.MethodGet, commentsURL, test.username, nil, &comments); status != http.StatusOK {
+				t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+			} else if len(comments) != test.count {
+				t.Fatalf("'%s': unexpected comments %v", test.username, comments)
+			} else if status := SendAs(t, http.MethodGet, commentsURL+"/1", test.username, nil, nil); status != test.status {
+				t.Fatalf("'%s': %d is not equal to %d", test.username, status, test.status)
+			}
+		}
+		if status := SendAs(t, http.MethodGet, commentsURL+"?status=hidden", "other", nil, nil); status != http.StatusForbidden {
+			t.Fatalf("%d is not equal to %d", status, http.StatusForbidden)
+		}
+
+		entries, err := repos.Audit.GetEntries(ctx, "comment", comment.Id)
+		CheckError(t, err)
+		if len(entries) != 4 || entries[2].Action != "report" || entries[3].Action != "moderate" {
+			t.Fatalf("unexpected audit entries %v", entries)
+		}
+	})
+
+	t.Run("The author of the art deletes", func(t *testing.T) {
+		var other dto.CommentDto
+		if status := SendAs(t, http.MethodPost, commentsURL, "other", dto.CommentDto{Body: "meh"}, &other); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if status := SendAs(t, http.MethodDelete, commentsURL+"/3", "commenter", nil, nil); status != http.StatusUnauthorized {
+			t.Fatalf("%d is not equal to %d", status, http.StatusUnauthorized)
+		} else if status := SendAs(t, http.MethodDelete, commentsURL+"/3", "artist", nil, nil); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if status := SendAs(t, http.MethodDelete, commentsURL+"/3", "artist", nil, nil); status != http.StatusNotFound {
+			t.Fatalf("%d is not equal to %d", status, http.StatusNotFound)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for _, test := range []struct {
+			method   string
+			url      string
+			username string
+			body     interface{}
+			status   int
+		}{
+			{http.MethodPost, commentsURL, "", dto.CommentDto{Body: "body"}, http.StatusUnauthorized},
+			{http.MethodPost, commentsURL, "other", dto.CommentDto{Body: ""}, http.StatusUnprocessableEntity},
+			{http.MethodPost, commentsURL, "other", dto.CommentDto{Body: "body", ParentId: 100}, http.StatusUnprocessableEntity},
+			{http.MethodPost, server.URL + "/arts/100/comments", "other", dto.CommentDto{Body: "body"}, http.StatusNotFound},
+			{http.MethodGet, server.URL + "/arts/100/comments", "", nil, http.StatusNotFound},
+			{http.MethodGet, server.URL + "/arts/100/comments/2", "", nil, http.StatusNotFound},
+			{http.MethodGet, commentsURL + "?limit=0", "", nil, http.StatusBadRequest},
+			{http.MethodGet, commentsURL + "?status=gone", "", nil, http.StatusBadRequest},
+			{http.MethodPost, commentsURL + "/100/report", "other", nil, http.StatusNotFound},
+		} {
+			if status := SendAs(t, test.method, test.url, test.username, test.body, nil); status != test.status {
+				t.Fatalf("%s %s as '%s': %d is not equal to %d", test.method, test.url, test.username, status, test.status)
+			}
+		}
+	})
+}
+
+func TestCanDeleteComment(t *testing.T) {
+	h := ArtsHandler{artDB: unreadableArts{}}
+	comment := dto.CommentDto{Id: 1, ArtId: 1, AuthorId: 1}
+
+	if err := h.canDeleteComment(context.Background(), 1, comment, dto.AccountDto{Id: 1}); err != nil {
+		t.Fatal(err)
+	}
+	// the art can't be read to find out whether the account is its author, which isn't the fault of the client
+	if err := h.canDeleteComment(context.Background(), 1, comment, dto.AccountDto{Id: 2}); err == nil || modelStatus(err) != http.StatusInternalServerError {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+// Responds with an error returned by the model and the status code matching it.
+func modelError(w http.ResponseWriter, err error) {
+	http.Error(w, err.Error(), modelStatus(err))
+}
+
+// The status code matching an error returned by the model.
+func modelStatus(err error) int {
+	if errors.Is(err, model.ErrNotFound) {
+		return http.StatusNotFound
+	} else if errors.Is(err, model.ErrUsernameTaken) || errors.Is(err, model.ErrOutOfStock) {
+		return http.StatusConflict
+	} else if errors.Is(err, model.ErrInvalidQuantity) || errors.Is(err, model.ErrInvalidSale) || errors.Is(err, model.ErrInvalidComment) || errors.Is(err, model.ErrInvalidParent) ||
+		errors.Is(err, model.ErrSelfFollow) || errors.Is(err, model.ErrInvalidWebhook) {
+		return http.StatusUnprocessableEntity
+	} else if errors.Is(err, model.ErrInvalidCursor) {
+		return http.StatusBadRequest
+	} else if errors.As(err, &notAuthorError{}) || errors.As(err, &notCommenterError{}) {
+		return http.StatusUnauthorized
+	} else {
+		return http.StatusInternalServerError
+	}
+}
+
+// Returned when an account changes an art it is not the author of.
+type notAuthorError struct {
+	artId    uint
+	username string
+}
+
+func (e notAuthorError) Error() string {
+	return fmt.Sprintf("art #%d does not belong to '%s'", e.artId, e.username)
+}
+
+// Returned when an account changes a comment it is not the author of.
+type notCommenterError struct {
+	commentId uint
+	username  string
+}
+
+func (e notCommenterError) Error() string {
+	return fmt.Sprintf("comment #%d does not belong to '%s'", e.commentId, e.username)
+}
@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+// Arts written between flushes of an export.
+const exportFlushRows = 100
+
+// Columns of a CSV export, in order. An export can be imported back as is.
+var exportColumns = []string{"id", "title", "quantity", "author_id", "author_username"}
+
+// Writes exported arts one at a time.
+type exportWriter interface {
+	Write(art dto.ArtExportDto) error
+	// Writes anything left once every art has been written.
+	Close() error
+}
+
+type csvExportWriter struct {
+	w *csv.Writer
+}
+
+func newCSVExportWriter(w io.Writer) (*csvExportWriter, error) {
+	writer := &csvExportWriter{w: csv.NewWriter(w)}
+	return writer, writer.w.Write(exportColumns)
+}
+
+func (c *csvExportWriter) Write(art dto.ArtExportDto) error {
+	return c.w.Write([]string{
+		strconv.FormatUint(uint64(art.Id), 10),
+		art.Title,
+		strconv.Itoa(art.Quantity),
+		strconv.FormatUint(uint64(art.AuthorId), 10),
+		art.AuthorUsername,
+	})
+}
+
+func (c *csvExportWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonExportWriter struct {
+	encoder *json.Encoder
+}
+
+func (n ndjsonExportWriter) Write(art dto.ArtExportDto) error {
+	return n.encoder.Encode(art)
+}
+
+func (n ndjsonExportWriter) Close() error {
+	return nil
+}
+
+// Writes a JSON array an element at a time.
+type jsonExportWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (j *jsonExportWriter) Write(art dto.ArtExportDto) error {
+	b, err := json.Marshal(art)
+	if err != nil {
+		return err
+	}
+
+	separator := ",\n"
+	if j.count == 0 {
+		separator = "[\n"
+	}
+	j.count++
+	if _, err := io.WriteString(j.w, separator); err != nil {
+		return err
+	}
+	_, err = j.w.Write(b)
+	return err
+}
+
+func (j *jsonExportWriter) Close() error {
+	closing := "\n]\n"
+	if j.count == 0 {
+		closing = "[]\n"
+	}
+	_, err := io.WriteString(j.w, closing)
+	return err
+}
+
+// Streams every art matching the author_id and title query parameters as CSV, NDJSON or a JSON array,
+// chosen by the format query parameter. Arts are read from the database one row at a time and the response is
+// flushed every exportFlushRows arts, so the catalog is never held in memory. The export outlives the request
+// timeout, and the write timeout of the server as long as each art is written within streamWriteTimeout.
+func (h ArtsHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var filter model.ArtFilter
+	if s := query.Get("author_id"); s != "" {
+		if id, err := strconv.ParseUint(s, 10, 32); err != nil || id == 0 {
+			http.Error(w, "author_id must be an account id", http.StatusBadRequest)
+			return
+		} else {
+			filter.AuthorId = uint(id)
+		}
+	}
+	filter.Title = query.Get("title")
+
+	format := query.Get("format")
+	if format == "" {
+		format = "ndjson"
+	}
+	var contentType string
+	switch format {
+	case "csv":
+		contentType = "text/csv; charset=utf-8"
+	case "ndjson":
+		contentType = "application/x-ndjson"
+	case "json":
+		contentType = "application/json"
+	default:
+		http.Error(w, fmt.Sprintf("unknown format '%s', expected csv, ndjson or json", format), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="arts.%s"`, format))
+
+	out := &countingWriter{w: w}
+	buffered := bufio.NewWriter(out)
+	var writer exportWriter
+	var err error
+	switch format {
+	case "csv":
+		writer, err = newCSVExportWriter(buffered)
+	case "ndjson":
+		writer = ndjsonExportWriter{encoder: json.NewEncoder(buffered)}
+	case "json":
+		writer = &jsonExportWriter{w: buffered}
+	}
+
+	clearReadDeadline(r)
+	flusher, _ := w.(http.Flusher)
+	rows := 0
+	if err == nil {
+		err = h.artDB.ExportArts(r.Context(), filter, func(art dto.ArtExportDto) error {
+			extendWriteDeadline(r)
+			if err := writer.Write(art); err != nil {
+				return err
+			}
+			rows++
+			if rows%exportFlushRows == 0 {
+				if err := buffered.Flush(); err != nil {
+					return err
+				} else if flusher != nil {
+					flusher.Flush()
+				}
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		extendWriteDeadline(r)
+		if err = writer.Close(); err == nil {
+			err = buffered.Flush()
+		}
+	}
+
+	if err == nil {
+		return
+	} else if out.n == 0 {
+		// Nothing reached the client yet, so the error can still be reported.
+		w.Header().Del("Content-Disposition")
+		modelError(w, err)
+	} else {
+		// The response is under way: abort it so the client sees a truncated export rather than a complete one.
+		log.Printf("export of arts failed after %d rows: %v", rows, err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// Counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+	"github.com/nafiz1001/gallery-go/model/memory"
+)
+
+// Reads the arts of an export slowly, as it goes for a large catalog.
+type slowExport struct {
+	model.ArtRepository
+	delay time.Duration
+}
+
+func (s slowExport) ExportArts(ctx context.Context, filter model.ArtFilter, each func(dto.ArtExportDto) error) error {
+	return s.ArtRepository.ExportArts(ctx, filter, func(art dto.ArtExportDto) error {
+		select {
+		case <-time.After(s.delay):
+			return each(art)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+func TestExport(t *testing.T) {
+	server := newTestServer(t, testServerOptions{})
+	repos := server.Repos
+
+	ctx := context.Background()
+	importer, err := repos.Accounts.CreateAccount(ctx, dto.AccountDto{Username: "importer", Password: "password"})
+	CheckError(t, err)
+	other, err := repos.Accounts.CreateAccount(ctx, dto.AccountDto{Username: "other", Password: "password"})
+	CheckError(t, err)
+
+	arts := []dto.ArtExportDto{}
+	for i, author := range []*dto.AccountDto{importer, other, importer} {
+		art, err := repos.Arts.CreateArt(ctx, dto.ArtDto{Title: fmt.Sprintf("title, \"%d\"", i), Quantity: i, AuthorId: author.Id})
+		CheckError(t, err)
+		arts = append(arts, dto.ArtExportDto{ArtDto: *art, AuthorUsername: author.Username})
+	}
+
+	t.Run("CSV", func(t *testing.T) {
+		var body rawBody
+		status := SendAs(t, http.MethodGet, server.URL+"/arts/export?format=csv", "", nil, &body)
+		expected := "id,title,quantity,author_id,author_username\n" +
+			"1,\"title, \"\"0\"\"\",0,1,importer\n" +
+			"2,\"title, \"\"1\"\"\",1,2,other\n" +
+			"3,\"title, \"\"2\"\"\",2,1,importer\n"
+		if status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if body.ContentType != "text/csv; charset=utf-8" {
+			t.Fatalf("unexpected content type %s", body.ContentType)
+		} else if body.Text != expected {
+			t.Fatalf("%q is not equal to %q", body.Text, expected)
+		}
+	})
+
+	t.Run("NDJSON by default", func(t *testing.T) {
+		var body rawBody
+		status := SendAs(t, http.MethodGet, server.URL+"/arts/export?author_id=1", "", nil, &body)
+		expected := ""
+		for _, art := range []dto.ArtExportDto{arts[0], arts[2]} {
+			b, err := json.Marshal(art)
+			CheckError(t, err)
+			expected += string(b) + "\n"
+		}
+		if status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if body.ContentType != "application/x-ndjson" {
+			t.Fatalf("unexpected content type %s", body.ContentType)
+		} else if body.Text != expected {
+			t.Fatalf("%q is not equal to %q", body.Text, expected)
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		for _, test := range []struct {
+			query    string
+			expected []dto.ArtExportDto
+		}{
+			{"", arts},
+			{"&title=" + "%22" + "1", arts[1:2]},
+			{"&author_id=3", []dto.ArtExportDto{}},
+		} {
+			var body rawBody
+			status := SendAs(t, http.MethodGet, server.URL+"/arts/export?format=json"+test.query, "", nil, &body)
+			var exported []dto.ArtExportDto
+			if status != http.StatusOK {
+				t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+			} else if err := json.Unmarshal([]byte(body.Text), &exported); err != nil {
+				t.Fatalf("%s is not valid JSON: %v", body.Text, err)
+			} else if !reflect.DeepEqual(exported, test.expected) {
+				t.Fatalf("%v is not equal to %v", exported, test.expected)
+			}
+		}
+	})
+
+	t.Run("Bad parameters", func(t *testing.T) {
+		for _, query := range []string{"format=xml", "author_id=0", "author_id=me"} {
+			if status := SendAs(t, http.MethodGet, server.URL+"/arts/export?"+query, "", nil, nil); status != http.StatusBadRequest {
+				t.Fatalf("%s: %d is not equal to %d", query, status, http.StatusBadRequest)
+			}
+		}
+	})
+
+	t.Run("Exports can be imported back", func(t *testing.T) {
+		for _, format := range []struct{ name, contentType string }{{"csv", "text/csv"}, {"ndjson", "application/x-ndjson"}} {
+			var body rawBody
+			SendAs(t, http.MethodGet, server.URL+"/arts/export?format="+format.name+"&author_id=2", "", nil, &body)
+			var report dto.ImportReportDto
+			status := SendAs(t, http.MethodPost, server.URL+"/arts/import", "importer", rawBody{ContentType: format.contentType, Text: body.Text}, &report)
+			if status != http.StatusOK {
+				t.Fatalf("%s: %d is not equal to %d", format.name, status, http.StatusOK)
+			} else if report.Succeeded != 1 || report.Failed != 0 {
+				t.Fatalf("%s: unexpected report %v", format.name, report)
+			} else if art := report.Rows[0].Art; art.Title != arts[1].Title || art.Quantity != arts[1].Quantity || art.AuthorId != importer.Id {
+				t.Fatalf("%s: unexpected art %v", format.name, art)
+			}
+		}
+	})
+
+	t.Run("Exports outlive the timeouts", func(t *testing.T) {
+		memoryDB := &memory.DB{}
+		CheckError(t, memoryDB.Init())
+		repos, err := memoryDB.Repositories()
+		CheckError(t, err)
+		repos.Arts = slowExport{ArtRepository: repos.Arts, delay: 50 * time.Millisecond}
+		server := newTestServer(t, testServerOptions{
+			Gallery:       GalleryHandler{RequestTimeout: 50 * time.Millisecond},
+			Repos:         repos,
+			ServerTimeout: 100 * time.Millisecond,
+		})
+
+		author, err := repos.Accounts.CreateAccount(ctx, dto.AccountDto{Username: "author", Password: "password"})
+		CheckError(t, err)
+		for i := 0; i < 4; i++ {
+			_, err := repos.Arts.CreateArt(ctx, dto.ArtDto{Title: fmt.Sprint(i), AuthorId: author.Id})
+			CheckError(t, err)
+		}
+
+		var body rawBody
+		if status := SendAs(t, http.MethodGet, server.URL+"/arts/export", "", nil, &body); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if lines := strings.Count(body.Text, "\n"); lines != 4 {
+			t.Fatalf("%d arts exported instead of 4", lines)
+		}
+	})
+}
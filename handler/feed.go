@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/nafiz1001/gallery-go/dto"
+)
+
+const (
+	// Page of the feed a client gets without asking for a limit.
+	defaultFeedLimit = 20
+	// Largest page of the feed a client can ask for.
+	maxFeedLimit = 100
+)
+
+// Lists the arts of the accounts account follows, most recently created or updated first.
+// The optional limit query parameter sets the size of the page and cursor, the next_cursor of the previous page, continues the feed.
+func (h ArtsHandler) GetFeed(w http.ResponseWriter, r *http.Request, account dto.AccountDto) {
+	w.Header().Set("Content-Type", "application/json")
+
+	limit := uint64(defaultFeedLimit)
+	if s := r.URL.Query().Get("limit"); s != "" {
+		var err error
+		if limit, err = strconv.ParseUint(s, 10, 32); err != nil || limit == 0 || limit > maxFeedLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxFeedLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if arts, next, err := h.followDB.GetFeed(r.Context(), account.Id, r.URL.Query().Get("cursor"), int(limit)); err != nil {
+		modelError(w, err)
+	} else if err := withLikes(r.Context(), h.likeDB, &account, arts); err != nil {
+		modelError(w, err)
+	} else {
+		json.NewEncoder(w).Encode(dto.FeedDto{Arts: arts, NextCursor: next})
+	}
+}
+
+func (h ArtsHandler) FeedFuncHandler(w http.ResponseWriter, r *http.Request) {
+	h.AccountAuth(w, r, func(account dto.AccountDto) {
+		h.GetFeed(w, r, account)
+	})
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/nafiz1001/gallery-go/dto"
+)
+
+func TestFeed(t *testing.T) {
+	// more requests are made to /accounts than its rate limit allows
+	server := newTestServer(t, testServerOptions{Unlimited: []string{"accounts"}})
+	repos := server.Repos
+
+	ctx := context.Background()
+	for _, username := range []string{"fan", "artist1", "artist2", "unfollowed"} {
+		_, err := repos.Accounts.CreateAccount(ctx, dto.AccountDto{Username: username, Password: "password"})
+		CheckError(t, err)
+	}
+	arts := []*dto.ArtDto{}
+	for _, authorId := range []uint{2, 3, 4, 2} {
+		art, err := repos.Arts.CreateArt(ctx, dto.ArtDto{Title: "title", Quantity: 1, AuthorId: authorId})
+		CheckError(t, err)
+		arts = append(arts, art)
+	}
+
+	t.Run("Follow", func(t *testing.T) {
+		for _, id := range []string{"2", "3", "3"} {
+			var followed dto.AccountDto
+			if status := SendAs(t, http.MethodPut, server.URL+"/accounts/"+id+"/follow", "fan", nil, &followed); status != http.StatusOK {
+				t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+			} else if followed.Password != "" {
+				t.Fatalf("unexpected account %v", followed)
+			}
+		}
+
+		var followers, following []dto.AccountDto
+		if status := SendAs(t, http.MethodGet, server.URL+"/accounts/3/followers", "", nil, &followers); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if len(followers) != 1 || followers[0].Username != "fan" || followers[0].Password != "" {
+			t.Fatalf("unexpected followers %v", followers)
+		} else if status := SendAs(t, http.MethodGet, server.URL+"/accounts/1/following", "", nil, &following); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if len(following) != 2 || following[0].Username != "artist2" {
+			t.Fatalf("unexpected following %v", following)
+		}
+	})
+
+	t.Run("Feed", func(t *testing.T) {
+		// updating an art brings it to the top of the feed
+		_, err := repos.Arts.UpdateArt(ctx, dto.ArtDto{Id: arts[0].Id, Title: "updated", AuthorId: 2})
+		CheckError(t, err)
+		_, err = repos.Likes.LikeArt(ctx, 1, arts[0].Id)
+		CheckError(t, err)
+
+		ids := []uint{}
+		cursor := ""
+		for pages := 0; pages == 0 || cursor != ""; pages++ {
+			if pages == 3 {
+				t.Fatalf("the feed doesn't end: %v", ids)
+			}
+
+			var feed dto.FeedDto
+			if status := SendAs(t, http.MethodGet, server.URL+"/feed?limit=2&cursor="+url.QueryEscape(cursor), "fan", nil, &feed); status != http.StatusOK {
+				t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+			}
+			for _, art := range feed.Arts {
+				ids = append(ids, art.Id)
+				if art.LikedByMe != (art.Id == arts[0].Id) {
+					t.Fatalf("unexpected art %v", art)
+				}
+			}
+			cursor = feed.NextCursor
+		}
+		if len(ids) != 3 || ids[0] != arts[0].Id || ids[1] != arts[3].Id || ids[2] != arts[1].Id {
+			t.Fatalf("unexpected feed %v", ids)
+		}
+	})
+
+	t.Run("Unfollow", func(t *testing.T) {
+		var feed dto.FeedDto
+		if status := SendAs(t, http.MethodDelete, server.URL+"/accounts/2/follow", "fan", nil, nil); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if status := SendAs(t, http.MethodGet, server.URL+"/feed", "fan", nil, &feed); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if len(feed.Arts) != 1 || feed.Arts[0].Id != arts[1].Id || feed.NextCursor != "" {
+			t.Fatalf("unexpected feed %v", feed)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for _, test := range []struct {
+			method   string
+			url      string
+			username string
+			status   int
+		}{
+			{http.MethodPut, server.URL + "/accounts/1/follow", "fan", http.StatusUnprocessableEntity},
+			{http.MethodPut, server.URL + "/accounts/100/follow", "fan", http.StatusNotFound},
+			{http.MethodDelete, server.URL + "/accounts/100/follow", "fan", http.StatusNotFound},
+			{http.MethodPut, server.URL + "/accounts/2/follow", "", http.StatusUnauthorized},
+			{http.MethodGet, server.URL + "/accounts/100/followers", "", http.StatusNotFound},
+			{http.MethodGet, server.URL + "/feed", "", http.StatusUnauthorized},
+			{http.MethodGet, server.URL + "/feed?cursor=nonsense", "fan", http.StatusBadRequest},
+			{http.MethodGet, server.URL + "/feed?limit=1000", "fan", http.StatusBadRequest},
+		} {
+			if status := SendAs(t, test.method, test.url, test.username, nil, nil); status != test.status {
+				t.Fatalf("%s %s as '%s': %d is not equal to %d", test.method, test.url, test.username, status, test.status)
+			}
+		}
+	})
+}
@@ -1,50 +1,262 @@
 package handler
 
 import (
+	"context"
+	"io"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/nafiz1001/gallery-go/events"
+	"github.com/nafiz1001/gallery-go/metrics"
 	"github.com/nafiz1001/gallery-go/model"
+	"github.com/nafiz1001/gallery-go/webhook"
 )
 
 type GalleryHandler struct {
-	artDB           *model.ArtDB
-	accountDB       *model.AccountDB
-	artsHandler     ArtsHandler
-	accountsHandler AccountsHandler
+	// Rate limits per route group, falling back to DefaultRateLimits for missing groups.
+	RateLimits map[string]RateLimitConfig
+	// Lockout policy for failed logins, a LoginGuard with default settings if nil.
+	LoginGuard *LoginGuard
+	// Destination of the JSON access log, os.Stderr if nil.
+	AccessLog io.Writer
+	// Registry the gallery metrics are registered in, no metrics are collected if nil.
+	Metrics *metrics.Registry
+	// Deadline for the database work of a request, none if zero.
+	RequestTimeout time.Duration
+	// Directory backups triggered through /admin/backups are written to, backups are disabled if empty.
+	BackupDir string
+	// Deadline for a backup triggered through /admin/backups, which RequestTimeout doesn't apply to, an hour if zero.
+	BackupTimeout time.Duration
+	// Bus the domain events of the gallery are published on once their operation is committed, a new one if nil.
+	Events *events.Bus
+	// Queues the events for the webhooks subscribed to them, a Dispatcher with default settings if nil.
+	// The deliveries are only sent while the Dispatcher runs, which is up to the caller.
+	Webhooks *webhook.Dispatcher
+	// How long the changes streamed by /events are kept for clients to resume from, a week if zero.
+	EventRetention time.Duration
+	// Interval between two keepalives on idle streams of /events, 15 seconds if zero.
+	StreamHeartbeat time.Duration
+	// Limits applied to each WebSocket of /inventory, the defaults of InventoryLimits for its zero fields.
+	Inventory InventoryLimits
+
+	metrics *Metrics
+
+	logging              LoggingMiddleware
+	rateLimits           map[string]*RateLimitMiddleware
+	repos                model.Repositories
+	outbox               *Outbox
+	artsHandler          ArtsHandler
+	accountsHandler      AccountsHandler
+	auditHandler         AuditHandler
+	backupHandler        BackupHandler
+	healthHandler        HealthHandler
+	notifier             Notifier
+	notificationsHandler NotificationsHandler
+	webhooksHandler      WebhooksHandler
+	artEventLog          *ArtEventLog
+	eventsHandler        EventsHandler
+	inventoryHub         *InventoryHub
+	inventoryHandler     InventoryHandler
 }
 
-func (h *GalleryHandler) Init(db *model.DB) error {
-	h.artDB = &model.ArtDB{}
-	if err := h.artDB.Init(db); err != nil {
+// Sets the gallery up on top of repos.
+// Readiness checks and query metrics are only available if the repositories are backed by a database.
+func (h *GalleryHandler) Init(repos model.Repositories) error {
+	h.repos = repos
+
+	if h.AccessLog == nil {
+		h.AccessLog = os.Stderr
+	}
+	if err := h.logging.Init(h.AccessLog); err != nil {
 		return err
 	}
 
-	h.accountDB = &model.AccountDB{}
-	if err := h.accountDB.Init(db); err != nil {
+	if h.LoginGuard == nil {
+		h.LoginGuard = &LoginGuard{}
+	}
+	if err := h.LoginGuard.Init(); err != nil {
+		return err
+	}
+
+	if h.Metrics != nil {
+		h.metrics = &Metrics{}
+		if err := h.metrics.Init(h.Metrics, repos.Arts, repos.Accounts); err != nil {
+			return err
+		}
+		if repos.DB != nil {
+			if err := repos.DB.GormDB.Use(h.metrics.QueryTimer()); err != nil {
+				return err
+			}
+		}
+	}
+
+	auth := Authenticator{}
+	if err := auth.Init(repos.Accounts, h.LoginGuard, h.metrics); err != nil {
+		return err
+	}
+
+	h.rateLimits = map[string]*RateLimitMiddleware{}
+	for group, config := range DefaultRateLimits() {
+		if custom, ok := h.RateLimits[group]; ok {
+			config = custom
+		}
+		h.rateLimits[group] = &RateLimitMiddleware{}
+		if err := h.rateLimits[group].Init(config); err != nil {
+			return err
+		}
+	}
+
+	if h.Events == nil {
+		h.Events = &events.Bus{}
+	}
+
+	h.outbox = &Outbox{}
+	if err := h.outbox.Init(h.Events); err != nil {
 		return err
 	}
 
+	h.notifier = Notifier{}
+	h.outbox.Use(h.notifier.Notify)
+
+	if h.Webhooks == nil {
+		h.Webhooks = &webhook.Dispatcher{}
+	}
+	if err := h.Webhooks.Init(repos.Webhooks); err != nil {
+		return err
+	}
+	h.outbox.Use(h.Webhooks.Enqueue)
+	h.Events.Subscribe(h.Webhooks.Notify)
+
+	if h.EventRetention == 0 {
+		h.EventRetention = 7 * 24 * time.Hour
+	}
+	h.artEventLog = &ArtEventLog{}
+	if err := h.artEventLog.Init(repos.ArtEvents, h.EventRetention); err != nil {
+		return err
+	}
+	h.outbox.Use(h.artEventLog.Record)
+	h.Events.Subscribe(h.artEventLog.Notify)
+
+	h.inventoryHub = &InventoryHub{}
+	h.Events.Subscribe(h.inventoryHub.Broadcast)
+
 	h.artsHandler = ArtsHandler{}
-	if err := h.artsHandler.Init(h.artDB, h.accountDB); err != nil {
+	if err := h.artsHandler.Init(repos, auth, h.outbox); err != nil {
 		return err
 	}
 
 	h.accountsHandler = AccountsHandler{}
-	if err := h.accountsHandler.Init(h.accountDB); err != nil {
+	if err := h.accountsHandler.Init(repos, auth, h.outbox); err != nil {
+		return err
+	}
+
+	h.auditHandler = AuditHandler{}
+	if err := h.auditHandler.Init(repos.Audit, auth); err != nil {
+		return err
+	}
+
+	h.notificationsHandler = NotificationsHandler{}
+	if err := h.notificationsHandler.Init(repos.Notifications, auth); err != nil {
+		return err
+	}
+
+	h.webhooksHandler = WebhooksHandler{}
+	if err := h.webhooksHandler.Init(repos, h.Webhooks, auth); err != nil {
+		return err
+	}
+
+	if h.StreamHeartbeat == 0 {
+		h.StreamHeartbeat = 15 * time.Second
+	}
+	h.eventsHandler = EventsHandler{}
+	if err := h.eventsHandler.Init(repos.ArtEvents, h.artEventLog, h.StreamHeartbeat); err != nil {
+		return err
+	}
+
+	h.inventoryHandler = InventoryHandler{}
+	if err := h.inventoryHandler.Init(repos.Arts, h.inventoryHub, h.Inventory); err != nil {
+		return err
+	}
+
+	if h.BackupTimeout == 0 {
+		h.BackupTimeout = time.Hour
+	}
+	h.backupHandler = BackupHandler{}
+	if err := h.backupHandler.Init(repos.DB, h.BackupDir, h.BackupTimeout, repos.Audit, auth); err != nil {
+		return err
+	}
+
+	checks := []HealthCheck{}
+	if repos.DB != nil {
+		checks = append(checks,
+			HealthCheck{Name: "database", Check: repos.DB.Ping},
+			HealthCheck{Name: "schema", Check: repos.DB.CheckSchema},
+		)
+	}
+	h.healthHandler = HealthHandler{}
+	if err := h.healthHandler.Init(checks); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// Registers every route of the gallery on router.
+// The routes of each group are rate limited by the limits of the group.
+func (h GalleryHandler) Routes(router *mux.Router) {
+	router.Use(recordRoute)
+
+	router.HandleFunc("/openapi.json", GetOpenAPI).Methods(http.MethodGet)
+	h.healthHandler.Routes(router)
+
+	accounts := router.NewRoute().Subrouter()
+	accounts.Use(h.rateLimits["accounts"].Handler)
+	h.accountsHandler.Routes(accounts)
+
+	arts := router.NewRoute().Subrouter()
+	arts.Use(h.rateLimits["arts"].Handler)
+	h.artsHandler.Routes(arts)
+
+	notifications := router.NewRoute().Subrouter()
+	notifications.Use(h.rateLimits["notifications"].Handler)
+	h.notificationsHandler.Routes(notifications)
+
+	stream := router.NewRoute().Subrouter()
+	stream.Use(h.rateLimits["events"].Handler)
+	h.eventsHandler.Routes(stream)
+
+	inventory := router.NewRoute().Subrouter()
+	inventory.Use(h.rateLimits["inventory"].Handler)
+	h.inventoryHandler.Routes(inventory)
+
+	webhooks := router.NewRoute().Subrouter()
+	webhooks.Use(h.rateLimits["webhooks"].Handler)
+	h.webhooksHandler.Routes(webhooks)
+
+	audit := router.NewRoute().Subrouter()
+	audit.Use(h.rateLimits["audit"].Handler)
+	h.auditHandler.Routes(audit)
+
+	admin := router.NewRoute().Subrouter()
+	admin.Use(h.rateLimits["admin"].Handler)
+	h.backupHandler.Routes(admin)
+}
+
 func (h GalleryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	router := mux.NewRouter()
-	router.PathPrefix("/accounts").Handler(h.accountsHandler)
-	router.PathPrefix("/accounts/").Handler(h.accountsHandler)
+	if h.RequestTimeout > 0 && !isLongRunning(r) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.RequestTimeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+	}
 
-	router.PathPrefix("/arts").Handler(h.artsHandler)
-	router.PathPrefix("/arts/").Handler(h.artsHandler)
+	router := mux.NewRouter()
+	h.Routes(router)
 
-	router.ServeHTTP(w, r)
+	if h.metrics != nil {
+		h.logging.Handler(h.metrics.Handler(router)).ServeHTTP(w, r)
+	} else {
+		h.logging.Handler(router).ServeHTTP(w, r)
+	}
 }
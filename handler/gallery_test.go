@@ -2,152 +2,184 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/metrics"
 	"github.com/nafiz1001/gallery-go/model"
-	"gorm.io/driver/sqlite"
-	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
-
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/nafiz1001/gallery-go/model/memory"
 )
 
-func GetArts(t *testing.T) []dto.ArtDto {
-	var arr []dto.ArtDto
-
-	if resp, err := http.Get("http://localhost:8080/arts/"); err != nil {
-		t.Fatal(err)
-	} else if err := json.NewDecoder(resp.Body).Decode(&arr); err != nil {
-		t.Fatal(err)
-	}
-
-	return arr
+// How newTestServer serves the gallery.
+type testServerOptions struct {
+	// The gallery to serve. Its access log is discarded unless it has one.
+	Gallery GalleryHandler
+	// Repositories the gallery is served on top of, an empty memory.DB if they have no arts.
+	Repos model.Repositories
+	// Route groups whose rate limits are lifted, for tests making more requests than they allow.
+	Unlimited []string
+	// Read and write timeouts of the server, none if zero.
+	ServerTimeout time.Duration
 }
 
-func NewRequest(t *testing.T, method string, url string, body string, username string, password string) (*http.Response, error) {
-	if req, err := http.NewRequest(method, url, bytes.NewBufferString(body)); err != nil {
-		t.Fatal(err)
-		return nil, nil
-	} else {
-		req.Header.Set("Content-Type", "application/json")
-		if len(username) > 0 {
-			req.SetBasicAuth(username, password)
-		}
-		if resp, _ := http.DefaultClient.Do(req); 400 <= resp.StatusCode && resp.StatusCode <= 599 {
-			b, _ := io.ReadAll(resp.Body)
-			return resp, fmt.Errorf("%s: %s", resp.Status, string(b))
-		} else {
-			return resp, nil
-		}
-	}
+// A gallery served over HTTP until the end of a test.
+type testServer struct {
+	*httptest.Server
+	Gallery *GalleryHandler
+	Repos   model.Repositories
 }
 
-func CheckError(t *testing.T, err error) {
-	if err != nil {
-		t.Fatal(err)
+func newTestServer(t *testing.T, opts testServerOptions) testServer {
+	if opts.Repos.Arts == nil {
+		memoryDB := &memory.DB{}
+		CheckError(t, memoryDB.Init())
+		repos, err := memoryDB.Repositories()
+		CheckError(t, err)
+		opts.Repos = repos
 	}
-}
 
-func TestGallery(t *testing.T) {
-	go func() {
-		// postgresql://[user[:password]@][netloc][:port][/dbname][?param1=value1&...]
-		// psqlconn, ok := os.LookupEnv("DATABASE_URL")
-		// if !ok {
-		// 	log.Fatal("expected env var DATABASE_URL")
-		// }
+	h := &opts.Gallery
+	if h.AccessLog == nil {
+		h.AccessLog = io.Discard
+	}
+	if len(opts.Unlimited) > 0 && h.RateLimits == nil {
+		h.RateLimits = map[string]RateLimitConfig{}
+	}
+	for _, group := range opts.Unlimited {
+		h.RateLimits[group] = RateLimitConfig{IP: Rate{PerSecond: 100, Burst: 100}, Username: Rate{PerSecond: 100, Burst: 100}}
+	}
+	CheckError(t, h.Init(opts.Repos))
 
-		// // open database
-		// db, err := sql.Open("postgres", psqlconn)
+	server := httptest.NewUnstartedServer(h)
+	server.Config.ReadTimeout = opts.ServerTimeout
+	server.Config.WriteTimeout = opts.ServerTimeout
+	server.Config.ConnContext = ConnContext
+	server.Start()
+	t.Cleanup(server.Close)
 
-		// db, err := sql.Open("sqlite3", "file::memory:?cache=shared&_foreign_keys=true")
-		// db.SetMaxIdleConns(2)
-		// db.SetConnMaxLifetime(-1)
-		// CheckError(t, err)
+	return testServer{Server: server, Gallery: h, Repos: opts.Repos}
+}
 
-		// // close database
-		// defer db.Close()
+// A body sent or received as it is rather than as JSON.
+type rawBody struct {
+	ContentType string
+	Text        string
+}
 
-		// // check db
-		// err = db.Ping()
-		// CheckError(t, err)
+// Sends a request as username, anonymously if empty, and returns the status of the response.
+// A username of the form "name:password" overrides the default password. The body is sent as JSON unless it is nil
+// or a rawBody. Whatever the status, a JSON response is decoded into out unless it is nil, and any response is read
+// into out if it is a *rawBody.
+func SendAs(t *testing.T, method string, url string, username string, body interface{}, out interface{}) int {
+	var payload bytes.Buffer
+	contentType := ""
+	if raw, ok := body.(rawBody); ok {
+		payload.WriteString(raw.Text)
+		contentType = raw.ContentType
+	} else if body != nil {
+		CheckError(t, json.NewEncoder(&payload).Encode(body))
+		contentType = "application/json"
+	}
 
-		// fmt.Println("Connected!")
+	req, err := http.NewRequest(method, url, &payload)
+	CheckError(t, err)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if credentials := strings.SplitN(username, ":", 2); len(credentials) == 2 {
+		req.SetBasicAuth(credentials[0], credentials[1])
+	} else if username != "" {
+		req.SetBasicAuth(username, "password")
+	}
 
-		gormDB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{
-			Logger: logger.Default.LogMode(logger.Silent),
-		})
-		CheckError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	CheckError(t, err)
+	defer resp.Body.Close()
 
-		h := GalleryHandler{}
-		err = h.Init(&model.DB{
-			GormDB: gormDB,
-		})
+	if raw, ok := out.(*rawBody); ok {
+		text, err := io.ReadAll(resp.Body)
 		CheckError(t, err)
+		*raw = rawBody{ContentType: resp.Header.Get("Content-Type"), Text: string(text)}
+	} else if out != nil && resp.Header.Get("Content-Type") == "application/json" {
+		CheckError(t, json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp.StatusCode
+}
 
-		srv := &http.Server{
-			Handler: h,
-			Addr:    "localhost:8080",
-			// Good practice: enforce timeouts for servers you create!
-			WriteTimeout: 15 * time.Second,
-			ReadTimeout:  15 * time.Second,
-		}
-
-		log.Fatal(srv.ListenAndServe())
-	}()
+func CheckError(t *testing.T, err error) {
+	if err != nil {
+		t.Fatal(err)
+	}
+}
 
-	time.Sleep(500 * time.Millisecond)
+func TestGallery(t *testing.T) {
+	registry := &metrics.Registry{}
+	server := newTestServer(t, testServerOptions{Gallery: GalleryHandler{Metrics: registry}})
+	repos := server.Repos
 
 	var art dto.ArtDto
 	var account dto.AccountDto
 
+	getArts := func(t *testing.T) []dto.ArtDto {
+		var arts []dto.ArtDto
+		if status := SendAs(t, http.MethodGet, server.URL+"/arts/", "", nil, &arts); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		}
+		return arts
+	}
+
+	t.Run("Gallery is ready", func(t *testing.T) {
+		if status := SendAs(t, http.MethodGet, server.URL+"/readyz", "", nil, nil); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		}
+	})
+
 	t.Run("No art at the beginning", func(t *testing.T) {
-		if arts := GetArts(t); len(arts) != 0 {
+		if arts := getArts(t); len(arts) != 0 {
 			t.Fatalf("%v length is not 0", arts)
 		}
 	})
 
 	t.Run("Successfully create first account", func(t *testing.T) {
-		if resp, err := NewRequest(t, http.MethodPost, "http://localhost:8080/accounts/", `{"username":"good", "password":"good"}`, "", ""); err != nil {
-			t.Fatal(err)
-		} else if err := json.NewDecoder(resp.Body).Decode(&account); err != nil {
-			t.Fatal(err)
+		if status := SendAs(t, http.MethodPost, server.URL+"/accounts/", "", dto.AccountDto{Username: "good", Password: "good"}, &account); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
 		} else if account.Username != "good" {
 			t.Fatalf("%s is not equal to 'good'", account.Username)
 		}
 	})
 
 	t.Run("First account created exists", func(t *testing.T) {
-		if resp, err := NewRequest(t, http.MethodGet, fmt.Sprintf("http://localhost:8080/accounts/%d", account.Id), "", "", ""); err != nil {
-			t.Fatal(err)
-		} else {
-			var tmp dto.AccountDto
-			if err := json.NewDecoder(resp.Body).Decode(&tmp); err != nil {
-				t.Fatal(err)
-			} else if tmp.Id != account.Id {
-				t.Fatalf("%d is not equal to %d", tmp.Id, account.Id)
-			}
+		var tmp dto.AccountDto
+		if status := SendAs(t, http.MethodGet, fmt.Sprintf("%s/accounts/%d", server.URL, account.Id), "", nil, &tmp); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if tmp.Id != account.Id {
+			t.Fatalf("%d is not equal to %d", tmp.Id, account.Id)
+		}
+	})
+
+	t.Run("Don't create account because the username is taken", func(t *testing.T) {
+		if status := SendAs(t, http.MethodPost, server.URL+"/accounts/", "", dto.AccountDto{Username: "good", Password: "other"}, nil); status != http.StatusConflict {
+			t.Fatalf("%d is not equal to %d", status, http.StatusConflict)
 		}
 	})
 
 	t.Run("Don't create art because basic auth is missing", func(t *testing.T) {
-		if resp, err := NewRequest(t, http.MethodPost, "http://localhost:8080/arts/", `{"title":"title"}`, "", ""); err == nil {
-			b, _ := io.ReadAll(resp.Body)
-			t.Fatalf("expected to fail creating art because basic auth is missing\n%s", string(b))
+		if status := SendAs(t, http.MethodPost, server.URL+"/arts/", "", dto.ArtDto{Title: "title"}, nil); status != http.StatusUnauthorized {
+			t.Fatalf("%d is not equal to %d", status, http.StatusUnauthorized)
 		}
 	})
 
 	t.Run("Successfully create art", func(t *testing.T) {
-		if resp, err := NewRequest(t, http.MethodPost, "http://localhost:8080/arts/", `{"title":"title"}`, "good", "good"); err != nil {
-			t.Fatal(err)
-		} else if err := json.NewDecoder(resp.Body).Decode(&art); err != nil {
-			t.Fatal(err)
+		if status := SendAs(t, http.MethodPost, server.URL+"/arts/", "good:good", dto.ArtDto{Title: "title"}, &art); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
 		} else if art.Title != "title" {
 			t.Fatalf("the title of response (%v) is not equal to 'title'", art)
 		} else if art.AuthorId != account.Id {
@@ -155,8 +187,20 @@ func TestGallery(t *testing.T) {
 		}
 	})
 
+	t.Run("Don't create art because the quantity is negative", func(t *testing.T) {
+		if status := SendAs(t, http.MethodPost, server.URL+"/arts/", "good:good", dto.ArtDto{Title: "title", Quantity: -1}, nil); status != http.StatusUnprocessableEntity {
+			t.Fatalf("%d is not equal to %d", status, http.StatusUnprocessableEntity)
+		}
+	})
+
+	t.Run("Missing art is not found", func(t *testing.T) {
+		if status := SendAs(t, http.MethodGet, fmt.Sprintf("%s/arts/%d", server.URL, art.Id+1), "", nil, nil); status != http.StatusNotFound {
+			t.Fatalf("%d is not equal to %d", status, http.StatusNotFound)
+		}
+	})
+
 	t.Run("The new art exists with valid information", func(t *testing.T) {
-		if arts := GetArts(t); arts[0].Id != art.Id {
+		if arts := getArts(t); arts[0].Id != art.Id {
 			t.Fatalf("the response (%v) does not have art with id %d", arts, art.Id)
 		} else if arts[0].AuthorId != account.Id {
 			t.Fatalf("the authorId of response (%v) is not equal to '%d'", arts[0].AuthorId, account.Id)
@@ -164,41 +208,91 @@ func TestGallery(t *testing.T) {
 	})
 
 	t.Run("Fail to update art because of invalid credential", func(t *testing.T) {
-		if _, err := NewRequest(t, http.MethodPut, fmt.Sprintf("http://localhost:8080/arts/%d", art.Id), `{"title":"title2"}`, "good", "bad"); err == nil {
-			t.Fatalf("expected to not update art because of invalid credential")
+		if status := SendAs(t, http.MethodPut, fmt.Sprintf("%s/arts/%d", server.URL, art.Id), "good:bad", dto.ArtDto{Title: "title2"}, nil); status != http.StatusUnauthorized {
+			t.Fatalf("%d is not equal to %d", status, http.StatusUnauthorized)
 		}
 	})
 
 	t.Run("Successfully update existing art", func(t *testing.T) {
-		if resp, err := NewRequest(t, http.MethodPut, fmt.Sprintf("http://localhost:8080/arts/%d", art.Id), `{"title":"title2"}`, "good", "good"); err != nil {
-			t.Fatalf("%s", err)
-		} else if err := json.NewDecoder(resp.Body).Decode(&art); err != nil {
-			t.Fatal(err)
+		if status := SendAs(t, http.MethodPut, fmt.Sprintf("%s/arts/%d", server.URL, art.Id), "good:good", dto.ArtDto{Title: "title2"}, &art); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
 		} else if art.Title != "title2" {
 			t.Fatalf("the title of response (%v) is not equal to 'title2'", art)
 		}
-		if arts := GetArts(t); arts[0].Title != art.Title {
+		if arts := getArts(t); arts[0].Title != art.Title {
 			t.Fatalf("the response (%v) does not have art with %s", arts, art.Title)
 		}
 	})
 
+	t.Run("The previous version of art is kept as a revision", func(t *testing.T) {
+		var revisions []dto.ArtRevisionDto
+		if status := SendAs(t, http.MethodGet, fmt.Sprintf("%s/arts/%d/revisions", server.URL, art.Id), "", nil, &revisions); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if len(revisions) != 1 || revisions[0].Art.Title != "title" {
+			t.Fatalf("the response (%v) does not have a revision with title 'title'", revisions)
+		}
+	})
+
+	t.Run("Successfully restore previous version of art", func(t *testing.T) {
+		if status := SendAs(t, http.MethodPost, fmt.Sprintf("%s/arts/%d/revisions/1/restore", server.URL, art.Id), "good:good", nil, &art); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if art.Title != "title" {
+			t.Fatalf("the title of response (%v) is not equal to 'title'", art)
+		}
+	})
+
 	t.Run("Fail to delete art because of invalid credential", func(t *testing.T) {
-		if _, err := NewRequest(t, http.MethodDelete, fmt.Sprintf("http://localhost:8080/arts/%d", art.Id), "", "good", "bad"); err == nil {
-			t.Fatal("expected to not delete art because of invalid credential")
+		if status := SendAs(t, http.MethodDelete, fmt.Sprintf("%s/arts/%d", server.URL, art.Id), "good:bad", nil, nil); status != http.StatusUnauthorized {
+			t.Fatalf("%d is not equal to %d", status, http.StatusUnauthorized)
 		}
-		if arts := GetArts(t); len(arts) != 1 {
+		if arts := getArts(t); len(arts) != 1 {
 			t.Fatalf("%v length is not 1", arts)
 		}
 	})
 
-	t.Run("Successfully delete art", func(t *testing.T) {
-		if resp, err := NewRequest(t, http.MethodDelete, fmt.Sprintf("http://localhost:8080/arts/%d", art.Id), "", "good", "good"); err != nil {
-			t.Fatal(err)
-		} else if err := json.NewDecoder(resp.Body).Decode(&art); err != nil {
+	t.Run("Fail to read audit log because account is not an administrator", func(t *testing.T) {
+		if status := SendAs(t, http.MethodGet, fmt.Sprintf("%s/audit?entity=art&id=%d", server.URL, art.Id), "good:good", nil, nil); status != http.StatusForbidden {
+			t.Fatalf("%d is not equal to %d", status, http.StatusForbidden)
+		}
+	})
+
+	t.Run("Administrator reads audit log of art", func(t *testing.T) {
+		if _, err := repos.Accounts.SetAdmin(context.Background(), account.Id, true); err != nil {
 			t.Fatal(err)
 		}
-		if arts := GetArts(t); len(arts) != 0 {
+		defer repos.Accounts.SetAdmin(context.Background(), account.Id, false)
+
+		var entries []dto.AuditEntryDto
+		if status := SendAs(t, http.MethodGet, fmt.Sprintf("%s/audit?entity=art&id=%d", server.URL, art.Id), "good:good", nil, &entries); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if len(entries) != 3 || entries[0].Action != "create" || entries[1].Action != "update" || entries[2].Action != "restore" {
+			t.Fatalf("the response (%v) does not have the create, update and restore of art", entries)
+		} else if entries[1].Diff["title"].After != "title2" {
+			t.Fatalf("the diff of the update (%v) does not change title to 'title2'", entries[1].Diff)
+		}
+	})
+
+	t.Run("Successfully delete art", func(t *testing.T) {
+		if status := SendAs(t, http.MethodDelete, fmt.Sprintf("%s/arts/%d", server.URL, art.Id), "good:good", nil, &art); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		}
+		if arts := getArts(t); len(arts) != 0 {
 			t.Fatalf("%v length is not 0", arts)
 		}
 	})
+
+	t.Run("Requests and authentication attempts are counted", func(t *testing.T) {
+		var out bytes.Buffer
+		CheckError(t, registry.Write(&out))
+		for _, sample := range []string{
+			`gallery_http_requests_total{route="/arts/",method="GET",status="200"}`,
+			`gallery_auth_attempts_total{result="failure"} 2`,
+			`gallery_arts 0`,
+			`gallery_accounts 1`,
+		} {
+			if !strings.Contains(out.String(), sample) {
+				t.Fatalf("metrics do not contain %s\n%s", sample, out.String())
+			}
+		}
+	})
 }
@@ -0,0 +1,32 @@
+// Package handlertest serves the gallery for the tests of its clients.
+package handlertest
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nafiz1001/gallery-go/handler"
+	"github.com/nafiz1001/gallery-go/model/memory"
+)
+
+// Serves a GalleryHandler with default settings on top of an empty memory.DB until the end of the test.
+func NewServer(t testing.TB) *httptest.Server {
+	memoryDB := &memory.DB{}
+	if err := memoryDB.Init(); err != nil {
+		t.Fatal(err)
+	}
+	repos, err := memoryDB.Repositories()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.GalleryHandler{AccessLog: io.Discard}
+	if err := h.Init(repos); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(h)
+	t.Cleanup(server.Close)
+	return server
+}
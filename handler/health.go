@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/nafiz1001/gallery-go/dto"
+)
+
+// A named dependency the gallery needs in order to serve requests.
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Serves /healthz, which succeeds as long as the process is running,
+// and /readyz, which runs every check and reports 503 if any of them fails.
+type HealthHandler struct {
+	checks  []HealthCheck
+	timeout time.Duration
+}
+
+func (h *HealthHandler) Init(checks []HealthCheck) error {
+	h.checks = checks
+	h.timeout = 2 * time.Second
+	return nil
+}
+
+func (h HealthHandler) GetHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dto.HealthDto{Status: "ok"})
+}
+
+func (h HealthHandler) GetReadiness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	health := dto.HealthDto{Status: "ok", Checks: map[string]dto.CheckDto{}}
+	for _, check := range h.checks {
+		if err := check.Check(ctx); err != nil {
+			health.Status = "unavailable"
+			health.Checks[check.Name] = dto.CheckDto{Status: "fail", Error: err.Error()}
+		} else {
+			health.Checks[check.Name] = dto.CheckDto{Status: "ok"}
+		}
+	}
+
+	if health.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(health)
+}
+
+// Registers the routes served by the handler on router.
+func (h HealthHandler) Routes(router *mux.Router) {
+	router.HandleFunc("/healthz", h.GetHealth).Methods(http.MethodGet)
+	router.HandleFunc("/readyz", h.GetReadiness).Methods(http.MethodGet)
+}
+
+func (h HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	router := mux.NewRouter()
+	router.Use(recordRoute)
+	h.Routes(router)
+	router.ServeHTTP(w, r)
+}
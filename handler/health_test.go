@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nafiz1001/gallery-go/dto"
+)
+
+func TestHealthHandler(t *testing.T) {
+	h := HealthHandler{}
+	CheckError(t, h.Init([]HealthCheck{
+		{Name: "good", Check: func(ctx context.Context) error { return nil }},
+		{Name: "bad", Check: func(ctx context.Context) error { return errors.New("broken") }},
+	}))
+
+	t.Run("Process is alive regardless of dependencies", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", w.Code, http.StatusOK)
+		}
+	})
+
+	t.Run("Not ready because a dependency failed", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		var health dto.HealthDto
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("%d is not equal to %d", w.Code, http.StatusServiceUnavailable)
+		} else if err := json.NewDecoder(w.Body).Decode(&health); err != nil {
+			t.Fatal(err)
+		} else if health.Checks["good"].Status != "ok" || health.Checks["bad"].Status != "fail" || health.Checks["bad"].Error != "broken" {
+			t.Fatalf("unexpected checks %v", health.Checks)
+		}
+	})
+}
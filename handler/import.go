@@ -0,0 +1,329 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/events"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+const (
+	// Largest import body accepted.
+	maxImportBytes = 10 << 20
+	// Most rows a single import may have.
+	maxImportRows = 10000
+	// Rows inserted per transaction.
+	importBatchSize = 100
+)
+
+// Rolls back a dry run once every row has been tried.
+var errDryRun = errors.New("dry run")
+
+var errImportTooLarge = fmt.Errorf("an import can't be larger than %d bytes", maxImportBytes)
+
+// Reads at most n bytes from r, failing with errImportTooLarge past them.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		return 0, errImportTooLarge
+	} else if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+// A row of an import along with the line it was read from.
+type importRow struct {
+	line int
+	art  dto.ArtDto
+	err  error
+	// Recorded along with the creation of the art, and published once it is committed.
+	event events.Event
+}
+
+// Creates arts authored by account from a CSV or NDJSON body and reports what happened to each row.
+//
+// CSV bodies start with a header naming the title and quantity columns. NDJSON bodies have an art object per line.
+// Rows are inserted in batches of importBatchSize, one transaction per batch. With dry_run=true, every row is
+// tried and then rolled back. With atomic=true, no art is created unless every row can be.
+// The report is sent even if a batch fails, so that the client knows which rows were created before it.
+func (h ArtsHandler) PostImport(w http.ResponseWriter, r *http.Request, account dto.AccountDto) {
+	w.Header().Set("Content-Type", "application/json")
+
+	report := dto.ImportReportDto{Rows: []dto.ImportRowDto{}}
+	for name, flag := range map[string]*bool{"dry_run": &report.DryRun, "atomic": &report.Atomic} {
+		if s := r.URL.Query().Get(name); s != "" {
+			var err error
+			if *flag, err = strconv.ParseBool(s); err != nil {
+				http.Error(w, name+" must be true or false", http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	rows, status, err := readImport(r.Header.Get("Content-Type"), &limitedReader{r: r.Body, n: maxImportBytes + 1})
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	for i := range rows {
+		rows[i].art.Id = 0
+		rows[i].art.AuthorId = account.Id
+		report.Rows = append(report.Rows, dto.ImportRowDto{Line: rows[i].line})
+	}
+
+	err = h.importRows(r, account, rows, &report)
+	for i := range report.Rows {
+		if report.Rows[i].Status == "" {
+			// the import stopped before the row
+			report.Rows[i].Status = "skipped"
+		}
+	}
+	// rows still created at this point are committed, dry runs leave none
+	for i, row := range report.Rows {
+		if row.Status == "created" && row.Art != nil {
+			h.outbox.Publish(r.Context(), rows[i].event)
+		}
+	}
+	if errors.Is(err, errDryRun) || err == nil {
+		json.NewEncoder(w).Encode(report)
+	} else if report.Atomic && report.Failed > 0 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(report)
+	} else {
+		report.Error = err.Error()
+		w.WriteHeader(modelStatus(err))
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+// Inserts the valid rows and fills in the report.
+func (h ArtsHandler) importRows(r *http.Request, account dto.AccountDto, rows []importRow, report *dto.ImportReportDto) error {
+	ctx := r.Context()
+	failed := false
+
+	// every row is tried in its own savepoint, so that a failed row doesn't undo the rest of its batch
+	insertRow := func(batch model.Repositories, i int) error {
+		if rows[i].err != nil {
+			return rows[i].err
+		}
+		return batch.Transaction(ctx, func(tx model.Repositories) error {
+			art, err := tx.Arts.CreateArt(ctx, rows[i].art)
+			if err != nil {
+				return err
+			}
+			rows[i].event = events.Event{Type: events.ArtCreated, ActorId: account.Id, Art: art}
+			if err := h.outbox.Record(ctx, tx, &rows[i].event); err != nil {
+				return err
+			}
+			if err := recordAudit(tx.Audit, r, account.Id, "create", "art", art.Id, nil, art); err != nil {
+				return err
+			}
+			report.Rows[i].Art = art
+			return nil
+		})
+	}
+
+	insert := func(repos model.Repositories) error {
+		for start := 0; start < len(rows); start += importBatchSize {
+			end := start + importBatchSize
+			if end > len(rows) {
+				end = len(rows)
+			}
+
+			err := repos.Transaction(ctx, func(batch model.Repositories) error {
+				for i := start; i < end; i++ {
+					result := &report.Rows[i]
+					if failed && report.Atomic && rows[i].err == nil {
+						// the import is rolled back anyway, only the remaining invalid rows are worth reporting
+						result.Status = "skipped"
+					} else if err := insertRow(batch, i); err != nil {
+						result.Status = "failed"
+						result.Error = err.Error()
+						report.Failed++
+						failed = true
+					} else {
+						result.Status = "created"
+						report.Succeeded++
+					}
+				}
+				return ctx.Err()
+			})
+			if err != nil {
+				undoImport(report, start, end)
+				return err
+			}
+		}
+
+		if report.DryRun {
+			return errDryRun
+		} else if failed && report.Atomic {
+			return errors.New("import failed")
+		} else {
+			return nil
+		}
+	}
+
+	if report.DryRun || report.Atomic {
+		err := h.repos.Transaction(ctx, insert)
+		if err != nil {
+			undoImport(report, 0, len(rows))
+		}
+		return err
+	} else {
+		return insert(h.repos)
+	}
+}
+
+// Marks the rows from start to end that were created as rolled back, or as valid on a dry run.
+func undoImport(report *dto.ImportReportDto, start int, end int) {
+	for i := start; i < end; i++ {
+		if result := &report.Rows[i]; result.Status == "created" {
+			result.Art = nil
+			report.Succeeded--
+			if report.DryRun {
+				result.Status = "valid"
+				report.Succeeded++
+			} else {
+				result.Status = "rolled_back"
+			}
+		}
+	}
+}
+
+// Reads the rows of an import body in the format named by contentType.
+// Malformed rows are returned with their error. Errors that prevent reading the body are returned with a status code.
+func readImport(contentType string, body io.Reader) ([]importRow, int, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, http.StatusUnsupportedMediaType, errors.New("Content-Type must be text/csv or application/x-ndjson")
+	}
+
+	var rows []importRow
+	switch mediaType {
+	case "text/csv":
+		rows, err = readImportCSV(body)
+	case "application/x-ndjson", "application/jsonl", "application/json":
+		rows, err = readImportNDJSON(body)
+	default:
+		return nil, http.StatusUnsupportedMediaType, errors.New("Content-Type must be text/csv or application/x-ndjson")
+	}
+
+	if errors.Is(err, errImportTooLarge) {
+		return nil, http.StatusRequestEntityTooLarge, err
+	} else if err != nil {
+		return nil, http.StatusBadRequest, err
+	} else if len(rows) > maxImportRows {
+		return nil, http.StatusRequestEntityTooLarge, fmt.Errorf("an import can't have more than %d rows", maxImportRows)
+	}
+
+	for i := range rows {
+		if rows[i].err == nil {
+			rows[i].err = validateImportedArt(rows[i].art)
+		}
+	}
+	return rows, http.StatusOK, nil
+}
+
+func readImportCSV(body io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return []importRow{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "id" || name == "author_id" || name == "author_username" {
+			// Written by an export and ignored, so that an export can be imported back.
+			continue
+		} else if name != "title" && name != "quantity" {
+			return nil, fmt.Errorf("unknown column '%s', expected title and quantity", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, errors.New("missing title column")
+	}
+
+	rows := []importRow{}
+	for len(rows) <= maxImportRows {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := importRow{line: line}
+		if errors.Is(err, csv.ErrFieldCount) {
+			row.err = fmt.Errorf("expected %d fields", len(header))
+		} else if err != nil {
+			return nil, err
+		} else {
+			row.art.Title = record[columns["title"]]
+			if i, ok := columns["quantity"]; ok && record[i] != "" {
+				if row.art.Quantity, err = strconv.Atoi(record[i]); err != nil {
+					row.err = fmt.Errorf("quantity '%s' is not an integer", record[i])
+				}
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func readImportNDJSON(body io.Reader) ([]importRow, error) {
+	scanner := bufio.NewScanner(body)
+	rows := []importRow{}
+	for line := 1; scanner.Scan() && len(rows) <= maxImportRows; line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		row := importRow{line: line}
+		decoder := json.NewDecoder(strings.NewReader(text))
+		decoder.DisallowUnknownFields()
+		// Decoded as an export so that one can be imported back.
+		var art dto.ArtExportDto
+		if err := decoder.Decode(&art); err != nil {
+			row.err = err
+		}
+		row.art = art.ArtDto
+		rows = append(rows, row)
+	}
+	return rows, scanner.Err()
+}
+
+// Checks an imported art before it reaches the database. Its id and author are ignored.
+func validateImportedArt(art dto.ArtDto) error {
+	if strings.TrimSpace(art.Title) == "" {
+		return errors.New("title is required")
+	} else if art.Quantity < 0 {
+		return model.ErrInvalidQuantity
+	} else {
+		return nil
+	}
+}
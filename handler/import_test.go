@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+	"github.com/nafiz1001/gallery-go/model/memory"
+)
+
+func statuses(report dto.ImportReportDto) []string {
+	s := []string{}
+	for _, row := range report.Rows {
+		s = append(s, fmt.Sprintf("%d:%s", row.Line, row.Status))
+	}
+	return s
+}
+
+// Fails every transaction after the first n, like a database that went away.
+type failingTransactor struct {
+	model.Transactor
+	n int
+}
+
+func (f *failingTransactor) Transaction(ctx context.Context, fn func(tx model.Repositories) error) error {
+	if f.n == 0 {
+		return errors.New("database is gone")
+	}
+	f.n--
+	return f.Transactor.Transaction(ctx, fn)
+}
+
+func TestImport(t *testing.T) {
+	server := newTestServer(t, testServerOptions{})
+	repos := server.Repos
+
+	account, err := repos.Accounts.CreateAccount(context.Background(), dto.AccountDto{Username: "importer", Password: "password"})
+	CheckError(t, err)
+
+	countArts := func() int64 {
+		count, err := repos.Arts.CountArts(context.Background())
+		CheckError(t, err)
+		return count
+	}
+
+	t.Run("Valid rows are created and invalid ones reported", func(t *testing.T) {
+		csv := "title,quantity\nfirst,1\n,2\nthird,-1\nfourth,many\nfifth\nsixth,\n"
+		var report dto.ImportReportDto
+		status := SendAs(t, http.MethodPost, server.URL+"/arts/import", "importer", rawBody{ContentType: "text/csv", Text: csv}, &report)
+		if status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		}
+
+		expected := "[2:created 3:failed 4:failed 5:failed 6:failed 7:created]"
+		if s := fmt.Sprint(statuses(report)); s != expected {
+			t.Fatalf("%s is not equal to %s", s, expected)
+		} else if report.Succeeded != 2 || report.Failed != 4 {
+			t.Fatalf("unexpected counts %d and %d", report.Succeeded, report.Failed)
+		} else if art := report.Rows[0].Art; art == nil || art.Title != "first" || art.AuthorId != account.Id {
+			t.Fatalf("unexpected art %v", art)
+		} else if count := countArts(); count != 2 {
+			t.Fatalf("%d arts instead of 2", count)
+		}
+
+		entries, err := repos.Audit.GetEntries(context.Background(), "art", report.Rows[0].Art.Id)
+		CheckError(t, err)
+		if len(entries) != 1 || entries[0].Action != "create" {
+			t.Fatalf("unexpected audit trail %v", entries)
+		}
+	})
+
+	t.Run("Dry run creates nothing", func(t *testing.T) {
+		ndjson := "{\"title\":\"a\",\"quantity\":1}\n\n{\"title\":\"b\",\"color\":\"red\"}\n{\"title\":\"c\"}\n"
+		var report dto.ImportReportDto
+		status := SendAs(t, http.MethodPost, server.URL+"/arts/import?dry_run=true", "importer", rawBody{ContentType: "application/x-ndjson", Text: ndjson}, &report)
+		if status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		}
+
+		expected := "[1:valid 3:failed 4:valid]"
+		if s := fmt.Sprint(statuses(report)); s != expected {
+			t.Fatalf("%s is not equal to %s", s, expected)
+		} else if report.Succeeded != 2 || report.Rows[0].Art != nil {
+			t.Fatalf("unexpected report %v", report)
+		} else if count := countArts(); count != 2 {
+			t.Fatalf("%d arts instead of 2", count)
+		}
+	})
+
+	t.Run("Atomic import creates nothing when a row fails", func(t *testing.T) {
+		csv := "quantity,title\n1,a\n-1,b\n1,c\n1,\n"
+		var report dto.ImportReportDto
+		status := SendAs(t, http.MethodPost, server.URL+"/arts/import?atomic=true", "importer", rawBody{ContentType: "text/csv", Text: csv}, &report)
+		if status != http.StatusUnprocessableEntity {
+			t.Fatalf("%d is not equal to %d", status, http.StatusUnprocessableEntity)
+		}
+
+		expected := "[2:rolled_back 3:failed 4:skipped 5:failed]"
+		if s := fmt.Sprint(statuses(report)); s != expected {
+			t.Fatalf("%s is not equal to %s", s, expected)
+		} else if report.Succeeded != 0 || report.Failed != 2 {
+			t.Fatalf("unexpected counts %d and %d", report.Succeeded, report.Failed)
+		} else if count := countArts(); count != 2 {
+			t.Fatalf("%d arts instead of 2", count)
+		}
+	})
+
+	t.Run("Atomic import spans several batches", func(t *testing.T) {
+		var csv strings.Builder
+		csv.WriteString("title,quantity\n")
+		for i := 0; i < 2*importBatchSize+1; i++ {
+			fmt.Fprintf(&csv, "art %d,%d\n", i, i)
+		}
+
+		var report dto.ImportReportDto
+		status := SendAs(t, http.MethodPost, server.URL+"/arts/import?atomic=true", "importer", rawBody{ContentType: "text/csv", Text: csv.String()}, &report)
+		if status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if report.Succeeded != 2*importBatchSize+1 {
+			t.Fatalf("%d rows created", report.Succeeded)
+		} else if count := countArts(); count != 2*importBatchSize+3 {
+			t.Fatalf("%d arts instead of %d", count, 2*importBatchSize+3)
+		}
+	})
+
+	t.Run("A failed batch doesn't hide the rows created before it", func(t *testing.T) {
+		memoryDB := &memory.DB{}
+		CheckError(t, memoryDB.Init())
+		repos, err := memoryDB.Repositories()
+		CheckError(t, err)
+		repos.Transactor = &failingTransactor{Transactor: repos.Transactor, n: 1}
+		server := newTestServer(t, testServerOptions{Repos: repos})
+		_, err = repos.Accounts.CreateAccount(context.Background(), dto.AccountDto{Username: "importer", Password: "password"})
+		CheckError(t, err)
+
+		var csv strings.Builder
+		csv.WriteString("title,quantity\n")
+		for i := 0; i < importBatchSize+2; i++ {
+			fmt.Fprintf(&csv, "art %d,%d\n", i, i)
+		}
+
+		var report dto.ImportReportDto
+		status := SendAs(t, http.MethodPost, server.URL+"/arts/import", "importer", rawBody{ContentType: "text/csv", Text: csv.String()}, &report)
+		if status != http.StatusInternalServerError {
+			t.Fatalf("%d is not equal to %d", status, http.StatusInternalServerError)
+		} else if report.Succeeded != importBatchSize || report.Error != "database is gone" || len(report.Rows) != importBatchSize+2 {
+			t.Fatalf("unexpected report %d %q %d", report.Succeeded, report.Error, len(report.Rows))
+		} else if first, last := report.Rows[0], report.Rows[importBatchSize]; first.Status != "created" || first.Art == nil || last.Status != "skipped" {
+			t.Fatalf("unexpected rows %v and %v", first, last)
+		}
+	})
+
+	t.Run("Malformed imports are rejected", func(t *testing.T) {
+		for _, test := range []struct {
+			query       string
+			contentType string
+			body        string
+			status      int
+		}{
+			{"", "text/plain", "title\na\n", http.StatusUnsupportedMediaType},
+			{"", "text/csv", "title,color\na,red\n", http.StatusBadRequest},
+			{"", "text/csv", "quantity\n1\n", http.StatusBadRequest},
+			{"?atomic=maybe", "text/csv", "title\na\n", http.StatusBadRequest},
+			{"", "text/csv", "title\n" + strings.Repeat("a\n", maxImportRows+1), http.StatusRequestEntityTooLarge},
+		} {
+			if status := SendAs(t, http.MethodPost, server.URL+"/arts/import"+test.query, "importer", rawBody{ContentType: test.contentType, Text: test.body}, nil); status != test.status {
+				t.Errorf("%s %s: %d is not equal to %d", test.contentType, test.query, status, test.status)
+			}
+		}
+	})
+
+	t.Run("Import needs credentials", func(t *testing.T) {
+		if status := SendAs(t, http.MethodPost, server.URL+"/arts/import", "", rawBody{ContentType: "text/csv", Text: "title\na\n"}, nil); status != http.StatusUnauthorized {
+			t.Fatalf("%d is not equal to %d", status, http.StatusUnauthorized)
+		}
+	})
+}
@@ -0,0 +1,391 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/events"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+const (
+	// Time a write to an inventory connection has to complete in.
+	inventoryWriteWait = 10 * time.Second
+	// Time an inventory connection may go without a pong before it is closed.
+	inventoryPongWait = 60 * time.Second
+	// Interval between two pings, short enough for the pong to arrive within inventoryPongWait.
+	inventoryPingPeriod = inventoryPongWait * 9 / 10
+	// Errors queued for a connection beyond which its client is deemed not to read them, and is disconnected.
+	maxPendingInventoryErrors = 16
+)
+
+var errSubscriptionLimit = errors.New("too many subscriptions")
+
+// Limits applied to each connection of /inventory.
+type InventoryLimits struct {
+	// Arts a connection can be subscribed to at once, 50 if zero.
+	MaxSubscriptions int
+	// Largest message a client can send in bytes, 4096 if zero. The connection is closed on larger ones.
+	MaxMessageBytes int64
+	// Rate at which a client can send messages, 5 per second with bursts of 10 if zero. Messages beyond it are refused.
+	Messages Rate
+}
+
+// The inventory of art at a time.
+func inventoryOf(art dto.ArtDto, at time.Time) dto.InventoryDto {
+	status := dto.InventoryAvailable
+	if art.Quantity <= 0 {
+		status = dto.InventorySoldOut
+	}
+	return dto.InventoryDto{Type: "inventory", ArtId: art.Id, Quantity: art.Quantity, Status: status, Time: at}
+}
+
+// A connection of /inventory. Updates are coalesced per art until they are written, so that a client that reads
+// slowly gets the latest inventory of its arts rather than a growing backlog, and never holds up the others.
+type inventoryConn struct {
+	ws *websocket.Conn
+
+	mu sync.Mutex
+	// Time of the latest update pushed for each subscribed art, pending or written, zero until one is pushed.
+	subscriptions map[uint]time.Time
+	// Latest inventory of each art, yet to be written.
+	pending map[uint]dto.InventoryDto
+	// Errors yet to be written.
+	errors []dto.InventoryDto
+
+	// Signals writeLoop that there is something to write.
+	ready     chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newInventoryConn(ws *websocket.Conn) *inventoryConn {
+	return &inventoryConn{
+		ws:            ws,
+		subscriptions: map[uint]time.Time{},
+		pending:       map[uint]dto.InventoryDto{},
+		ready:         make(chan struct{}, 1),
+		closed:        make(chan struct{}),
+	}
+}
+
+// Subscribes the connection to an art and returns whether it wasn't already, or errSubscriptionLimit.
+func (c *inventoryConn) subscribe(artId uint, max int) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.subscriptions[artId]; ok {
+		return false, nil
+	} else if len(c.subscriptions) >= max {
+		return false, errSubscriptionLimit
+	}
+	c.subscriptions[artId] = time.Time{}
+	return true, nil
+}
+
+func (c *inventoryConn) unsubscribe(artId uint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.subscriptions, artId)
+	delete(c.pending, artId)
+}
+
+// The arts the connection is subscribed to.
+func (c *inventoryConn) subscribed() []uint {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	artIds := []uint{}
+	for artId := range c.subscriptions {
+		artIds = append(artIds, artId)
+	}
+	return artIds
+}
+
+// Queues the inventory of a subscribed art, replacing the one queued before it if it wasn't written yet.
+// Events may be published out of order, so an update older than the latest one pushed for the art is dropped.
+// A snapshot, read when the art was subscribed to, is dropped if an update was pushed since, as it may be older.
+func (c *inventoryConn) push(inventory dto.InventoryDto, snapshot bool) {
+	c.mu.Lock()
+	latest, ok := c.subscriptions[inventory.ArtId]
+	if !ok || (snapshot && !latest.IsZero()) || (!snapshot && inventory.Time.Before(latest)) {
+		c.mu.Unlock()
+		return
+	}
+	if !snapshot {
+		c.subscriptions[inventory.ArtId] = inventory.Time
+	}
+	c.pending[inventory.ArtId] = inventory
+	c.mu.Unlock()
+
+	c.wake()
+}
+
+// Queues an error about a request of the client, about an art unless artId is 0.
+func (c *inventoryConn) pushError(artId uint, message string) {
+	c.mu.Lock()
+	if len(c.errors) >= maxPendingInventoryErrors {
+		c.mu.Unlock()
+		c.close()
+		return
+	}
+	c.errors = append(c.errors, dto.InventoryDto{Type: "error", ArtId: artId, Error: message, Time: time.Now()})
+	c.mu.Unlock()
+
+	c.wake()
+}
+
+func (c *inventoryConn) wake() {
+	select {
+	case c.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Takes the queued messages, errors first and then the inventories in the order they changed.
+func (c *inventoryConn) drain() []dto.InventoryDto {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	inventories := []dto.InventoryDto{}
+	for _, inventory := range c.pending {
+		inventories = append(inventories, inventory)
+	}
+	sort.Slice(inventories, func(i, j int) bool {
+		if !inventories[i].Time.Equal(inventories[j].Time) {
+			return inventories[i].Time.Before(inventories[j].Time)
+		}
+		return inventories[i].ArtId < inventories[j].ArtId
+	})
+
+	messages := append(c.errors, inventories...)
+	c.errors = nil
+	c.pending = map[uint]dto.InventoryDto{}
+	return messages
+}
+
+// Writes the queued messages and pings the client until the connection is closed.
+func (c *inventoryConn) writeLoop() {
+	ping := time.NewTicker(inventoryPingPeriod)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-c.ready:
+			for _, message := range c.drain() {
+				c.ws.SetWriteDeadline(time.Now().Add(inventoryWriteWait))
+				if err := c.ws.WriteJSON(message); err != nil {
+					c.close()
+					return
+				}
+			}
+		case <-ping.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(inventoryWriteWait)); err != nil {
+				c.close()
+				return
+			}
+		}
+	}
+}
+
+func (c *inventoryConn) close() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.ws.Close()
+	})
+}
+
+// Pushes the changes to the quantity and status of arts to the connections of /inventory subscribed to them.
+// The zero value is ready to use.
+type InventoryHub struct {
+	mu sync.Mutex
+	// Connections subscribed to each art.
+	subscribers map[uint]map[*inventoryConn]struct{}
+}
+
+func (hub *InventoryHub) subscribe(artId uint, conn *inventoryConn) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	if hub.subscribers == nil {
+		hub.subscribers = map[uint]map[*inventoryConn]struct{}{}
+	}
+	if hub.subscribers[artId] == nil {
+		hub.subscribers[artId] = map[*inventoryConn]struct{}{}
+	}
+	hub.subscribers[artId][conn] = struct{}{}
+}
+
+func (hub *InventoryHub) unsubscribe(artId uint, conn *inventoryConn) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	delete(hub.subscribers[artId], conn)
+	if len(hub.subscribers[artId]) == 0 {
+		delete(hub.subscribers, artId)
+	}
+}
+
+// Pushes the inventory of an art to its subscribers when an update changes its quantity or when it is deleted.
+// It is meant to be subscribed to the events.Bus of the gallery, and never waits for the connections.
+func (hub *InventoryHub) Broadcast(_ context.Context, event events.Event) {
+	if event.Art == nil {
+		return
+	}
+
+	var inventory dto.InventoryDto
+	switch event.Type {
+	case events.ArtUpdated:
+		if event.Before != nil && event.Before.Quantity == event.Art.Quantity {
+			return
+		}
+		inventory = inventoryOf(*event.Art, event.Time)
+	case events.ArtDeleted:
+		inventory = inventoryOf(*event.Art, event.Time)
+		inventory.Status = dto.InventoryWithdrawn
+	default:
+		return
+	}
+
+	hub.mu.Lock()
+	conns := []*inventoryConn{}
+	for conn := range hub.subscribers[event.Art.Id] {
+		conns = append(conns, conn)
+	}
+	hub.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.push(inventory, false)
+	}
+}
+
+// Serves the live inventory of arts over WebSockets.
+type InventoryHandler struct {
+	artDB    model.ArtRepository
+	hub      *InventoryHub
+	limits   InventoryLimits
+	upgrader websocket.Upgrader
+}
+
+// The zero fields of limits are replaced by their defaults.
+func (h *InventoryHandler) Init(artDB model.ArtRepository, hub *InventoryHub, limits InventoryLimits) error {
+	h.artDB = artDB
+	h.hub = hub
+
+	if limits.MaxSubscriptions == 0 {
+		limits.MaxSubscriptions = 50
+	}
+	if limits.MaxMessageBytes == 0 {
+		limits.MaxMessageBytes = 4096
+	}
+	if limits.Messages == (Rate{}) {
+		limits.Messages = Rate{PerSecond: 5, Burst: 10}
+	}
+	h.limits = limits
+	h.upgrader = websocket.Upgrader{HandshakeTimeout: inventoryWriteWait}
+
+	return nil
+}
+
+// Upgrades the request to a WebSocket on which the client sends InventoryRequestDto messages to subscribe to arts,
+// and is pushed their inventory right away, then again every time their quantity or status changes.
+func (h InventoryHandler) GetInventory(w http.ResponseWriter, r *http.Request) {
+	ws, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader responded with the error
+		return
+	}
+
+	conn := newInventoryConn(ws)
+	defer func() {
+		for _, artId := range conn.subscribed() {
+			h.hub.unsubscribe(artId, conn)
+		}
+		conn.close()
+	}()
+	go conn.writeLoop()
+
+	limiter := RateLimiter{}
+	if err := limiter.Init(h.limits.Messages); err != nil {
+		return
+	}
+
+	ws.SetReadLimit(h.limits.MaxMessageBytes)
+	ws.SetReadDeadline(time.Now().Add(inventoryPongWait))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(inventoryPongWait))
+	})
+
+	for {
+		_, message, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var request dto.InventoryRequestDto
+		if limiter.Take("") > 0 {
+			conn.pushError(0, "too many messages")
+		} else if err := json.Unmarshal(message, &request); err != nil {
+			conn.pushError(0, err.Error())
+		} else if request.Type == "subscribe" {
+			h.subscribe(r.Context(), conn, request.ArtIds)
+		} else if request.Type == "unsubscribe" {
+			for _, artId := range request.ArtIds {
+				h.hub.unsubscribe(artId, conn)
+				conn.unsubscribe(artId)
+			}
+		} else {
+			conn.pushError(0, fmt.Sprintf("unknown type '%s', expected subscribe or unsubscribe", request.Type))
+		}
+	}
+}
+
+// Subscribes conn to arts and pushes their current inventory.
+func (h InventoryHandler) subscribe(ctx context.Context, conn *inventoryConn, artIds []uint) {
+	for _, artId := range artIds {
+		if added, err := conn.subscribe(artId, h.limits.MaxSubscriptions); err != nil {
+			conn.pushError(artId, fmt.Sprintf("at most %d arts can be subscribed to", h.limits.MaxSubscriptions))
+			continue
+		} else if !added {
+			continue
+		}
+
+		// subscribed before the art is read, so that no change is missed in between
+		h.hub.subscribe(artId, conn)
+		if art, err := h.artDB.GetArt(ctx, artId); err != nil {
+			h.hub.unsubscribe(artId, conn)
+			conn.unsubscribe(artId)
+			if errors.Is(err, model.ErrNotFound) {
+				conn.pushError(artId, "art not found")
+			} else {
+				conn.pushError(artId, err.Error())
+			}
+		} else {
+			conn.push(inventoryOf(*art, time.Now()), true)
+		}
+	}
+}
+
+// Registers the routes served by the handler on router.
+func (h InventoryHandler) Routes(router *mux.Router) {
+	router.HandleFunc("/inventory", h.GetInventory).Methods(http.MethodGet)
+	router.HandleFunc("/inventory/", h.GetInventory).Methods(http.MethodGet)
+}
+
+func (h InventoryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	router := mux.NewRouter()
+	router.Use(recordRoute)
+	h.Routes(router)
+	router.ServeHTTP(w, r)
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nafiz1001/gallery-go/dto"
+)
+
+// Opens a WebSocket on /inventory.
+func dialInventory(t *testing.T, serverURL string) *websocket.Conn {
+	ws, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(serverURL, "http")+"/inventory", nil)
+	CheckError(t, err)
+	resp.Body.Close()
+	return ws
+}
+
+// Reads the next message pushed on ws.
+func nextInventory(t *testing.T, ws *websocket.Conn) dto.InventoryDto {
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var inventory dto.InventoryDto
+	CheckError(t, ws.ReadJSON(&inventory))
+	return inventory
+}
+
+func TestInventory(t *testing.T) {
+	server := newTestServer(t, testServerOptions{
+		Gallery: GalleryHandler{
+			RequestTimeout: 200 * time.Millisecond,
+			Inventory:      InventoryLimits{MaxSubscriptions: 2, MaxMessageBytes: 256, Messages: Rate{PerSecond: 1, Burst: 10}},
+		},
+		Unlimited: []string{"inventory", "arts"},
+	})
+	repos := server.Repos
+
+	ctx := context.Background()
+	account, err := repos.Accounts.CreateAccount(ctx, dto.AccountDto{Username: "artist", Password: "password"})
+	CheckError(t, err)
+	first, err := repos.Arts.CreateArt(ctx, dto.ArtDto{Title: "first", Quantity: 1, AuthorId: account.Id})
+	CheckError(t, err)
+	second, err := repos.Arts.CreateArt(ctx, dto.ArtDto{Title: "second", Quantity: 2, AuthorId: account.Id})
+	CheckError(t, err)
+	third, err := repos.Arts.CreateArt(ctx, dto.ArtDto{Title: "third", Quantity: 3, AuthorId: account.Id})
+	CheckError(t, err)
+
+	put := func(t *testing.T, art dto.ArtDto) {
+		if status := SendAs(t, http.MethodPut, fmt.Sprintf("%s/arts/%d", server.URL, art.Id), "artist", art, nil); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		}
+	}
+
+	t.Run("Subscribe", func(t *testing.T) {
+		ws := dialInventory(t, server.URL)
+		defer ws.Close()
+
+		CheckError(t, ws.WriteJSON(dto.InventoryRequestDto{Type: "subscribe", ArtIds: []uint{1000, first.Id, second.Id, third.Id}}))
+		// errors come before the inventories queued along with them
+		if inventory := nextInventory(t, ws); inventory.Type != "error" || inventory.ArtId != 1000 {
+			t.Fatalf("unexpected message %v", inventory)
+		} else if inventory := nextInventory(t, ws); inventory.Type != "error" || inventory.ArtId != third.Id {
+			t.Fatalf("unexpected message %v", inventory)
+		} else if inventory := nextInventory(t, ws); inventory.ArtId != first.Id || inventory.Quantity != 1 || inventory.Status != dto.InventoryAvailable {
+			t.Fatalf("unexpected message %v", inventory)
+		} else if inventory := nextInventory(t, ws); inventory.ArtId != second.Id || inventory.Quantity != 2 {
+			t.Fatalf("unexpected message %v", inventory)
+		}
+
+		// the connection outlives the request timeout
+		time.Sleep(300 * time.Millisecond)
+		put(t, dto.ArtDto{Id: first.Id, Title: "first", Quantity: 3})
+		if inventory := nextInventory(t, ws); inventory.ArtId != first.Id || inventory.Quantity != 3 || inventory.Status != dto.InventoryAvailable {
+			t.Fatalf("unexpected message %v", inventory)
+		}
+
+		// selling the last copies sells the art out, and no more can be sold
+		sell := func(quantity int) int {
+			return SendAs(t, http.MethodPost, fmt.Sprintf("%s/arts/%d/sales", server.URL, first.Id), "artist", dto.SaleDto{Quantity: quantity}, nil)
+		}
+		if status := sell(2); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if inventory := nextInventory(t, ws); inventory.ArtId != first.Id || inventory.Quantity != 1 || inventory.Status != dto.InventoryAvailable {
+			t.Fatalf("unexpected message %v", inventory)
+		}
+		if status := sell(2); status != http.StatusConflict {
+			t.Fatalf("%d is not equal to %d", status, http.StatusConflict)
+		} else if status := sell(1); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		}
+		if inventory := nextInventory(t, ws); inventory.ArtId != first.Id || inventory.Quantity != 0 || inventory.Status != dto.InventorySoldOut {
+			t.Fatalf("unexpected message %v", inventory)
+		}
+
+		// changes that leave the quantity alone and arts that aren't subscribed to aren't pushed
+		put(t, dto.ArtDto{Id: first.Id, Title: "renamed"})
+		put(t, dto.ArtDto{Id: third.Id, Title: "third", Quantity: 4})
+		if status := SendAs(t, http.MethodDelete, fmt.Sprintf("%s/arts/%d", server.URL, second.Id), "artist", nil, nil); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		}
+		if inventory := nextInventory(t, ws); inventory.ArtId != second.Id || inventory.Status != dto.InventoryWithdrawn {
+			t.Fatalf("unexpected message %v", inventory)
+		}
+
+		// messages are handled in order, so the snapshot of third comes once first is unsubscribed from
+		CheckError(t, ws.WriteJSON(dto.InventoryRequestDto{Type: "unsubscribe", ArtIds: []uint{first.Id, second.Id}}))
+		CheckError(t, ws.WriteJSON(dto.InventoryRequestDto{Type: "subscribe", ArtIds: []uint{third.Id}}))
+		if inventory := nextInventory(t, ws); inventory.ArtId != third.Id || inventory.Quantity != 4 {
+			t.Fatalf("unexpected message %v", inventory)
+		}
+		put(t, dto.ArtDto{Id: first.Id, Title: "renamed", Quantity: 5})
+		put(t, dto.ArtDto{Id: third.Id, Title: "third", Quantity: 6})
+		if inventory := nextInventory(t, ws); inventory.ArtId != third.Id || inventory.Quantity != 6 {
+			t.Fatalf("unexpected message %v", inventory)
+		}
+
+		CheckError(t, ws.WriteJSON(dto.InventoryRequestDto{Type: "buy", ArtIds: []uint{third.Id}}))
+		if inventory := nextInventory(t, ws); inventory.Type != "error" || !strings.Contains(inventory.Error, "unknown type") {
+			t.Fatalf("unexpected message %v", inventory)
+		}
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		ws := dialInventory(t, server.URL)
+		defer ws.Close()
+
+		// messages beyond the rate are refused
+		for i := 0; i < 11; i++ {
+			CheckError(t, ws.WriteJSON(dto.InventoryRequestDto{Type: "unsubscribe", ArtIds: []uint{first.Id}}))
+		}
+		if inventory := nextInventory(t, ws); inventory.Type != "error" || inventory.Error != "too many messages" {
+			t.Fatalf("unexpected message %v", inventory)
+		}
+
+		// messages that are too large close the connection
+		CheckError(t, ws.WriteMessage(websocket.TextMessage, []byte(strings.Repeat(" ", 512))))
+		ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if _, _, err := ws.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseMessageTooBig) {
+			t.Fatalf("unexpected error %v", err)
+		}
+	})
+
+	t.Run("Coalesce", func(t *testing.T) {
+		// a client that doesn't keep up only gets the latest inventory of each art
+		conn := newInventoryConn(nil)
+		conn.subscribe(first.Id, 2)
+		now := time.Now()
+		conn.push(dto.InventoryDto{ArtId: first.Id, Quantity: 2, Time: now}, false)
+		conn.push(dto.InventoryDto{ArtId: first.Id, Quantity: 1, Time: now.Add(time.Second)}, false)
+		// the snapshot may be older than the update
+		conn.push(dto.InventoryDto{ArtId: first.Id, Quantity: 3, Time: now.Add(2 * time.Second)}, true)
+		// so may an update published late
+		conn.push(dto.InventoryDto{ArtId: first.Id, Quantity: 2, Time: now}, false)
+		if messages := conn.drain(); len(messages) != 1 || messages[0].Quantity != 1 {
+			t.Fatalf("unexpected messages %v", messages)
+		}
+
+		// an update older than one already written is dropped too
+		conn.push(dto.InventoryDto{ArtId: first.Id, Quantity: 4, Time: now.Add(-time.Second)}, false)
+		if messages := conn.drain(); len(messages) != 0 {
+			t.Fatalf("unexpected messages %v", messages)
+		}
+	})
+}
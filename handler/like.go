@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/events"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+// Fills in how many likes each art has, and whether viewer liked it unless viewer is nil.
+func withLikes(ctx context.Context, likeDB model.LikeRepository, viewer *dto.AccountDto, arts []dto.ArtDto) error {
+	ids := []uint{}
+	for _, art := range arts {
+		ids = append(ids, art.Id)
+	}
+
+	var viewerId uint
+	if viewer != nil {
+		viewerId = viewer.Id
+	}
+
+	counts, liked, err := likeDB.GetLikes(ctx, viewerId, ids)
+	if err != nil {
+		return err
+	}
+	for i := range arts {
+		arts[i].LikeCount = counts[arts[i].Id]
+		arts[i].LikedByMe = liked[arts[i].Id]
+	}
+	return nil
+}
+
+// Gets the art with id along with its likes as seen by viewer.
+func likedArt(ctx context.Context, artDB model.ArtRepository, likeDB model.LikeRepository, id uint, viewer *dto.AccountDto) (*dto.ArtDto, error) {
+	if art, err := artDB.GetArt(ctx, id); err != nil {
+		return nil, err
+	} else {
+		arts := []dto.ArtDto{*art}
+		err := withLikes(ctx, likeDB, viewer, arts)
+		return &arts[0], err
+	}
+}
+
+// Fills in the likes of art as seen by viewer, for the responses that return an art they just changed.
+func withArtLikes(ctx context.Context, likeDB model.LikeRepository, viewer *dto.AccountDto, art *dto.ArtDto) error {
+	arts := []dto.ArtDto{*art}
+	if err := withLikes(ctx, likeDB, viewer, arts); err != nil {
+		return err
+	}
+	*art = arts[0]
+	return nil
+}
+
+// Likes the art on behalf of account and responds with the art. Liking an art again changes nothing, and isn't published again.
+func (h ArtsHandler) PutLike(w http.ResponseWriter, r *http.Request, id uint, account dto.AccountDto) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var art *dto.ArtDto
+	err := h.outbox.Transaction(r.Context(), h.repos, func(tx model.Repositories) ([]events.Event, error) {
+		if liked, err := tx.Likes.LikeArt(r.Context(), account.Id, id); err != nil {
+			return nil, err
+		} else if art, err = likedArt(r.Context(), tx.Arts, tx.Likes, id, &account); err != nil || !liked {
+			return nil, err
+		} else {
+			return []events.Event{{Type: events.ArtLiked, ActorId: account.Id, Art: art}}, nil
+		}
+	})
+	if err != nil {
+		modelError(w, err)
+	} else {
+		json.NewEncoder(w).Encode(art)
+	}
+}
+
+// Takes back the like of account and responds with the art. Unliking an art that isn't liked changes nothing.
+func (h ArtsHandler) DeleteLike(w http.ResponseWriter, r *http.Request, id uint, account dto.AccountDto) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if _, err := h.artDB.GetArt(r.Context(), id); err != nil {
+		modelError(w, err)
+	} else if err := h.likeDB.UnlikeArt(r.Context(), account.Id, id); err != nil {
+		modelError(w, err)
+	} else if art, err := likedArt(r.Context(), h.artDB, h.likeDB, id, &account); err != nil {
+		modelError(w, err)
+	} else {
+		json.NewEncoder(w).Encode(art)
+	}
+}
+
+func (h ArtsHandler) LikeFuncHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 10, 32)
+
+	h.AccountAuth(w, r, func(account dto.AccountDto) {
+		switch r.Method {
+		case http.MethodPut:
+			h.PutLike(w, r, uint(id), account)
+		case http.MethodDelete:
+			h.DeleteLike(w, r, uint(id), account)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/nafiz1001/gallery-go/dto"
+)
+
+func TestLikes(t *testing.T) {
+	server := newTestServer(t, testServerOptions{})
+	repos := server.Repos
+
+	ctx := context.Background()
+	fan, err := repos.Accounts.CreateAccount(ctx, dto.AccountDto{Username: "fan", Password: "password"})
+	CheckError(t, err)
+	_, err = repos.Accounts.CreateAccount(ctx, dto.AccountDto{Username: "other", Password: "password"})
+	CheckError(t, err)
+	art, err := repos.Arts.CreateArt(ctx, dto.ArtDto{Title: "title", Quantity: 1, AuthorId: fan.Id})
+	CheckError(t, err)
+	likeURL := server.URL + "/arts/1/like"
+
+	t.Run("Liking twice counts once", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			var liked dto.ArtDto
+			if status := SendAs(t, http.MethodPut, likeURL, "fan", nil, &liked); status != http.StatusOK {
+				t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+			} else if liked.LikeCount != 1 || !liked.LikedByMe {
+				t.Fatalf("unexpected art %v", liked)
+			}
+		}
+	})
+
+	t.Run("Only the viewer's own likes are flagged", func(t *testing.T) {
+		for _, test := range []struct {
+			username  string
+			likedByMe bool
+		}{{"fan", true}, {"other", false}, {"", false}} {
+			var got dto.ArtDto
+			var arts []dto.ArtDto
+			if status := SendAs(t, http.MethodGet, server.URL+"/arts/1", test.username, nil, &got); status != http.StatusOK {
+				t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+			} else if got.LikeCount != 1 || got.LikedByMe != test.likedByMe {
+				t.Fatalf("'%s': unexpected art %v", test.username, got)
+			} else if status := SendAs(t, http.MethodGet, server.URL+"/arts", test.username, nil, &arts); status != http.StatusOK {
+				t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+			} else if len(arts) != 1 || arts[0].LikeCount != 1 || arts[0].LikedByMe != test.likedByMe {
+				t.Fatalf("'%s': unexpected arts %v", test.username, arts)
+			}
+		}
+	})
+
+	t.Run("Liked arts of an account", func(t *testing.T) {
+		var arts []dto.ArtDto
+		if status := SendAs(t, http.MethodGet, server.URL+"/accounts/1/likes", "other", nil, &arts); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if len(arts) != 1 || arts[0].Id != art.Id || arts[0].LikeCount != 1 || arts[0].LikedByMe {
+			t.Fatalf("unexpected arts %v", arts)
+		} else if status := SendAs(t, http.MethodGet, server.URL+"/accounts/2/likes", "", nil, &arts); status != http.StatusOK || len(arts) != 0 {
+			t.Fatalf("unexpected status %d and arts %v", status, arts)
+		} else if status := SendAs(t, http.MethodGet, server.URL+"/accounts/100/likes", "", nil, nil); status != http.StatusNotFound {
+			t.Fatalf("%d is not equal to %d", status, http.StatusNotFound)
+		}
+	})
+
+	t.Run("Changed arts are returned with their likes", func(t *testing.T) {
+		var put, sold, restored dto.ArtDto
+		var batch dto.BatchReportDto
+		update := dto.BatchDto{Operations: []dto.BatchOperationDto{{Op: "update", Id: art.Id, Art: &dto.ArtDto{Title: "batched"}}}}
+		if status := SendAs(t, http.MethodPut, server.URL+"/arts/1", "fan", dto.ArtDto{Title: "renamed"}, &put); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if status := SendAs(t, http.MethodPost, server.URL+"/arts/batch", "fan", update, &batch); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if status := SendAs(t, http.MethodPost, server.URL+"/arts/1/revisions/1/restore", "fan", nil, &restored); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if status := SendAs(t, http.MethodPost, server.URL+"/arts/1/sales", "fan", dto.SaleDto{Quantity: 1}, &sold); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		}
+
+		for _, changed := range []*dto.ArtDto{&put, batch.Results[0].Art, &restored, &sold} {
+			if changed == nil || changed.LikeCount != 1 || !changed.LikedByMe {
+				t.Fatalf("unexpected art %v", changed)
+			}
+		}
+	})
+
+	t.Run("Unliking twice is harmless", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			var unliked dto.ArtDto
+			if status := SendAs(t, http.MethodDelete, likeURL, "fan", nil, &unliked); status != http.StatusOK {
+				t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+			} else if unliked.LikeCount != 0 || unliked.LikedByMe {
+				t.Fatalf("unexpected art %v", unliked)
+			}
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for _, test := range []struct {
+			method   string
+			url      string
+			username string
+			status   int
+		}{
+			{http.MethodPut, likeURL, "", http.StatusUnauthorized},
+			{http.MethodPut, server.URL + "/arts/100/like", "fan", http.StatusNotFound},
+			{http.MethodDelete, server.URL + "/arts/100/like", "fan", http.StatusNotFound},
+			{http.MethodGet, server.URL + "/arts/1", "fan:wrong", http.StatusUnauthorized},
+		} {
+			if status := SendAs(t, test.method, test.url, test.username, nil, nil); status != test.status {
+				t.Fatalf("%s %s as '%s': %d is not equal to %d", test.method, test.url, test.username, status, test.status)
+			}
+		}
+	})
+}
@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+// Details about a request collected while it is being handled.
+type requestInfo struct {
+	route     string
+	accountId uint
+}
+
+type requestInfoKey struct{}
+
+func getRequestInfo(r *http.Request) *requestInfo {
+	if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+		return info
+	}
+	return &requestInfo{}
+}
+
+// Router middleware remembering the path template of the matched route.
+func recordRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := mux.CurrentRoute(r); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil {
+				getRequestInfo(r).route = template
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Remembers the account a request is authenticated as.
+func recordAccount(r *http.Request, accountId uint) {
+	getRequestInfo(r).accountId = accountId
+}
+
+// Wraps a ResponseWriter to remember the status and the number of bytes written.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// The status the client got, or is left with if the handler panicked before responding.
+func (w *statusRecorder) result(completed bool) int {
+	if w.status != 0 {
+		return w.status
+	} else if completed {
+		return http.StatusOK
+	} else {
+		return http.StatusInternalServerError
+	}
+}
+
+func (w *statusRecorder) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Lets WebSocket upgrades take the connection over, which then responds with 101 Switching Protocols.
+func (w *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the connection can't be hijacked")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+type accessLogEntry struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Status    int       `json:"status"`
+	LatencyMs float64   `json:"latency_ms"`
+	Bytes     int       `json:"bytes"`
+	AccountID uint      `json:"account_id,omitempty"`
+	RemoteIP  string    `json:"remote_ip"`
+	// Whether the handler panicked, such as an export aborting a response that is under way.
+	Aborted bool `json:"aborted,omitempty"`
+}
+
+// Middleware assigning every request an id and writing one JSON access log line per request.
+// An X-Request-ID sent by the client is kept, otherwise a random one is generated, and it is echoed in the response.
+// The path is the template of the matched route, so ids in the URL don't end up in the log.
+// Requests whose handler panics, such as aborted responses, are logged as well.
+type LoggingMiddleware struct {
+	out io.Writer
+}
+
+func (m *LoggingMiddleware) Init(out io.Writer) error {
+	m.out = out
+	return nil
+}
+
+func (m LoggingMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+
+		info := &requestInfo{}
+		ctx := context.WithValue(model.WithRequestID(r.Context(), id), requestInfoKey{}, info)
+		recorder := &statusRecorder{ResponseWriter: w}
+
+		completed := false
+		defer func() {
+			entry := accessLogEntry{
+				Time:      start.UTC(),
+				RequestID: id,
+				Method:    r.Method,
+				Path:      info.route,
+				Status:    recorder.result(completed),
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+				Bytes:     recorder.bytes,
+				AccountID: info.accountId,
+				RemoteIP:  clientIP(r),
+				Aborted:   !completed,
+			}
+			if b, err := json.Marshal(entry); err == nil {
+				m.out.Write(append(b, '\n'))
+			}
+		}()
+
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+		completed = true
+	})
+}
+
+// Request ids from clients are accepted as long as they are short and printable.
+func validRequestID(id string) bool {
+	if len(id) == 0 || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+func TestLoggingMiddleware(t *testing.T) {
+	var out bytes.Buffer
+	middleware := LoggingMiddleware{}
+	CheckError(t, middleware.Init(&out))
+
+	var requestId string
+	router := mux.NewRouter()
+	router.Use(recordRoute)
+	router.HandleFunc("/arts/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		requestId = model.RequestID(r.Context())
+		recordAccount(r, 7)
+		http.Error(w, "teapot", http.StatusTeapot)
+	})
+	router.HandleFunc("/arts/export", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("id,title\n"))
+		panic(http.ErrAbortHandler)
+	})
+	handler := middleware.Handler(router)
+
+	t.Run("Client request id is propagated and logged", func(t *testing.T) {
+		out.Reset()
+		r := httptest.NewRequest(http.MethodGet, "/arts/42", nil)
+		r.Header.Set("X-Request-ID", "abc")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		var entry accessLogEntry
+		if got := w.Header().Get("X-Request-ID"); got != "abc" {
+			t.Fatalf("X-Request-ID of response (%s) is not equal to 'abc'", got)
+		} else if requestId != "abc" {
+			t.Fatalf("request id in context (%s) is not equal to 'abc'", requestId)
+		} else if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+			t.Fatal(err)
+		} else if entry.RequestID != "abc" || entry.Path != "/arts/{id:[0-9]+}" || entry.Status != http.StatusTeapot || entry.AccountID != 7 || entry.Bytes == 0 {
+			t.Fatalf("unexpected access log entry %+v", entry)
+		}
+	})
+
+	t.Run("Missing request id is generated", func(t *testing.T) {
+		out.Reset()
+		r := httptest.NewRequest(http.MethodGet, "/nowhere", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		var entry accessLogEntry
+		if got := w.Header().Get("X-Request-ID"); got == "" {
+			t.Fatal("expected a generated X-Request-ID")
+		} else if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+			t.Fatal(err)
+		} else if entry.RequestID != got || entry.Status != http.StatusNotFound || entry.Path != "" {
+			t.Fatalf("unexpected access log entry %+v", entry)
+		}
+	})
+
+	t.Run("Aborted responses are logged", func(t *testing.T) {
+		out.Reset()
+		r := httptest.NewRequest(http.MethodGet, "/arts/export", nil)
+		w := httptest.NewRecorder()
+		func() {
+			defer func() {
+				if recovered := recover(); recovered != http.ErrAbortHandler {
+					t.Fatalf("unexpected panic %v", recovered)
+				}
+			}()
+			handler.ServeHTTP(w, r)
+		}()
+
+		var entry accessLogEntry
+		if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+			t.Fatal(err)
+		} else if !entry.Aborted || entry.Status != http.StatusOK || entry.Path != "/arts/export" || entry.Bytes == 0 {
+			t.Fatalf("unexpected access log entry %+v", entry)
+		}
+	})
+}
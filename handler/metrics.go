@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/metrics"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+// Instrumentation of the gallery exposed through a metrics.Registry.
+type Metrics struct {
+	requests     *metrics.Counter
+	latency      *metrics.Histogram
+	queries      *metrics.Histogram
+	authAttempts *metrics.Counter
+}
+
+// Registers the metrics of the gallery in registry, including gauges counting the arts and accounts in the database.
+func (m *Metrics) Init(registry *metrics.Registry, artDB model.ArtRepository, accountDB model.AccountRepository) error {
+	m.requests = &metrics.Counter{
+		Name:   "gallery_http_requests_total",
+		Help:   "Number of HTTP requests by route template, method and status.",
+		Labels: []string{"route", "method", "status"},
+	}
+	m.requests.Register(registry)
+
+	m.latency = &metrics.Histogram{
+		Name:   "gallery_http_request_duration_seconds",
+		Help:   "Latency of HTTP requests by route template, method and status.",
+		Labels: []string{"route", "method", "status"},
+	}
+	m.latency.Register(registry)
+
+	m.queries = &metrics.Histogram{
+		Name:   "gallery_db_query_duration_seconds",
+		Help:   "Duration of database statements by operation and table.",
+		Labels: []string{"operation", "table"},
+	}
+	m.queries.Register(registry)
+
+	m.authAttempts = &metrics.Counter{
+		Name:   "gallery_auth_attempts_total",
+		Help:   "Number of Basic authentication attempts by result.",
+		Labels: []string{"result"},
+	}
+	m.authAttempts.Register(registry)
+
+	arts := &metrics.GaugeFunc{
+		Name: "gallery_arts",
+		Help: "Number of arts in the gallery.",
+		Value: func() (float64, error) {
+			count, err := artDB.CountArts(context.Background())
+			return float64(count), err
+		},
+	}
+	arts.Register(registry)
+
+	accounts := &metrics.GaugeFunc{
+		Name: "gallery_accounts",
+		Help: "Number of registered accounts.",
+		Value: func() (float64, error) {
+			count, err := accountDB.CountAccounts(context.Background())
+			return float64(count), err
+		},
+	}
+	accounts.Register(registry)
+
+	return nil
+}
+
+// A gorm plugin feeding statement durations into the metrics.
+func (m *Metrics) QueryTimer() model.QueryTimer {
+	return model.QueryTimer{
+		Observe: func(operation string, table string, elapsed time.Duration) {
+			m.queries.Observe(elapsed.Seconds(), operation, table)
+		},
+	}
+}
+
+// Counts a Basic authentication attempt as either success or failure.
+// It does nothing on a nil Metrics so that instrumentation stays optional.
+func (m *Metrics) authAttempt(success bool) {
+	if m == nil {
+		return
+	}
+	if success {
+		m.authAttempts.Inc("success")
+	} else {
+		m.authAttempts.Inc("failure")
+	}
+}
+
+// Middleware counting requests and observing their latency.
+// It has to run inside LoggingMiddleware, which provides the route template.
+func (m *Metrics) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+
+		// recorded even if the handler panics, as it does to abort a response
+		completed := false
+		defer func() {
+			route := getRequestInfo(r).route
+			if route == "" {
+				route = "unmatched"
+			}
+			status := strconv.Itoa(recorder.result(completed))
+			m.requests.Inc(route, r.Method, status)
+			m.latency.Observe(time.Since(start).Seconds(), route, r.Method, status)
+		}()
+
+		next.ServeHTTP(recorder, r)
+		completed = true
+	})
+}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/events"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+const (
+	// Page of notifications a client gets without asking for a limit.
+	defaultNotificationsLimit = 20
+	// Largest page of notifications a client can ask for.
+	maxNotificationsLimit = 100
+)
+
+// Turns the events of the gallery into notifications for the accounts they concern.
+// The zero value is ready to use.
+type Notifier struct{}
+
+// Notifies the author of the art of likes and comments, and the author of the parent comment of replies.
+// Nobody is notified of what they did themselves, nor twice of the same event.
+// It is meant to be used by the Outbox of the gallery, so that the notifications are created along with their event.
+func (n Notifier) Notify(ctx context.Context, tx model.Repositories, event events.Event) error {
+	if event.Art == nil {
+		return nil
+	}
+
+	notifications := []dto.NotificationDto{}
+	switch event.Type {
+	case events.ArtLiked:
+		notifications = append(notifications, dto.NotificationDto{AccountId: event.Art.AuthorId, Type: dto.NotificationLike})
+	case events.ArtCommented:
+		if event.Comment == nil {
+			return nil
+		}
+		if event.Comment.ParentId != 0 {
+			if parent, err := tx.Comments.GetComment(ctx, event.Comment.ParentId); err != nil {
+				return err
+			} else {
+				notifications = append(notifications, dto.NotificationDto{AccountId: parent.AuthorId, Type: dto.NotificationReply})
+			}
+		}
+		notifications = append(notifications, dto.NotificationDto{AccountId: event.Art.AuthorId, Type: dto.NotificationComment})
+	}
+
+	notified := map[uint]bool{event.ActorId: true}
+	for _, notification := range notifications {
+		if notified[notification.AccountId] {
+			continue
+		}
+		notified[notification.AccountId] = true
+
+		notification.ActorId = event.ActorId
+		notification.ArtId = event.Art.Id
+		if event.Comment != nil {
+			notification.CommentId = event.Comment.Id
+		}
+		if _, err := tx.Notifications.CreateNotification(ctx, notification); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type NotificationsHandler struct {
+	notificationDB model.NotificationRepository
+	auth           Authenticator
+}
+
+func (h *NotificationsHandler) Init(notificationDB model.NotificationRepository, auth Authenticator) error {
+	h.notificationDB = notificationDB
+	h.auth = auth
+
+	return nil
+}
+
+// Lists the notifications of account, newest first.
+// Only unread notifications are listed if the unread query parameter is true, and the before query parameter pages through older ones.
+func (h NotificationsHandler) GetNotifications(w http.ResponseWriter, r *http.Request, account dto.AccountDto) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	unread := false
+	if s := query.Get("unread"); s != "" {
+		var err error
+		if unread, err = strconv.ParseBool(s); err != nil {
+			http.Error(w, "unread must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var before uint64
+	if s := query.Get("before"); s != "" {
+		var err error
+		if before, err = strconv.ParseUint(s, 10, 32); err != nil {
+			http.Error(w, "before must be a positive integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := uint64(defaultNotificationsLimit)
+	if s := query.Get("limit"); s != "" {
+		var err error
+		if limit, err = strconv.ParseUint(s, 10, 32); err != nil || limit == 0 || limit > maxNotificationsLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxNotificationsLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if notifications, err := h.notificationDB.GetNotifications(r.Context(), account.Id, unread, uint(before), int(limit)); err != nil {
+		modelError(w, err)
+	} else {
+		json.NewEncoder(w).Encode(notifications)
+	}
+}
+
+// Marks the notification by id of account as read and responds with it.
+func (h NotificationsHandler) PostRead(w http.ResponseWriter, r *http.Request, id uint, account dto.AccountDto) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if notification, err := h.notificationDB.MarkRead(r.Context(), account.Id, id); err != nil {
+		modelError(w, err)
+	} else {
+		json.NewEncoder(w).Encode(notification)
+	}
+}
+
+// Marks every notification of account as read and responds with how many were unread.
+func (h NotificationsHandler) PostReadAll(w http.ResponseWriter, r *http.Request, account dto.AccountDto) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if marked, err := h.notificationDB.MarkAllRead(r.Context(), account.Id); err != nil {
+		modelError(w, err)
+	} else {
+		json.NewEncoder(w).Encode(map[string]int64{"marked": marked})
+	}
+}
+
+func (h NotificationsHandler) NotificationsFuncHandler(w http.ResponseWriter, r *http.Request) {
+	h.auth.AccountAuth(w, r, func(account dto.AccountDto) {
+		h.GetNotifications(w, r, account)
+	})
+}
+
+func (h NotificationsHandler) ReadFuncHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 10, 32)
+
+	h.auth.AccountAuth(w, r, func(account dto.AccountDto) {
+		h.PostRead(w, r, uint(id), account)
+	})
+}
+
+func (h NotificationsHandler) ReadAllFuncHandler(w http.ResponseWriter, r *http.Request) {
+	h.auth.AccountAuth(w, r, func(account dto.AccountDto) {
+		h.PostReadAll(w, r, account)
+	})
+}
+
+// Registers the routes served by the handler on router.
+func (h NotificationsHandler) Routes(router *mux.Router) {
+	router.HandleFunc("/notifications", h.NotificationsFuncHandler).Methods(http.MethodGet)
+	router.HandleFunc("/notifications/", h.NotificationsFuncHandler).Methods(http.MethodGet)
+
+	router.HandleFunc("/notifications/read", h.ReadAllFuncHandler).Methods(http.MethodPost)
+	router.HandleFunc("/notifications/read/", h.ReadAllFuncHandler).Methods(http.MethodPost)
+
+	router.HandleFunc("/notifications/{id:[0-9]+}/read", h.ReadFuncHandler).Methods(http.MethodPost)
+	router.HandleFunc("/notifications/{id:[0-9]+}/read/", h.ReadFuncHandler).Methods(http.MethodPost)
+}
+
+func (h NotificationsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	router := mux.NewRouter()
+	router.Use(recordRoute)
+	h.Routes(router)
+	router.ServeHTTP(w, r)
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/nafiz1001/gallery-go/dto"
+)
+
+func TestNotifications(t *testing.T) {
+	// more requests are made to /notifications than its rate limit allows
+	server := newTestServer(t, testServerOptions{Unlimited: []string{"notifications"}})
+	repos := server.Repos
+
+	ctx := context.Background()
+	for _, username := range []string{"artist", "fan", "replier"} {
+		_, err := repos.Accounts.CreateAccount(ctx, dto.AccountDto{Username: username, Password: "password"})
+		CheckError(t, err)
+	}
+	_, err := repos.Arts.CreateArt(ctx, dto.ArtDto{Title: "title", Quantity: 1, AuthorId: 1})
+	CheckError(t, err)
+
+	getNotifications := func(t *testing.T, username string, query string) []dto.NotificationDto {
+		var notifications []dto.NotificationDto
+		if status := SendAs(t, http.MethodGet, server.URL+"/notifications"+query, username, nil, &notifications); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		}
+		return notifications
+	}
+
+	t.Run("Events", func(t *testing.T) {
+		// liking again and liking an art of one's own notify nobody
+		for _, username := range []string{"fan", "fan", "artist"} {
+			if status := SendAs(t, http.MethodPut, server.URL+"/arts/1/like", username, nil, nil); status != http.StatusOK {
+				t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+			}
+		}
+
+		var comment dto.CommentDto
+		if status := SendAs(t, http.MethodPost, server.URL+"/arts/1/comments", "fan", dto.CommentDto{Body: "nice"}, &comment); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		}
+		// the artist isn't told of replying themselves
+		for _, username := range []string{"replier", "artist"} {
+			if status := SendAs(t, http.MethodPost, server.URL+"/arts/1/comments", username, dto.CommentDto{Body: "reply", ParentId: comment.Id}, nil); status != http.StatusOK {
+				t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+			}
+		}
+
+		artist := getNotifications(t, "artist", "")
+		types := []string{}
+		for _, notification := range artist {
+			types = append(types, fmt.Sprint(notification.Type, notification.ActorId))
+		}
+		if fmt.Sprint(types) != "[comment3 comment2 like2]" {
+			t.Fatalf("unexpected notifications %v", artist)
+		} else if artist[1].CommentId != comment.Id || artist[2].CommentId != 0 || artist[0].Read {
+			t.Fatalf("unexpected notifications %v", artist)
+		}
+
+		fan := getNotifications(t, "fan", "")
+		if len(fan) != 2 || fan[0].Type != dto.NotificationReply || fan[0].ActorId != 1 || fan[1].ActorId != 3 {
+			t.Fatalf("unexpected notifications %v", fan)
+		} else if replier := getNotifications(t, "replier", ""); len(replier) != 0 {
+			t.Fatalf("unexpected notifications %v", replier)
+		}
+	})
+
+	t.Run("Read", func(t *testing.T) {
+		artist := getNotifications(t, "artist", "?limit=1")
+		if len(artist) != 1 {
+			t.Fatalf("unexpected notifications %v", artist)
+		} else if older := getNotifications(t, "artist", fmt.Sprintf("?before=%d", artist[0].Id)); len(older) != 2 {
+			t.Fatalf("unexpected notifications %v", older)
+		}
+
+		var read dto.NotificationDto
+		if status := SendAs(t, http.MethodPost, fmt.Sprintf("%s/notifications/%d/read", server.URL, artist[0].Id), "artist", nil, &read); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if !read.Read || read.Id != artist[0].Id {
+			t.Fatalf("unexpected notification %v", read)
+		} else if unread := getNotifications(t, "artist", "?unread=true"); len(unread) != 2 {
+			t.Fatalf("unexpected notifications %v", unread)
+		}
+
+		var marked map[string]int64
+		if status := SendAs(t, http.MethodPost, server.URL+"/notifications/read", "artist", nil, &marked); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if marked["marked"] != 2 {
+			t.Fatalf("unexpected response %v", marked)
+		} else if unread := getNotifications(t, "artist", "?unread=true"); len(unread) != 0 {
+			t.Fatalf("unexpected notifications %v", unread)
+		} else if unread := getNotifications(t, "fan", "?unread=true"); len(unread) != 2 {
+			t.Fatalf("unexpected notifications %v", unread)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for _, test := range []struct {
+			method   string
+			url      string
+			username string
+			status   int
+		}{
+			{http.MethodGet, server.URL + "/notifications", "", http.StatusUnauthorized},
+			{http.MethodGet, server.URL + "/notifications?unread=maybe", "artist", http.StatusBadRequest},
+			{http.MethodGet, server.URL + "/notifications?limit=1000", "artist", http.StatusBadRequest},
+			{http.MethodGet, server.URL + "/notifications?before=-1", "artist", http.StatusBadRequest},
+			{http.MethodPost, server.URL + "/notifications/read", "", http.StatusUnauthorized},
+			// the notification belongs to the fan
+			{http.MethodPost, server.URL + "/notifications/3/read", "artist", http.StatusNotFound},
+			{http.MethodPost, server.URL + "/notifications/100/read", "artist", http.StatusNotFound},
+		} {
+			if status := SendAs(t, test.method, test.url, test.username, nil, nil); status != test.status {
+				t.Fatalf("%s %s as '%s': %d is not equal to %d", test.method, test.url, test.username, status, test.status)
+			}
+		}
+	})
+}
@@ -0,0 +1,17 @@
+package handler
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// OpenAPI 3 document describing the routes of GalleryHandler.
+// TestOpenAPI keeps it in sync with the routes and the DTOs.
+//
+//go:embed openapi.json
+var openAPI []byte
+
+func GetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPI)
+}
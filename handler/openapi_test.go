@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model/memory"
+)
+
+type openAPIDocument struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+// Turns a mux path template such as /arts/{id:[0-9]+}/ into the OpenAPI path /arts/{id}.
+func openAPIPath(template string) string {
+	path := regexp.MustCompile(`\{([^:}]+):[^}]+\}`).ReplaceAllString(template, "{$1}")
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path
+}
+
+// Gets the JSON names of the fields of a DTO.
+func jsonFields(t reflect.Type) []string {
+	fields := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if field.Anonymous && name == "" {
+			// Fields of an embedded struct are encoded as if they were its own.
+			fields = append(fields, jsonFields(field.Type)...)
+		} else if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func TestOpenAPI(t *testing.T) {
+	var doc openAPIDocument
+	CheckError(t, json.Unmarshal(openAPI, &doc))
+
+	memoryDB := &memory.DB{}
+	CheckError(t, memoryDB.Init())
+	repos, err := memoryDB.Repositories()
+	CheckError(t, err)
+
+	h := GalleryHandler{AccessLog: io.Discard}
+	CheckError(t, h.Init(repos))
+
+	t.Run("Every route is in the spec", func(t *testing.T) {
+		router := mux.NewRouter()
+		h.Routes(router)
+
+		registered := map[string]bool{}
+		err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+			template, err := route.GetPathTemplate()
+			if err != nil {
+				// subrouters of the rate limit groups have no path of their own
+				return nil
+			}
+			methods, err := route.GetMethods()
+			if err != nil {
+				t.Errorf("%s accepts any method", template)
+				return nil
+			}
+
+			path := openAPIPath(template)
+			for _, method := range methods {
+				operation := strings.ToLower(method)
+				registered[path+" "+operation] = true
+				if _, ok := doc.Paths[path][operation]; !ok {
+					t.Errorf("%s %s is missing from openapi.json", method, path)
+				}
+			}
+			return nil
+		})
+		CheckError(t, err)
+
+		for path, operations := range doc.Paths {
+			for operation := range operations {
+				if operation != "parameters" && !registered[path+" "+operation] {
+					t.Errorf("%s %s is in openapi.json but not routed", strings.ToUpper(operation), path)
+				}
+			}
+		}
+	})
+
+	t.Run("Every schema matches its DTO", func(t *testing.T) {
+		dtos := map[string]reflect.Type{
+			"Account":         reflect.TypeOf(dto.AccountDto{}),
+			"Art":             reflect.TypeOf(dto.ArtDto{}),
+			"ExportedArt":     reflect.TypeOf(dto.ArtExportDto{}),
+			"Sale":            reflect.TypeOf(dto.SaleDto{}),
+			"PublishedArt":    reflect.TypeOf(dto.PublishedArtDto{}),
+			"Comment":         reflect.TypeOf(dto.CommentDto{}),
+			"Feed":            reflect.TypeOf(dto.FeedDto{}),
+			"Notification":    reflect.TypeOf(dto.NotificationDto{}),
+			"ArtEvent":        reflect.TypeOf(dto.ArtEventDto{}),
+			"Subscription":    reflect.TypeOf(dto.InventoryRequestDto{}),
+			"Inventory":       reflect.TypeOf(dto.InventoryDto{}),
+			"Webhook":         reflect.TypeOf(dto.WebhookDto{}),
+			"WebhookDelivery": reflect.TypeOf(dto.WebhookDeliveryDto{}),
+			"ArtRevision":     reflect.TypeOf(dto.ArtRevisionDto{}),
+			"FieldChange":     reflect.TypeOf(dto.FieldChange{}),
+			"Backup":          reflect.TypeOf(dto.BackupDto{}),
+			"AuditEntry":      reflect.TypeOf(dto.AuditEntryDto{}),
+			"ImportRow":       reflect.TypeOf(dto.ImportRowDto{}),
+			"ImportReport":    reflect.TypeOf(dto.ImportReportDto{}),
+			"BatchOperation":  reflect.TypeOf(dto.BatchOperationDto{}),
+			"Batch":           reflect.TypeOf(dto.BatchDto{}),
+			"BatchResult":     reflect.TypeOf(dto.BatchResultDto{}),
+			"BatchReport":     reflect.TypeOf(dto.BatchReportDto{}),
+			"Check":           reflect.TypeOf(dto.CheckDto{}),
+			"Health":          reflect.TypeOf(dto.HealthDto{}),
+		}
+
+		for name, schema := range doc.Components.Schemas {
+			dtoType, ok := dtos[name]
+			if !ok {
+				t.Errorf("schema %s has no DTO", name)
+				continue
+			}
+
+			properties := []string{}
+			for property := range schema.Properties {
+				properties = append(properties, property)
+			}
+			sort.Strings(properties)
+
+			if fields := jsonFields(dtoType); !reflect.DeepEqual(properties, fields) {
+				t.Errorf("schema %s has properties %v but %s has fields %v", name, properties, dtoType.Name(), fields)
+			}
+		}
+		for name := range dtos {
+			if _, ok := doc.Components.Schemas[name]; !ok {
+				t.Errorf("%s is missing from openapi.json", name)
+			}
+		}
+	})
+
+	t.Run("Spec is served", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", w.Code, http.StatusOK)
+		} else if w.Body.String() != string(openAPI) {
+			t.Fatalf("the served document is not openapi.json")
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/events"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+// Records an event in the transaction of the operation that caused it, so that it is committed along with the
+// operation or not at all. An error rolls the operation back.
+type Recorder func(ctx context.Context, tx model.Repositories, event events.Event) error
+
+// Records the events of the gallery in the transactions of their operations, then publishes them on a bus once these
+// are committed. What must not be lost, such as notifications, is recorded; what may be, such as telling the open
+// connections, subscribes to the bus.
+type Outbox struct {
+	bus       *events.Bus
+	recorders []Recorder
+}
+
+// Events are published on bus once committed.
+func (o *Outbox) Init(bus *events.Bus) error {
+	o.bus = bus
+	return nil
+}
+
+// Adds a recorder, called in the order they were added.
+func (o *Outbox) Use(recorder Recorder) {
+	o.recorders = append(o.recorders, recorder)
+}
+
+// Records event in tx with every recorder, stopping at the first that fails.
+// The time of event is set if missing, so that it is published as it was recorded.
+func (o *Outbox) Record(ctx context.Context, tx model.Repositories, event *events.Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	for _, recorder := range o.recorders {
+		if err := recorder(ctx, tx, *event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Publishes an event recorded by Record, once its transaction is committed.
+func (o *Outbox) Publish(ctx context.Context, event events.Event) {
+	o.bus.Publish(ctx, event)
+}
+
+// Runs f in a transaction of repos and records the events it returns in the same transaction.
+// The events are published once the transaction is committed.
+func (o *Outbox) Transaction(ctx context.Context, repos model.Repositories, f func(tx model.Repositories) ([]events.Event, error)) error {
+	var recorded []events.Event
+	err := repos.Transaction(ctx, func(tx model.Repositories) error {
+		happened, err := f(tx)
+		if err != nil {
+			return err
+		}
+
+		for i := range happened {
+			if err := o.Record(ctx, tx, &happened[i]); err != nil {
+				return err
+			}
+		}
+		recorded = happened
+		return nil
+	})
+
+	if err == nil {
+		for _, event := range recorded {
+			o.Publish(ctx, event)
+		}
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/events"
+	"github.com/nafiz1001/gallery-go/model"
+	"github.com/nafiz1001/gallery-go/model/memory"
+)
+
+func TestOutbox(t *testing.T) {
+	memoryDB := &memory.DB{}
+	CheckError(t, memoryDB.Init())
+	repos, err := memoryDB.Repositories()
+	CheckError(t, err)
+
+	ctx := context.Background()
+	artist, err := repos.Accounts.CreateAccount(ctx, dto.AccountDto{Username: "artist", Password: "password"})
+	CheckError(t, err)
+	fan, err := repos.Accounts.CreateAccount(ctx, dto.AccountDto{Username: "fan", Password: "password"})
+	CheckError(t, err)
+	art, err := repos.Arts.CreateArt(ctx, dto.ArtDto{Title: "title", Quantity: 1, AuthorId: artist.Id})
+	CheckError(t, err)
+
+	bus := &events.Bus{}
+	published := []events.Event{}
+	bus.Subscribe(func(ctx context.Context, event events.Event) {
+		published = append(published, event)
+	})
+
+	errRecorder := errors.New("recorder failed")
+	fail := false
+	outbox := Outbox{}
+	CheckError(t, outbox.Init(bus))
+	outbox.Use(Notifier{}.Notify)
+	outbox.Use(func(ctx context.Context, tx model.Repositories, event events.Event) error {
+		if fail {
+			return errRecorder
+		}
+		return nil
+	})
+
+	like := func(tx model.Repositories) ([]events.Event, error) {
+		if _, err := tx.Likes.LikeArt(ctx, fan.Id, art.Id); err != nil {
+			return nil, err
+		}
+		return []events.Event{{Type: events.ArtLiked, ActorId: fan.Id, Art: art}}, nil
+	}
+
+	t.Run("A failed recorder rolls the operation back", func(t *testing.T) {
+		fail = true
+		defer func() { fail = false }()
+
+		if err := outbox.Transaction(ctx, repos, like); !errors.Is(err, errRecorder) {
+			t.Fatalf("unexpected error %v", err)
+		} else if len(published) != 0 {
+			t.Fatalf("unexpected events %v", published)
+		}
+
+		// neither the like nor the notification of the artist were kept
+		if counts, _, err := repos.Likes.GetLikes(ctx, 0, []uint{art.Id}); err != nil || counts[art.Id] != 0 {
+			t.Fatalf("unexpected likes %v: %v", counts, err)
+		} else if notifications, err := repos.Notifications.GetNotifications(ctx, artist.Id, false, 0, 10); err != nil || len(notifications) != 0 {
+			t.Fatalf("unexpected notifications %v: %v", notifications, err)
+		}
+	})
+
+	t.Run("Events are published once committed", func(t *testing.T) {
+		CheckError(t, outbox.Transaction(ctx, repos, like))
+		if len(published) != 1 || published[0].Type != events.ArtLiked || published[0].Time.IsZero() {
+			t.Fatalf("unexpected events %v", published)
+		}
+
+		if notifications, err := repos.Notifications.GetNotifications(ctx, artist.Id, false, 0, 10); err != nil || len(notifications) != 1 {
+			t.Fatalf("unexpected notifications %v: %v", notifications, err)
+		} else if notifications[0].Type != dto.NotificationLike || notifications[0].ActorId != fan.Id {
+			t.Fatalf("unexpected notification %v", notifications[0])
+		}
+	})
+}
@@ -0,0 +1,241 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Rate at which a token bucket refills and how many tokens it can hold.
+// A non-positive PerSecond disables the limit.
+type Rate struct {
+	PerSecond float64
+	Burst     int
+}
+
+// Limits applied to a group of routes, such as everything under /arts.
+type RateLimitConfig struct {
+	IP       Rate
+	Username Rate
+}
+
+// Limits used for route groups that are not configured explicitly.
+func DefaultRateLimits() map[string]RateLimitConfig {
+	return map[string]RateLimitConfig{
+		"accounts":      {IP: Rate{PerSecond: 2, Burst: 10}, Username: Rate{PerSecond: 2, Burst: 10}},
+		"arts":          {IP: Rate{PerSecond: 20, Burst: 40}, Username: Rate{PerSecond: 10, Burst: 20}},
+		"audit":         {IP: Rate{PerSecond: 5, Burst: 10}, Username: Rate{PerSecond: 5, Burst: 10}},
+		"admin":         {IP: Rate{PerSecond: 1, Burst: 5}, Username: Rate{PerSecond: 1, Burst: 5}},
+		"notifications": {IP: Rate{PerSecond: 10, Burst: 20}, Username: Rate{PerSecond: 5, Burst: 10}},
+		"webhooks":      {IP: Rate{PerSecond: 5, Burst: 10}, Username: Rate{PerSecond: 5, Burst: 10}},
+		"events":        {IP: Rate{PerSecond: 1, Burst: 5}, Username: Rate{PerSecond: 1, Burst: 5}},
+		"inventory":     {IP: Rate{PerSecond: 1, Burst: 5}, Username: Rate{PerSecond: 1, Burst: 5}},
+	}
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// A set of token buckets keyed by an arbitrary string such as an IP address.
+type RateLimiter struct {
+	rate    Rate
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	now     func() time.Time
+}
+
+// Buckets are dropped once they are full again and the limiter tracks more keys than this.
+const maxIdleBuckets = 10000
+
+func (l *RateLimiter) Init(rate Rate) error {
+	l.rate = rate
+	l.buckets = map[string]*tokenBucket{}
+	if l.now == nil {
+		l.now = time.Now
+	}
+	return nil
+}
+
+// Takes a token from the bucket of key.
+// It returns zero if the token was available, otherwise how long until one will be.
+func (l *RateLimiter) Take(key string) time.Duration {
+	if l.rate.PerSecond <= 0 {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	bucket, ok := l.buckets[key]
+	if !ok {
+		l.prune(now)
+		bucket = &tokenBucket{tokens: float64(l.rate.Burst), last: now}
+		l.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(float64(l.rate.Burst), bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate.PerSecond)
+	bucket.last = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return 0
+	}
+	return time.Duration((1 - bucket.tokens) / l.rate.PerSecond * float64(time.Second))
+}
+
+func (l *RateLimiter) prune(now time.Time) {
+	if len(l.buckets) < maxIdleBuckets {
+		return
+	}
+	for key, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate.PerSecond >= float64(l.rate.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Middleware enforcing the per-IP and per-username limits of a route group.
+type RateLimitMiddleware struct {
+	ip       RateLimiter
+	username RateLimiter
+}
+
+func (m *RateLimitMiddleware) Init(config RateLimitConfig) error {
+	if err := m.ip.Init(config.IP); err != nil {
+		return err
+	}
+	return m.username.Init(config.Username)
+}
+
+func (m *RateLimitMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if wait := m.ip.Take(clientIP(r)); wait > 0 {
+			tooManyRequests(w, wait, "too many requests from this address")
+		} else if username, _, ok := r.BasicAuth(); ok {
+			if wait := m.username.Take(username); wait > 0 {
+				tooManyRequests(w, wait, fmt.Sprintf("too many requests for '%s'", username))
+			} else {
+				next.ServeHTTP(w, r)
+			}
+		} else {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+type loginFailures struct {
+	count       int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+// Tracks failed logins per key and locks a key out once it failed too often.
+// Each failure past MaxFailures doubles the lockout, starting at BaseLockout and capped at MaxLockout.
+// Failures older than Window are forgotten.
+type LoginGuard struct {
+	MaxFailures int
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+	Window      time.Duration
+
+	mu       sync.Mutex
+	failures map[string]*loginFailures
+	now      func() time.Time
+}
+
+func (g *LoginGuard) Init() error {
+	if g.MaxFailures <= 0 {
+		g.MaxFailures = 5
+	}
+	if g.BaseLockout <= 0 {
+		g.BaseLockout = time.Second
+	}
+	if g.MaxLockout <= 0 {
+		g.MaxLockout = 15 * time.Minute
+	}
+	if g.Window <= 0 {
+		g.Window = time.Hour
+	}
+	g.failures = map[string]*loginFailures{}
+	if g.now == nil {
+		g.now = time.Now
+	}
+	return nil
+}
+
+// Returns how long key stays locked out, or zero if it may attempt to log in.
+func (g *LoginGuard) Check(key string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if f, ok := g.failures[key]; !ok {
+		return 0
+	} else if wait := f.lockedUntil.Sub(g.now()); wait > 0 {
+		return wait
+	} else {
+		return 0
+	}
+}
+
+// Records a failed login of key.
+func (g *LoginGuard) Fail(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	f, ok := g.failures[key]
+	if !ok || now.Sub(f.last) > g.Window {
+		g.prune(now)
+		f = &loginFailures{}
+		g.failures[key] = f
+	}
+
+	f.count++
+	f.last = now
+	if excess := f.count - g.MaxFailures; excess >= 0 {
+		lockout := g.MaxLockout
+		if excess < 32 {
+			lockout = time.Duration(math.Min(float64(g.BaseLockout)*math.Pow(2, float64(excess)), float64(g.MaxLockout)))
+		}
+		f.lockedUntil = now.Add(lockout)
+	}
+}
+
+// Forgets the failed logins of key.
+func (g *LoginGuard) Succeed(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.failures, key)
+}
+
+func (g *LoginGuard) prune(now time.Time) {
+	if len(g.failures) < maxIdleBuckets {
+		return
+	}
+	for key, f := range g.failures {
+		if now.Sub(f.last) > g.Window && now.After(f.lockedUntil) {
+			delete(g.failures, key)
+		}
+	}
+}
+
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err != nil {
+		return r.RemoteAddr
+	} else {
+		return host
+	}
+}
+
+func tooManyRequests(w http.ResponseWriter, wait time.Duration, message string) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, message, http.StatusTooManyRequests)
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func TestRateLimiter(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	limiter := RateLimiter{now: clock.Now}
+	CheckError(t, limiter.Init(Rate{PerSecond: 1, Burst: 2}))
+
+	// the burst is available right away
+	for i := 0; i < 2; i++ {
+		if wait := limiter.Take("key"); wait != 0 {
+			t.Fatalf("expected token %d to be available but had to wait %s", i, wait)
+		}
+	}
+
+	// the bucket is empty
+	if wait := limiter.Take("key"); wait != time.Second {
+		t.Fatalf("expected to wait 1s but had to wait %s", wait)
+	}
+
+	// other keys have their own bucket
+	if wait := limiter.Take("other"); wait != 0 {
+		t.Fatalf("expected token of another key to be available but had to wait %s", wait)
+	}
+
+	// the bucket refills over time
+	clock.Advance(time.Second)
+	if wait := limiter.Take("key"); wait != 0 {
+		t.Fatalf("expected token to be available after refill but had to wait %s", wait)
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	middleware := RateLimitMiddleware{}
+	CheckError(t, middleware.Init(RateLimitConfig{
+		IP:       Rate{PerSecond: 0.001, Burst: 3},
+		Username: Rate{PerSecond: 0.001, Burst: 1},
+	}))
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	request := func(username string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/arts", nil)
+		if username != "" {
+			r.SetBasicAuth(username, "password")
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := request("good"); w.Code != http.StatusOK {
+		t.Fatalf("expected first request to pass but got %d", w.Code)
+	}
+
+	// the username is out of tokens
+	if w := request("good"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected %d but got %d", http.StatusTooManyRequests, w.Code)
+	} else if w.Header().Get("Retry-After") == "" {
+		t.Fatal("expected Retry-After header")
+	}
+
+	// anonymous requests are only limited by address
+	if w := request(""); w.Code != http.StatusOK {
+		t.Fatalf("expected anonymous request to pass but got %d", w.Code)
+	}
+
+	// the address is out of tokens
+	if w := request("other"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected %d but got %d", http.StatusTooManyRequests, w.Code)
+	}
+}
+
+func TestLoginGuard(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	guard := LoginGuard{
+		MaxFailures: 2,
+		BaseLockout: time.Second,
+		MaxLockout:  3 * time.Second,
+		now:         clock.Now,
+	}
+	CheckError(t, guard.Init())
+
+	// no lockout below the threshold
+	guard.Fail("key")
+	if wait := guard.Check("key"); wait != 0 {
+		t.Fatalf("expected no lockout but got %s", wait)
+	}
+
+	// the lockout doubles with every failure past the threshold
+	for _, expected := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		guard.Fail("key")
+		if wait := guard.Check("key"); wait != expected {
+			t.Fatalf("expected lockout of %s but got %s", expected, wait)
+		}
+	}
+
+	// the lockout expires
+	clock.Advance(3 * time.Second)
+	if wait := guard.Check("key"); wait != 0 {
+		t.Fatalf("expected lockout to expire but got %s", wait)
+	}
+
+	// a successful login resets the failures
+	guard.Succeed("key")
+	guard.Fail("key")
+	if wait := guard.Check("key"); wait != 0 {
+		t.Fatalf("expected no lockout after success but got %s", wait)
+	}
+}
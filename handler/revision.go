@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/events"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+func (h ArtsHandler) GetArtRevisions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 10, 32)
+
+	if revisions, err := h.artDB.GetArtRevisions(r.Context(), uint(id)); err != nil {
+		modelError(w, err)
+	} else {
+		json.NewEncoder(w).Encode(revisions)
+	}
+}
+
+func (h ArtsHandler) GetArtRevision(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 10, 32)
+	n, _ := strconv.ParseInt(vars["n"], 10, 32)
+
+	if revision, err := h.artDB.GetArtRevision(r.Context(), uint(id), uint(n)); err != nil {
+		modelError(w, err)
+	} else {
+		json.NewEncoder(w).Encode(revision)
+	}
+}
+
+// Compares revision n with the revision given by the "against" query parameter, or with the current art if it is missing.
+func (h ArtsHandler) GetArtRevisionDiff(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 10, 32)
+	n, _ := strconv.ParseInt(vars["n"], 10, 32)
+
+	var against uint64
+	if s := r.URL.Query().Get("against"); s != "" {
+		var err error
+		if against, err = strconv.ParseUint(s, 10, 32); err != nil {
+			http.Error(w, "against must be a revision number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if diff, err := h.artDB.DiffArtRevisions(r.Context(), uint(id), uint(n), uint(against)); err != nil {
+		modelError(w, err)
+	} else {
+		json.NewEncoder(w).Encode(diff)
+	}
+}
+
+func (h ArtsHandler) RestoreArtRevision(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 10, 32)
+	n, _ := strconv.ParseInt(vars["n"], 10, 32)
+
+	h.AuthorAuth(w, r, uint(id), func(account dto.AccountDto, before dto.ArtDto) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var art *dto.ArtDto
+		err := h.outbox.Transaction(r.Context(), h.repos, func(tx model.Repositories) ([]events.Event, error) {
+			var err error
+			if art, err = tx.Arts.RestoreArtRevision(r.Context(), uint(id), uint(n)); err != nil {
+				return nil, err
+			}
+			if err := recordAudit(tx.Audit, r, account.Id, "restore", "art", art.Id, before, art); err != nil {
+				return nil, err
+			}
+			if err := withArtLikes(r.Context(), tx.Likes, &account, art); err != nil {
+				return nil, err
+			}
+			return []events.Event{{Type: events.ArtUpdated, ActorId: account.Id, Art: art, Before: &before}}, nil
+		})
+		if err != nil {
+			modelError(w, err)
+		} else {
+			json.NewEncoder(w).Encode(art)
+		}
+	})
+}
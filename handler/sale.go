@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/events"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+// Sells copies of the art to account and responds with the art as it is left.
+// The sale is published as an update of the art, so the inventory of its subscribers turns sold_out with the last copy.
+func (h ArtsHandler) PostSale(w http.ResponseWriter, r *http.Request, id uint, account dto.AccountDto) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var sale dto.SaleDto
+	if err := json.NewDecoder(r.Body).Decode(&sale); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	var art *dto.ArtDto
+	err := h.outbox.Transaction(r.Context(), h.repos, func(tx model.Repositories) ([]events.Event, error) {
+		before, err := tx.Arts.GetArt(r.Context(), id)
+		if err != nil {
+			return nil, err
+		}
+		if art, err = tx.Arts.SellArt(r.Context(), id, sale.Quantity); err != nil {
+			return nil, err
+		}
+		if err := recordAudit(tx.Audit, r, account.Id, "sell", "art", art.Id, before, art); err != nil {
+			return nil, err
+		}
+		if err := withArtLikes(r.Context(), tx.Likes, &account, art); err != nil {
+			return nil, err
+		}
+		return []events.Event{{Type: events.ArtUpdated, ActorId: account.Id, Art: art, Before: before}}, nil
+	})
+	if err != nil {
+		modelError(w, err)
+	} else {
+		json.NewEncoder(w).Encode(art)
+	}
+}
+
+func (h ArtsHandler) SaleFuncHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 10, 32)
+
+	h.AccountAuth(w, r, func(account dto.AccountDto) {
+		h.PostSale(w, r, uint(id), account)
+	})
+}
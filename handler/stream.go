@@ -0,0 +1,292 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/events"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+const (
+	// Events read from the log at a time while a client catches up.
+	streamBatchSize = 100
+	// Time a write to a stream has to complete in.
+	streamWriteTimeout = 15 * time.Second
+	// Milliseconds EventSource clients wait before reconnecting.
+	streamRetry = 3000
+	// Interval between two prunes of the log.
+	artEventPruneInterval = time.Hour
+)
+
+// Logs the changes to the catalog and tells the streams of EventsHandler about them once they are committed.
+type ArtEventLog struct {
+	artEventDB model.ArtEventRepository
+	retention  time.Duration
+
+	mu sync.Mutex
+	// Closed and replaced every time events are logged, see logged.
+	appended   chan struct{}
+	lastPruned time.Time
+}
+
+// Events older than retention are pruned from the log as new ones are appended.
+func (l *ArtEventLog) Init(artEventDB model.ArtEventRepository, retention time.Duration) error {
+	l.artEventDB = artEventDB
+	l.retention = retention
+	l.appended = make(chan struct{})
+	return nil
+}
+
+// Logs the creation, update and deletion of arts in tx, and whether an update changed the quantity of the art, so that
+// the log has the changes that were committed and only those. It is meant to be a recorder of the Outbox of the
+// gallery, see Notify.
+func (l *ArtEventLog) Record(ctx context.Context, tx model.Repositories, event events.Event) error {
+	if event.Art == nil {
+		return nil
+	}
+
+	art := dto.PublishedArt(*event.Art)
+
+	logged := []dto.ArtEventDto{}
+	switch event.Type {
+	case events.ArtCreated, events.ArtUpdated, events.ArtDeleted:
+		logged = append(logged, dto.ArtEventDto{Type: event.Type, Art: art, CreatedAt: event.Time})
+		if event.Type == events.ArtUpdated && event.Before != nil && event.Before.Quantity != art.Quantity {
+			previous := event.Before.Quantity
+			logged = append(logged, dto.ArtEventDto{Type: dto.ArtQuantityChanged, Art: art, PreviousQuantity: &previous, CreatedAt: event.Time})
+		}
+	default:
+		return nil
+	}
+
+	for _, artEvent := range logged {
+		if _, err := tx.ArtEvents.AppendArtEvent(ctx, artEvent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tells the streams about the events logged by Record once they are committed, and prunes the log now and then.
+// It is meant to be subscribed to the events.Bus of the gallery.
+func (l *ArtEventLog) Notify(ctx context.Context, event events.Event) {
+	if event.Art == nil {
+		return
+	}
+
+	l.notify()
+	l.prune(ctx)
+}
+
+// A channel closed once events are logged after the call.
+func (l *ArtEventLog) logged() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.appended
+}
+
+func (l *ArtEventLog) notify() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	close(l.appended)
+	l.appended = make(chan struct{})
+}
+
+// Deletes the events older than the retention, at most once per artEventPruneInterval.
+func (l *ArtEventLog) prune(ctx context.Context) {
+	l.mu.Lock()
+	due := time.Since(l.lastPruned) >= artEventPruneInterval
+	if due {
+		l.lastPruned = time.Now()
+	}
+	l.mu.Unlock()
+
+	if due {
+		if _, err := l.artEventDB.PruneArtEvents(ctx, time.Now().Add(-l.retention)); err != nil {
+			log.Printf("events: prune: %s", err)
+		}
+	}
+}
+
+type connKey struct{}
+
+// Remembers the connection of a request, so that the streams of /events, the exports of /arts/export and the backups of
+// /admin/backups can outlive the read and write timeouts of the server. It is meant to be the ConnContext of the
+// http.Server serving the gallery over HTTP/1.
+func ConnContext(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connKey{}, c)
+}
+
+// Lifts the read timeout of the server from the connection of r, if ConnContext remembered it, which would otherwise
+// cancel a long response. The client closing the connection still does.
+func clearReadDeadline(r *http.Request) {
+	if conn, ok := r.Context().Value(connKey{}).(net.Conn); ok {
+		conn.SetReadDeadline(time.Time{})
+	}
+}
+
+// Gives the next writes to the connection of r, if ConnContext remembered it, streamWriteTimeout to complete in
+// rather than what is left of the write timeout of the server.
+func extendWriteDeadline(r *http.Request) {
+	if conn, ok := r.Context().Value(connKey{}).(net.Conn); ok {
+		conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	}
+}
+
+// Whether r is served past GalleryHandler.RequestTimeout: event streams and WebSockets last as long as the client
+// stays, exports as long as the catalog takes to stream, and backups until GalleryHandler.BackupTimeout.
+func isLongRunning(r *http.Request) bool {
+	switch strings.TrimSuffix(r.URL.Path, "/") {
+	case "/events", "/inventory", "/arts/export", "/admin/backups":
+		return true
+	default:
+		return false
+	}
+}
+
+// Streams the changes to the catalog as Server-Sent Events.
+type EventsHandler struct {
+	artEventDB model.ArtEventRepository
+	log        *ArtEventLog
+	heartbeat  time.Duration
+}
+
+// A comment is sent on idle streams every heartbeat, which keeps proxies from closing them.
+// The log is also read again then, in case another server appended to it.
+func (h *EventsHandler) Init(artEventDB model.ArtEventRepository, artEventLog *ArtEventLog, heartbeat time.Duration) error {
+	h.artEventDB = artEventDB
+	h.log = artEventLog
+	h.heartbeat = heartbeat
+	return nil
+}
+
+// The id of the last event a client got, from the Last-Event-ID header EventSource reconnects with or from the
+// last_event_id query parameter, and whether it gave one.
+func lastEventId(r *http.Request) (uint, bool, error) {
+	s := r.Header.Get("Last-Event-ID")
+	if s == "" {
+		s = r.URL.Query().Get("last_event_id")
+	}
+	if s == "" {
+		return 0, false, nil
+	}
+
+	if id, err := strconv.ParseUint(s, 10, 32); err != nil {
+		return 0, false, errors.New("the last event id must be a positive integer")
+	} else {
+		return uint(id), true, nil
+	}
+}
+
+// Streams the events logged after the last event the client got, then the new ones as they are logged.
+// Clients that don't give a last event id only get new events. Clients whose last event was pruned, or that
+// doesn't exist, get a reset event instead and should reload the catalog.
+func (h EventsHandler) GetEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	after, resume, err := lastEventId(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	first, last, err := h.artEventDB.GetArtEventBounds(ctx)
+	if err != nil {
+		modelError(w, err)
+		return
+	}
+	reset := resume && (after > last || after+1 < first)
+	if !resume || reset {
+		after = last
+	}
+
+	clearReadDeadline(r)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// keeps proxies such as nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	extendWriteDeadline(r)
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry)
+	if reset {
+		fmt.Fprintf(w, "id: %d\nevent: reset\ndata: {}\n\n", after)
+	}
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		logged := h.log.logged()
+		artEvents, err := h.artEventDB.GetArtEventsAfter(ctx, after, streamBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("events: %s", err)
+			}
+			return
+		}
+
+		extendWriteDeadline(r)
+		for _, artEvent := range artEvents {
+			data, err := json.Marshal(artEvent)
+			if err != nil {
+				log.Printf("events: #%d: %s", artEvent.Id, err)
+				return
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", artEvent.Id, artEvent.Type, data); err != nil {
+				return
+			}
+			after = artEvent.Id
+		}
+		flusher.Flush()
+
+		if len(artEvents) == streamBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-logged:
+		case <-ticker.C:
+			extendWriteDeadline(r)
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// Registers the routes served by the handler on router.
+func (h EventsHandler) Routes(router *mux.Router) {
+	router.HandleFunc("/events", h.GetEvents).Methods(http.MethodGet)
+	router.HandleFunc("/events/", h.GetEvents).Methods(http.MethodGet)
+}
+
+func (h EventsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	router := mux.NewRouter()
+	router.Use(recordRoute)
+	h.Routes(router)
+	router.ServeHTTP(w, r)
+}
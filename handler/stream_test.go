@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/dto"
+)
+
+// An event as read from a stream of /events.
+type streamEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+// Opens a stream of /events with the given Last-Event-ID, if any, and sends the events it gets on the returned channel.
+func openStream(t *testing.T, url string, lastEventId string) (<-chan streamEvent, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	CheckError(t, err)
+	if lastEventId != "" {
+		req.Header.Set("Last-Event-ID", lastEventId)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	CheckError(t, err)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("%d is not equal to %d", resp.StatusCode, http.StatusOK)
+	} else if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("unexpected content type %s", contentType)
+	}
+
+	received := make(chan streamEvent, 100)
+	go func() {
+		defer resp.Body.Close()
+		defer close(received)
+
+		var event streamEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line == "" {
+				if event.event != "" {
+					received <- event
+				}
+				event = streamEvent{}
+			} else if field := strings.SplitN(line, ": ", 2); len(field) == 2 {
+				switch field[0] {
+				case "id":
+					event.id = field[1]
+				case "event":
+					event.event = field[1]
+				case "data":
+					event.data = field[1]
+				}
+			}
+		}
+	}()
+	return received, cancel
+}
+
+// Waits for the next event of a stream.
+func nextEvent(t *testing.T, received <-chan streamEvent) (streamEvent, dto.ArtEventDto) {
+	select {
+	case event, ok := <-received:
+		if !ok {
+			t.Fatal("the stream ended")
+		}
+		var artEvent dto.ArtEventDto
+		if event.event != "reset" {
+			CheckError(t, json.Unmarshal([]byte(event.data), &artEvent))
+		}
+		return event, artEvent
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event was streamed")
+	}
+	return streamEvent{}, dto.ArtEventDto{}
+}
+
+func TestEvents(t *testing.T) {
+	// streams outlive the timeouts of the server
+	server := newTestServer(t, testServerOptions{
+		Gallery:       GalleryHandler{RequestTimeout: 200 * time.Millisecond, StreamHeartbeat: 50 * time.Millisecond},
+		Unlimited:     []string{"events", "arts"},
+		ServerTimeout: 200 * time.Millisecond,
+	})
+	repos := server.Repos
+
+	_, err := repos.Accounts.CreateAccount(context.Background(), dto.AccountDto{Username: "artist", Password: "password"})
+	CheckError(t, err)
+
+	live, cancel := openStream(t, server.URL+"/events", "")
+	defer cancel()
+
+	var art dto.ArtDto
+	var created streamEvent
+	t.Run("Live", func(t *testing.T) {
+		if status := SendAs(t, http.MethodPost, server.URL+"/arts", "artist", dto.ArtDto{Title: "title", Quantity: 1}, &art); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		}
+		var artEvent dto.ArtEventDto
+		if created, artEvent = nextEvent(t, live); created.event != "art.created" || artEvent.Art.Id != art.Id || created.id != fmt.Sprint(artEvent.Id) {
+			t.Fatalf("unexpected event %v", created)
+		}
+
+		// a change of quantity is streamed along with the update
+		url := fmt.Sprintf("%s/arts/%d", server.URL, art.Id)
+		if status := SendAs(t, http.MethodPut, url, "artist", dto.ArtDto{Title: "title", Quantity: 3}, nil); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		}
+		if event, _ := nextEvent(t, live); event.event != "art.updated" {
+			t.Fatalf("unexpected event %v", event)
+		} else if event, artEvent := nextEvent(t, live); event.event != dto.ArtQuantityChanged || artEvent.Art.Quantity != 3 || *artEvent.PreviousQuantity != 1 {
+			t.Fatalf("unexpected event %v", event)
+		}
+
+		if status := SendAs(t, http.MethodPut, url, "artist", dto.ArtDto{Title: "renamed", Quantity: 3}, nil); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		}
+		if event, artEvent := nextEvent(t, live); event.event != "art.updated" || artEvent.Art.Title != "renamed" {
+			t.Fatalf("unexpected event %v", event)
+		}
+
+		batch := dto.BatchDto{Operations: []dto.BatchOperationDto{{Op: "update", Id: art.Id, Art: &dto.ArtDto{Title: "renamed", Quantity: 5}}}}
+		if status := SendAs(t, http.MethodPost, server.URL+"/arts/batch", "artist", batch, nil); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		}
+		if event, _ := nextEvent(t, live); event.event != "art.updated" {
+			t.Fatalf("unexpected event %v", event)
+		} else if event, artEvent := nextEvent(t, live); event.event != dto.ArtQuantityChanged || *artEvent.PreviousQuantity != 3 {
+			t.Fatalf("unexpected event %v", event)
+		}
+
+		// the changes of a batch that is rolled back are never logged, so the deletion is the next event
+		batch = dto.BatchDto{Operations: []dto.BatchOperationDto{{Op: "create", Art: &dto.ArtDto{Title: "title", Quantity: 1}}, {Op: "delete", Id: 1000}}}
+		if status := SendAs(t, http.MethodPost, server.URL+"/arts/batch", "artist", batch, nil); status != http.StatusNotFound {
+			t.Fatalf("%d is not equal to %d", status, http.StatusNotFound)
+		}
+
+		time.Sleep(300 * time.Millisecond)
+		if status := SendAs(t, http.MethodDelete, url, "artist", nil, nil); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		}
+		if event, _ := nextEvent(t, live); event.event != "art.deleted" {
+			t.Fatalf("unexpected event %v", event)
+		}
+	})
+
+	t.Run("Resume", func(t *testing.T) {
+		resumed, cancel := openStream(t, server.URL+"/events", created.id)
+		defer cancel()
+		for _, expected := range []string{"art.updated", dto.ArtQuantityChanged, "art.updated", "art.updated", dto.ArtQuantityChanged, "art.deleted"} {
+			if event, _ := nextEvent(t, resumed); event.event != expected {
+				t.Fatalf("%s is not equal to %s", event.event, expected)
+			}
+		}
+
+		resumed, cancel = openStream(t, server.URL+"/events?last_event_id=6", "")
+		defer cancel()
+		if event, _ := nextEvent(t, resumed); event.event != "art.deleted" || event.id != "7" {
+			t.Fatalf("unexpected event %v", event)
+		}
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		// the client can't have gotten an event that doesn't exist yet
+		reset, cancel := openStream(t, server.URL+"/events", "100")
+		defer cancel()
+		if event, _ := nextEvent(t, reset); event.event != "reset" || event.id != "7" {
+			t.Fatalf("unexpected event %v", event)
+		}
+
+		if status := SendAs(t, http.MethodPost, server.URL+"/arts", "artist", dto.ArtDto{Title: "title", Quantity: 1}, nil); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		}
+		if event, _ := nextEvent(t, reset); event.event != "art.created" || event.id != "8" {
+			t.Fatalf("unexpected event %v", event)
+		}
+	})
+
+	if status := SendAs(t, http.MethodGet, server.URL+"/events?last_event_id=last", "", nil, nil); status != http.StatusBadRequest {
+		t.Fatalf("%d is not equal to %d", status, http.StatusBadRequest)
+	}
+}
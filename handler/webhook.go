@@ -0,0 +1,265 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+	"github.com/nafiz1001/gallery-go/webhook"
+)
+
+const (
+	// Page of deliveries a client gets without asking for a limit.
+	defaultDeliveriesLimit = 20
+	// Largest page of deliveries a client can ask for.
+	maxDeliveriesLimit = 100
+)
+
+// Lets administrators subscribe URLs to the events of the gallery and follow their deliveries.
+type WebhooksHandler struct {
+	webhookDB  model.WebhookRepository
+	repos      model.Repositories
+	dispatcher *webhook.Dispatcher
+	auth       Authenticator
+}
+
+func (h *WebhooksHandler) Init(repos model.Repositories, dispatcher *webhook.Dispatcher, auth Authenticator) error {
+	h.webhookDB = repos.Webhooks
+	h.repos = repos
+	h.dispatcher = dispatcher
+	h.auth = auth
+
+	return nil
+}
+
+// The webhook without its secret.
+func redactWebhook(webhook dto.WebhookDto) dto.WebhookDto {
+	webhook.Secret = ""
+	return webhook
+}
+
+// Creates a webhook and responds with it, secret included. A random secret is made up if none is given.
+func (h WebhooksHandler) PostWebhook(w http.ResponseWriter, r *http.Request, account dto.AccountDto) {
+	w.Header().Set("Content-Type", "application/json")
+
+	webhook, err := dto.DecodeWebhook(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if webhook.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		webhook.Secret = hex.EncodeToString(secret)
+	}
+
+	err = h.repos.Transaction(r.Context(), func(tx model.Repositories) error {
+		var err error
+		if webhook, err = tx.Webhooks.CreateWebhook(r.Context(), *webhook); err != nil {
+			return err
+		}
+		return recordAudit(tx.Audit, r, account.Id, "create", "webhook", webhook.Id, nil, redactWebhook(*webhook))
+	})
+	if err != nil {
+		modelError(w, err)
+	} else {
+		json.NewEncoder(w).Encode(webhook)
+	}
+}
+
+func (h WebhooksHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if webhooks, err := h.webhookDB.GetWebhooks(r.Context()); err != nil {
+		modelError(w, err)
+	} else {
+		for i := range webhooks {
+			webhooks[i] = redactWebhook(webhooks[i])
+		}
+		json.NewEncoder(w).Encode(webhooks)
+	}
+}
+
+func (h WebhooksHandler) GetWebhook(w http.ResponseWriter, r *http.Request, id uint) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if webhook, err := h.webhookDB.GetWebhook(r.Context(), id); err != nil {
+		modelError(w, err)
+	} else {
+		json.NewEncoder(w).Encode(redactWebhook(*webhook))
+	}
+}
+
+// Replaces the URL, events and state of a webhook. The secret is kept unless a new one is given.
+func (h WebhooksHandler) PutWebhook(w http.ResponseWriter, r *http.Request, id uint, account dto.AccountDto) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if webhook, err := dto.DecodeWebhook(r.Body); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	} else if before, err := h.webhookDB.GetWebhook(r.Context(), id); err != nil {
+		modelError(w, err)
+	} else {
+		webhook.Id = id
+		var redacted dto.WebhookDto
+		err := h.repos.Transaction(r.Context(), func(tx model.Repositories) error {
+			if webhook, err := tx.Webhooks.UpdateWebhook(r.Context(), *webhook); err != nil {
+				return err
+			} else {
+				redacted = redactWebhook(*webhook)
+				return recordAudit(tx.Audit, r, account.Id, "update", "webhook", id, redactWebhook(*before), redacted)
+			}
+		})
+		if err != nil {
+			modelError(w, err)
+		} else {
+			json.NewEncoder(w).Encode(redacted)
+		}
+	}
+}
+
+// Deletes a webhook along with its deliveries, pending ones included.
+func (h WebhooksHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request, id uint, account dto.AccountDto) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var redacted dto.WebhookDto
+	err := h.repos.Transaction(r.Context(), func(tx model.Repositories) error {
+		if webhook, err := tx.Webhooks.DeleteWebhook(r.Context(), id); err != nil {
+			return err
+		} else {
+			redacted = redactWebhook(*webhook)
+			return recordAudit(tx.Audit, r, account.Id, "delete", "webhook", id, redacted, nil)
+		}
+	})
+	if err != nil {
+		modelError(w, err)
+	} else {
+		json.NewEncoder(w).Encode(redacted)
+	}
+}
+
+// Lists the deliveries of a webhook, newest first, along with the outcome of their last attempt.
+// The before query parameter pages through older ones.
+func (h WebhooksHandler) GetDeliveries(w http.ResponseWriter, r *http.Request, id uint) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var before uint64
+	if s := r.URL.Query().Get("before"); s != "" {
+		var err error
+		if before, err = strconv.ParseUint(s, 10, 32); err != nil {
+			http.Error(w, "before must be a positive integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := uint64(defaultDeliveriesLimit)
+	if s := r.URL.Query().Get("limit"); s != "" {
+		var err error
+		if limit, err = strconv.ParseUint(s, 10, 32); err != nil || limit == 0 || limit > maxDeliveriesLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxDeliveriesLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if deliveries, err := h.webhookDB.GetDeliveries(r.Context(), id, uint(before), int(limit)); err != nil {
+		modelError(w, err)
+	} else {
+		json.NewEncoder(w).Encode(deliveries)
+	}
+}
+
+// Queues the payload of a delivery again, whatever became of it, and responds with the new delivery.
+func (h WebhooksHandler) PostReplay(w http.ResponseWriter, r *http.Request, id uint, deliveryId uint, account dto.AccountDto) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var delivery *dto.WebhookDeliveryDto
+	err := h.repos.Transaction(r.Context(), func(tx model.Repositories) error {
+		var err error
+		if delivery, err = tx.Webhooks.ReplayDelivery(r.Context(), id, deliveryId); err != nil {
+			return err
+		}
+		return recordAudit(tx.Audit, r, account.Id, "replay", "webhook", id, nil, delivery)
+	})
+	if err != nil {
+		modelError(w, err)
+	} else {
+		h.dispatcher.Wake()
+		json.NewEncoder(w).Encode(delivery)
+	}
+}
+
+func (h WebhooksHandler) WebhooksFuncHandler(w http.ResponseWriter, r *http.Request) {
+	h.auth.AdminAuth(w, r, func(account dto.AccountDto) {
+		switch r.Method {
+		case http.MethodPost:
+			h.PostWebhook(w, r, account)
+		case http.MethodGet:
+			h.GetWebhooks(w, r)
+		}
+	})
+}
+
+func (h WebhooksHandler) WebhookByIdFuncHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 10, 32)
+
+	h.auth.AdminAuth(w, r, func(account dto.AccountDto) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetWebhook(w, r, uint(id))
+		case http.MethodPut:
+			h.PutWebhook(w, r, uint(id), account)
+		case http.MethodDelete:
+			h.DeleteWebhook(w, r, uint(id), account)
+		}
+	})
+}
+
+func (h WebhooksHandler) DeliveriesFuncHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 10, 32)
+
+	h.auth.AdminAuth(w, r, func(_ dto.AccountDto) {
+		h.GetDeliveries(w, r, uint(id))
+	})
+}
+
+func (h WebhooksHandler) ReplayFuncHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 10, 32)
+	deliveryId, _ := strconv.ParseInt(vars["delivery"], 10, 32)
+
+	h.auth.AdminAuth(w, r, func(account dto.AccountDto) {
+		h.PostReplay(w, r, uint(id), uint(deliveryId), account)
+	})
+}
+
+// Registers the routes served by the handler on router.
+func (h WebhooksHandler) Routes(router *mux.Router) {
+	router.HandleFunc("/webhooks", h.WebhooksFuncHandler).Methods(http.MethodPost, http.MethodGet)
+	router.HandleFunc("/webhooks/", h.WebhooksFuncHandler).Methods(http.MethodPost, http.MethodGet)
+
+	router.HandleFunc("/webhooks/{id:[0-9]+}", h.WebhookByIdFuncHandler).Methods(http.MethodGet, http.MethodPut, http.MethodDelete)
+	router.HandleFunc("/webhooks/{id:[0-9]+}/", h.WebhookByIdFuncHandler).Methods(http.MethodGet, http.MethodPut, http.MethodDelete)
+
+	router.HandleFunc("/webhooks/{id:[0-9]+}/deliveries", h.DeliveriesFuncHandler).Methods(http.MethodGet)
+	router.HandleFunc("/webhooks/{id:[0-9]+}/deliveries/", h.DeliveriesFuncHandler).Methods(http.MethodGet)
+
+	router.HandleFunc("/webhooks/{id:[0-9]+}/deliveries/{delivery:[0-9]+}/replay", h.ReplayFuncHandler).Methods(http.MethodPost)
+	router.HandleFunc("/webhooks/{id:[0-9]+}/deliveries/{delivery:[0-9]+}/replay/", h.ReplayFuncHandler).Methods(http.MethodPost)
+}
+
+func (h WebhooksHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	router := mux.NewRouter()
+	router.Use(recordRoute)
+	h.Routes(router)
+	router.ServeHTTP(w, r)
+}
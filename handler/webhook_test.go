@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/webhook"
+)
+
+func TestWebhooks(t *testing.T) {
+	// more requests are made to /webhooks than its rate limit allows
+	server := newTestServer(t, testServerOptions{Unlimited: []string{"webhooks", "accounts"}})
+	repos := server.Repos
+
+	var mu sync.Mutex
+	received := []webhook.Payload{}
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var payload webhook.Payload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || r.Header.Get(webhook.EventHeader) != payload.Type {
+			w.WriteHeader(http.StatusBadRequest)
+		} else {
+			received = append(received, payload)
+		}
+	}))
+	defer receiver.Close()
+
+	// delivers the queued events and returns those the receiver got
+	deliver := func(t *testing.T) []webhook.Payload {
+		if _, err := server.Gallery.Webhooks.DeliverDue(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		payloads := received
+		received = []webhook.Payload{}
+		return payloads
+	}
+
+	ctx := context.Background()
+	for _, username := range []string{"admin", "artist"} {
+		account, err := repos.Accounts.CreateAccount(ctx, dto.AccountDto{Username: username, Password: "password"})
+		CheckError(t, err)
+		if username == "admin" {
+			_, err = repos.Accounts.SetAdmin(ctx, account.Id, true)
+			CheckError(t, err)
+		}
+	}
+
+	var hook dto.WebhookDto
+	t.Run("Subscribe", func(t *testing.T) {
+		subscription := dto.WebhookDto{URL: receiver.URL, Events: []string{"art.created", "art.deleted", "account.created"}}
+		if status := SendAs(t, http.MethodPost, server.URL+"/webhooks", "admin", subscription, &hook); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if len(hook.Secret) != 64 || hook.URL != receiver.URL {
+			t.Fatalf("unexpected webhook %v", hook)
+		}
+
+		var webhooks []dto.WebhookDto
+		if status := SendAs(t, http.MethodGet, server.URL+"/webhooks", "admin", nil, &webhooks); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if len(webhooks) != 1 || webhooks[0].Secret != "" {
+			t.Fatalf("unexpected webhooks %v", webhooks)
+		}
+
+		entries, err := repos.Audit.GetEntries(ctx, "webhook", hook.Id)
+		CheckError(t, err)
+		if len(entries) != 1 || strings.Contains(string(entries[0].After), hook.Secret) {
+			t.Fatalf("unexpected audit entries %v", entries)
+		}
+	})
+
+	t.Run("Events", func(t *testing.T) {
+		var art dto.ArtDto
+		if status := SendAs(t, http.MethodPost, server.URL+"/arts", "artist", dto.ArtDto{Title: "title", Quantity: 1}, &art); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if payloads := deliver(t); len(payloads) != 1 || payloads[0].Type != "art.created" || payloads[0].Art.Id != art.Id || payloads[0].ActorId != 2 {
+			t.Fatalf("unexpected payloads %v", payloads)
+		}
+
+		// updates aren't subscribed to
+		if status := SendAs(t, http.MethodPut, fmt.Sprintf("%s/arts/%d", server.URL, art.Id), "artist", dto.ArtDto{Title: "renamed", Quantity: 1}, nil); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if payloads := deliver(t); len(payloads) != 0 {
+			t.Fatalf("unexpected payloads %v", payloads)
+		}
+
+		// a batch publishes its events once it is committed, and none if it is rolled back
+		batch := dto.BatchDto{Operations: []dto.BatchOperationDto{{Op: "create", Art: &dto.ArtDto{Title: "new", Quantity: 1}}, {Op: "delete", Id: art.Id}}}
+		if status := SendAs(t, http.MethodPost, server.URL+"/arts/batch", "artist", batch, nil); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if payloads := deliver(t); len(payloads) != 2 || payloads[0].Type != "art.created" || payloads[1].Type != "art.deleted" || payloads[1].Art.Id != art.Id {
+			t.Fatalf("unexpected payloads %v", payloads)
+		}
+		batch = dto.BatchDto{Operations: []dto.BatchOperationDto{{Op: "create", Art: &dto.ArtDto{Title: "new", Quantity: 1}}, {Op: "delete", Id: 1000}}}
+		if status := SendAs(t, http.MethodPost, server.URL+"/arts/batch", "artist", batch, nil); status != http.StatusNotFound {
+			t.Fatalf("%d is not equal to %d", status, http.StatusNotFound)
+		} else if payloads := deliver(t); len(payloads) != 0 {
+			t.Fatalf("unexpected payloads %v", payloads)
+		}
+
+		if status := SendAs(t, http.MethodPost, server.URL+"/accounts", "", dto.AccountDto{Username: "newcomer", Password: "password"}, nil); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if payloads := deliver(t); len(payloads) != 1 || payloads[0].Account.Username != "newcomer" || payloads[0].Account.Password != "" {
+			t.Fatalf("unexpected payloads %v", payloads)
+		}
+	})
+
+	t.Run("Deliveries", func(t *testing.T) {
+		var deliveries []dto.WebhookDeliveryDto
+		if status := SendAs(t, http.MethodGet, fmt.Sprintf("%s/webhooks/%d/deliveries?limit=2", server.URL, hook.Id), "admin", nil, &deliveries); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if len(deliveries) != 2 || deliveries[0].Event != "account.created" || deliveries[0].Status != dto.DeliveryDelivered || deliveries[0].ResponseStatus != http.StatusOK {
+			t.Fatalf("unexpected deliveries %v", deliveries)
+		}
+
+		var replay dto.WebhookDeliveryDto
+		if status := SendAs(t, http.MethodPost, fmt.Sprintf("%s/webhooks/%d/deliveries/%d/replay", server.URL, hook.Id, deliveries[1].Id), "admin", nil, &replay); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if replay.ReplayOf != deliveries[1].Id || replay.Status != dto.DeliveryPending {
+			t.Fatalf("unexpected delivery %v", replay)
+		} else if payloads := deliver(t); len(payloads) != 1 || payloads[0].Type != "art.deleted" {
+			t.Fatalf("unexpected payloads %v", payloads)
+		}
+	})
+
+	t.Run("Update and delete", func(t *testing.T) {
+		var updated dto.WebhookDto
+		disabled := dto.WebhookDto{URL: receiver.URL, Events: hook.Events, Disabled: true}
+		if status := SendAs(t, http.MethodPut, fmt.Sprintf("%s/webhooks/%d", server.URL, hook.Id), "admin", disabled, &updated); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if !updated.Disabled || updated.Secret != "" {
+			t.Fatalf("unexpected webhook %v", updated)
+		} else if stored, err := repos.Webhooks.GetWebhook(ctx, hook.Id); err != nil || stored.Secret != hook.Secret {
+			t.Fatalf("the secret changed: %v %v", stored, err)
+		}
+
+		// disabled webhooks get nothing
+		if status := SendAs(t, http.MethodPost, server.URL+"/arts", "artist", dto.ArtDto{Title: "title", Quantity: 1}, nil); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if payloads := deliver(t); len(payloads) != 0 {
+			t.Fatalf("unexpected payloads %v", payloads)
+		}
+
+		if status := SendAs(t, http.MethodDelete, fmt.Sprintf("%s/webhooks/%d", server.URL, hook.Id), "admin", nil, nil); status != http.StatusOK {
+			t.Fatalf("%d is not equal to %d", status, http.StatusOK)
+		} else if status := SendAs(t, http.MethodGet, fmt.Sprintf("%s/webhooks/%d", server.URL, hook.Id), "admin", nil, nil); status != http.StatusNotFound {
+			t.Fatalf("%d is not equal to %d", status, http.StatusNotFound)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		valid := dto.WebhookDto{URL: receiver.URL, Events: []string{"art.created"}}
+		for _, test := range []struct {
+			method   string
+			url      string
+			username string
+			body     interface{}
+			status   int
+		}{
+			{http.MethodGet, server.URL + "/webhooks", "", nil, http.StatusUnauthorized},
+			{http.MethodGet, server.URL + "/webhooks", "artist", nil, http.StatusForbidden},
+			{http.MethodPost, server.URL + "/webhooks", "artist", valid, http.StatusForbidden},
+			{http.MethodPost, server.URL + "/webhooks", "admin", dto.WebhookDto{URL: "ftp://localhost", Events: []string{"art.created"}}, http.StatusUnprocessableEntity},
+			{http.MethodPost, server.URL + "/webhooks", "admin", dto.WebhookDto{URL: receiver.URL, Events: []string{"art.sold"}}, http.StatusUnprocessableEntity},
+			{http.MethodPut, server.URL + "/webhooks/100", "admin", valid, http.StatusNotFound},
+			{http.MethodGet, server.URL + "/webhooks/100/deliveries", "admin", nil, http.StatusNotFound},
+			{http.MethodGet, server.URL + "/webhooks/100/deliveries?limit=1000", "admin", nil, http.StatusBadRequest},
+			{http.MethodPost, server.URL + "/webhooks/100/deliveries/1/replay", "admin", nil, http.StatusNotFound},
+		} {
+			if status := SendAs(t, test.method, test.url, test.username, test.body, nil); status != test.status {
+				t.Fatalf("%s %s as '%s': %d is not equal to %d", test.method, test.url, test.username, status, test.status)
+			}
+		}
+	})
+}
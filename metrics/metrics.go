@@ -0,0 +1,260 @@
+// Package metrics implements the small subset of Prometheus instrumentation the gallery needs:
+// counters, histograms and gauges with labels, exposed in the Prometheus text format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Upper bounds in seconds suitable for request and query latencies.
+var DefaultBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type collector interface {
+	write(w *bufio.Writer) error
+}
+
+// A set of metrics exposed together.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// Writes every metric of the registry in the Prometheus text format.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	collectors := append([]collector{}, r.collectors...)
+	r.mu.Unlock()
+
+	buf := bufio.NewWriter(w)
+	for _, c := range collectors {
+		if err := c.write(buf); err != nil {
+			return err
+		}
+	}
+	return buf.Flush()
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := r.Write(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// A monotonically increasing value per combination of label values.
+// The methods of a nil Counter do nothing, so optional instrumentation needs no checks.
+type Counter struct {
+	Name   string
+	Help   string
+	Labels []string
+
+	mu     sync.Mutex
+	series map[string]*counterSeries
+}
+
+type counterSeries struct {
+	labels []string
+	value  float64
+}
+
+func (c *Counter) Register(r *Registry) {
+	c.series = map[string]*counterSeries{}
+	r.register(c)
+}
+
+func (c *Counter) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+func (c *Counter) Add(v float64, labels ...string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := strings.Join(labels, "\xff")
+	s, ok := c.series[key]
+	if !ok {
+		s = &counterSeries{labels: labels}
+		c.series[key] = s
+	}
+	s.value += v
+}
+
+func (c *Counter) write(w *bufio.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	writeHeader(w, c.Name, c.Help, "counter")
+	for _, key := range sortedKeys(c.series) {
+		s := c.series[key]
+		writeSample(w, c.Name, c.Labels, s.labels, "", "", s.value)
+	}
+	return nil
+}
+
+// Observations counted into cumulative buckets per combination of label values.
+// The methods of a nil Histogram do nothing.
+type Histogram struct {
+	Name    string
+	Help    string
+	Labels  []string
+	Buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labels []string
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *Histogram) Register(r *Registry) {
+	if h.Buckets == nil {
+		h.Buckets = DefaultBuckets
+	}
+	sort.Float64s(h.Buckets)
+	h.series = map[string]*histogramSeries{}
+	r.register(h)
+}
+
+func (h *Histogram) Observe(v float64, labels ...string) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := strings.Join(labels, "\xff")
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{labels: labels, counts: make([]uint64, len(h.Buckets))}
+		h.series[key] = s
+	}
+	for i, bound := range h.Buckets {
+		if v <= bound {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *Histogram) write(w *bufio.Writer) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	writeHeader(w, h.Name, h.Help, "histogram")
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		for i, bound := range h.Buckets {
+			writeSample(w, h.Name+"_bucket", h.Labels, s.labels, "le", formatFloat(bound), float64(s.counts[i]))
+		}
+		writeSample(w, h.Name+"_bucket", h.Labels, s.labels, "le", "+Inf", float64(s.count))
+		writeSample(w, h.Name+"_sum", h.Labels, s.labels, "", "", s.sum)
+		writeSample(w, h.Name+"_count", h.Labels, s.labels, "", "", float64(s.count))
+	}
+	return nil
+}
+
+// A gauge whose value is computed by Value every time the registry is scraped.
+// The gauge is left out of the scrape if Value fails.
+type GaugeFunc struct {
+	Name  string
+	Help  string
+	Value func() (float64, error)
+}
+
+func (g *GaugeFunc) Register(r *Registry) {
+	r.register(g)
+}
+
+func (g *GaugeFunc) write(w *bufio.Writer) error {
+	v, err := g.Value()
+	if err != nil {
+		return nil
+	}
+
+	writeHeader(w, g.Name, g.Help, "gauge")
+	writeSample(w, g.Name, nil, nil, "", "", v)
+	return nil
+}
+
+func writeHeader(w *bufio.Writer, name string, help string, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+func writeSample(w *bufio.Writer, name string, labelNames []string, labelValues []string, extraName string, extraValue string, v float64) {
+	w.WriteString(name)
+
+	pairs := []string{}
+	for i, label := range labelNames {
+		value := ""
+		if i < len(labelValues) {
+			value = labelValues[i]
+		}
+		pairs = append(pairs, label+`="`+escapeLabel(value)+`"`)
+	}
+	if extraName != "" {
+		pairs = append(pairs, extraName+`="`+extraValue+`"`)
+	}
+	if len(pairs) > 0 {
+		w.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+
+	w.WriteString(" " + formatFloat(v) + "\n")
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+func sortedKeys(m interface{}) []string {
+	keys := []string{}
+	switch series := m.(type) {
+	case map[string]*counterSeries:
+		for key := range series {
+			keys = append(keys, key)
+		}
+	case map[string]*histogramSeries:
+		for key := range series {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
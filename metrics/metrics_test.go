@@ -0,0 +1,59 @@
+package metrics_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/nafiz1001/gallery-go/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := metrics.Registry{}
+
+	counter := &metrics.Counter{Name: "requests_total", Help: "Requests.", Labels: []string{"route", "status"}}
+	counter.Register(&registry)
+	counter.Inc("/arts", "200")
+	counter.Inc("/arts", "200")
+	counter.Inc(`/a"b`, "500")
+
+	histogram := &metrics.Histogram{Name: "latency_seconds", Help: "Latency.", Labels: []string{"route"}, Buckets: []float64{0.1, 1}}
+	histogram.Register(&registry)
+	histogram.Observe(0.05, "/arts")
+	histogram.Observe(0.5, "/arts")
+
+	gauge := &metrics.GaugeFunc{Name: "arts", Help: "Arts.", Value: func() (float64, error) { return 3, nil }}
+	gauge.Register(&registry)
+
+	broken := &metrics.GaugeFunc{Name: "broken", Help: "Broken.", Value: func() (float64, error) { return 0, errors.New("broken") }}
+	broken.Register(&registry)
+
+	var out bytes.Buffer
+	require.NoError(t, registry.Write(&out))
+	assert.Equal(t, `# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{route="/a\"b",status="500"} 1
+requests_total{route="/arts",status="200"} 2
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/arts",le="0.1"} 1
+latency_seconds_bucket{route="/arts",le="1"} 2
+latency_seconds_bucket{route="/arts",le="+Inf"} 2
+latency_seconds_sum{route="/arts"} 0.55
+latency_seconds_count{route="/arts"} 2
+# HELP arts Arts.
+# TYPE arts gauge
+arts 3
+`, out.String())
+}
+
+func TestNilMetrics(t *testing.T) {
+	var counter *metrics.Counter
+	var histogram *metrics.Histogram
+
+	// optional metrics can be used without being set up
+	counter.Inc("label")
+	histogram.Observe(1, "label")
+}
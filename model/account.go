@@ -1,7 +1,7 @@
 package model
 
 import (
-	"fmt"
+	"context"
 
 	"github.com/nafiz1001/gallery-go/dto"
 	"gorm.io/gorm"
@@ -15,7 +15,8 @@ type Account struct {
 	gorm.Model
 	Username string
 	Password string
-	Arts     []Art `gorm:"foreignKey:AccountID"`
+	Admin    bool
+	Arts     []Art `gorm:"foreignKey:AccountID" json:"-"`
 }
 
 // Creates Account object from AccountDto.
@@ -25,6 +26,7 @@ func DtoToAccount(data dto.AccountDto) Account {
 	model.ID = uint(data.Id)
 	model.Username = data.Username
 	model.Password = data.Password
+	model.Admin = data.Admin
 	model.Arts = []Art{}
 
 	return model
@@ -36,34 +38,34 @@ func (model *Account) ToDto() *dto.AccountDto {
 		Id:       uint(model.ID),
 		Username: model.Username,
 		Password: model.Password,
+		Admin:    model.Admin,
 	}
 }
 
-// Creates account table in the database.
+// The tables are created by the migrations, see Migrator.
 func (db *AccountDB) Init(database *DB) error {
 	db.db = database.GormDB
-	return db.db.AutoMigrate(&Account{})
+	return nil
 }
 
 // Creates new account if there is no existing account with identical username.
-func (db *AccountDB) CreateAccount(account dto.AccountDto) (*dto.AccountDto, error) {
+// Uniqueness is enforced by the database, a duplicate fails with ErrUsernameTaken.
+func (db *AccountDB) CreateAccount(ctx context.Context, account dto.AccountDto) (*dto.AccountDto, error) {
 	account.Id = 0
-	if _, err := db.GetAccountByUsername(account.Username); err == nil {
-		return nil, fmt.Errorf("username '%s' already exists", account.Username)
+	account.Admin = false
+	model := DtoToAccount(account)
+
+	if err := db.db.WithContext(ctx).Create(&model).Error; err != nil {
+		return nil, translateError(err)
 	} else {
-		model := DtoToAccount(account)
-		if err := db.db.Create(&model).Error; err != nil {
-			return nil, err
-		} else {
-			return model.ToDto(), nil
-		}
+		return model.ToDto(), nil
 	}
 }
 
 // Gets account by id if it exists.
-func (db *AccountDB) GetAccountById(id uint) (*dto.AccountDto, error) {
+func (db *AccountDB) GetAccountById(ctx context.Context, id uint) (*dto.AccountDto, error) {
 	var model Account
-	if err := db.db.First(&model, id).Error; err != nil {
+	if err := db.db.WithContext(ctx).First(&model, id).Error; err != nil {
 		return nil, err
 	} else {
 		return model.ToDto(), err
@@ -71,11 +73,36 @@ func (db *AccountDB) GetAccountById(id uint) (*dto.AccountDto, error) {
 }
 
 // Gets account by username if it exists
-func (db *AccountDB) GetAccountByUsername(username string) (*dto.AccountDto, error) {
+func (db *AccountDB) GetAccountByUsername(ctx context.Context, username string) (*dto.AccountDto, error) {
 	var model Account
-	if err := db.db.First(&model, "username = ?", username).Error; err != nil {
+	if err := db.db.WithContext(ctx).First(&model, "username = ?", username).Error; err != nil {
 		return nil, err
 	} else {
 		return model.ToDto(), err
 	}
 }
+
+// Grants or revokes administrator privileges of an existing account.
+func (db *AccountDB) SetAdmin(ctx context.Context, id uint, admin bool) (*dto.AccountDto, error) {
+	var model Account
+
+	err := db.transaction(ctx, func(tx *AccountDB) error {
+		if err := tx.db.First(&model, id).Error; err != nil {
+			return err
+		} else {
+			return tx.db.Model(&model).Update("admin", admin).Error
+		}
+	})
+	if err != nil {
+		return nil, err
+	} else {
+		return model.ToDto(), nil
+	}
+}
+
+// Counts the accounts that are not deleted.
+func (db *AccountDB) CountAccounts(ctx context.Context) (int64, error) {
+	var count int64
+	err := db.db.WithContext(ctx).Model(&Account{}).Count(&count).Error
+	return count, err
+}
@@ -1,6 +1,7 @@
 package model_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -14,7 +15,7 @@ import (
 )
 
 func AccountDBInit(t *testing.T) (model.AccountDB, *gorm.DB) {
-	gormDB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{
+	gormDB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared&_foreign_keys=true"), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
 	})
 	if err != nil {
@@ -22,6 +23,7 @@ func AccountDBInit(t *testing.T) (model.AccountDB, *gorm.DB) {
 	}
 
 	db := model.DB{GormDB: gormDB}
+	Migrate(t, &db)
 
 	var accountDB model.AccountDB
 	err = accountDB.Init(&db)
@@ -36,7 +38,7 @@ func CreateAccount(t *testing.T, db model.AccountDB, username string, password s
 		Password: password,
 	}
 
-	account, err := db.CreateAccount(dto)
+	account, err := db.CreateAccount(context.Background(), dto)
 	require.NoError(t, err)
 	require.NotNil(t, account)
 
@@ -63,15 +65,15 @@ func TestCreateAccount(t *testing.T) {
 	assert.Equal(t, account1.Username, "username")
 
 	// duplicate username
-	account2, err := db.CreateAccount(dto.AccountDto{
+	account2, err := db.CreateAccount(context.Background(), dto.AccountDto{
 		Username: "username",
 		Password: "password",
 	})
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, model.ErrUsernameTaken)
 	assert.Nil(t, account2)
 
 	// successful second create
-	account2, err = db.CreateAccount(dto.AccountDto{
+	account2, err = db.CreateAccount(context.Background(), dto.AccountDto{
 		Username: "username2",
 		Password: "password2",
 	})
@@ -90,13 +92,13 @@ func TestGetAccountById(t *testing.T) {
 	}()
 
 	// successful get account by id
-	if dto, err := db.GetAccountById(account.Id); assert.NoError(t, err) && assert.NotNil(t, dto) {
+	if dto, err := db.GetAccountById(context.Background(), account.Id); assert.NoError(t, err) && assert.NotNil(t, dto) {
 		assert.Equal(t, dto.Id, account.Id)
 		assert.Equal(t, dto.Username, account.Username)
 	}
 
 	// get account by non-existent id
-	dto, err := db.GetAccountById(420)
+	dto, err := db.GetAccountById(context.Background(), 420)
 	assert.Error(t, err)
 	assert.Nil(t, dto)
 }
@@ -110,14 +112,45 @@ func TestGetAccountByUsername(t *testing.T) {
 	account := CreateAccount(t, db, "username", "password")
 
 	// successful get account by username
-	dto, err := db.GetAccountByUsername(account.Username)
+	dto, err := db.GetAccountByUsername(context.Background(), account.Username)
 	if assert.NoError(t, err) && assert.NotNil(t, dto) {
 		assert.Equal(t, dto.Id, account.Id)
 		assert.Equal(t, dto.Username, dto.Username)
 	}
 
 	// get account by non-existent username
-	dto, err = db.GetAccountByUsername("username2")
+	dto, err = db.GetAccountByUsername(context.Background(), "username2")
+	assert.Error(t, err)
+	assert.Nil(t, dto)
+}
+
+func TestSetAdmin(t *testing.T) {
+	db, gormDB := AccountDBInit(t)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+
+	// accounts can't make themselves administrators on creation
+	account, err := db.CreateAccount(context.Background(), dto.AccountDto{
+		Username: "username",
+		Password: "password",
+		Admin:    true,
+	})
+	if assert.NoError(t, err) && assert.NotNil(t, account) {
+		assert.False(t, account.Admin)
+	}
+
+	// successfully grant administrator privileges
+	if dto, err := db.SetAdmin(context.Background(), account.Id, true); assert.NoError(t, err) && assert.NotNil(t, dto) {
+		assert.True(t, dto.Admin)
+	}
+	if dto, err := db.GetAccountById(context.Background(), account.Id); assert.NoError(t, err) && assert.NotNil(t, dto) {
+		assert.True(t, dto.Admin)
+	}
+
+	// can't grant administrator privileges to non-existent account
+	dto, err := db.SetAdmin(context.Background(), 420, true)
 	assert.Error(t, err)
 	assert.Nil(t, dto)
 }
@@ -1,10 +1,17 @@
 package model
 
 import (
+	"context"
+	"database/sql"
+	"strings"
+
 	"github.com/nafiz1001/gallery-go/dto"
 	"gorm.io/gorm"
 )
 
+// Arts read at a time by ExportArts.
+const exportPageSize = 500
+
 type ArtDB struct {
 	db *gorm.DB
 }
@@ -35,39 +42,58 @@ func (model *Art) ToDto() *dto.ArtDto {
 	}
 }
 
+// The tables are created by the migrations, see Migrator.
 func (db *ArtDB) Init(database *DB) error {
 	db.db = database.GormDB
-	return db.db.AutoMigrate(&Art{})
+	return nil
 }
 
-func (db *ArtDB) CreateArt(art dto.ArtDto) (*dto.ArtDto, error) {
+// Creates an art for an existing account.
+// The steps run in one transaction, so a failure leaves no art behind.
+func (db *ArtDB) CreateArt(ctx context.Context, art dto.ArtDto) (*dto.ArtDto, error) {
 	artModel := DtoToArt(art)
 	accModel := Account{}
 
-	if err := db.db.First(&accModel, art.AuthorId).Error; err != nil {
-		return nil, err
-	} else if err := db.db.Create(&artModel).Error; err != nil {
-		return nil, err
-	} else if err := db.db.Model(&accModel).Association("Arts").Append(&artModel); err != nil {
+	err := db.transaction(ctx, func(tx *ArtDB) error {
+		if err := tx.db.First(&accModel, art.AuthorId).Error; err != nil {
+			return err
+		} else if err := tx.db.Create(&artModel).Error; err != nil {
+			return err
+		} else {
+			return tx.db.Model(&accModel).Association("Arts").Append(&artModel)
+		}
+	})
+	if err != nil {
 		return nil, err
 	} else {
 		return artModel.ToDto(), nil
 	}
 }
 
-func (db *ArtDB) GetArt(id uint) (*dto.ArtDto, error) {
+func (db *ArtDB) GetArt(ctx context.Context, id uint) (*dto.ArtDto, error) {
 	var model Art
-	if err := db.db.First(&model, id).Error; err != nil {
+	if err := db.db.WithContext(ctx).First(&model, id).Error; err != nil {
 		return nil, err
 	} else {
 		return model.ToDto(), err
 	}
 }
 
-func (db *ArtDB) GetArts() ([]dto.ArtDto, error) {
+func (db *ArtDB) GetArts(ctx context.Context) ([]dto.ArtDto, error) {
+	return db.GetArtsAfter(ctx, 0, 0)
+}
+
+// Gets at most limit arts whose id is greater than after, ordered by id.
+// A limit of 0 gets every one of them.
+func (db *ArtDB) GetArtsAfter(ctx context.Context, after uint, limit int) ([]dto.ArtDto, error) {
 	var models []Art
 
-	if err := db.db.Find(&models).Error; err != nil {
+	query := db.db.WithContext(ctx).Where("id > ?", after).Order("id")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	if err := query.Find(&models).Error; err != nil {
 		return []dto.ArtDto{}, err
 	} else {
 		arts := []dto.ArtDto{}
@@ -78,33 +104,156 @@ func (db *ArtDB) GetArts() ([]dto.ArtDto, error) {
 	}
 }
 
-func (db *ArtDB) UpdateArt(art dto.ArtDto) (*dto.ArtDto, error) {
+// Updates an existing art after storing its current state as a revision.
+// The revision and the update are made in one transaction.
+func (db *ArtDB) UpdateArt(ctx context.Context, art dto.ArtDto) (*dto.ArtDto, error) {
 	model := DtoToArt(art)
-	if err := db.db.First(&Account{}, art.AuthorId).Error; err != nil {
-		return nil, err
-	} else if _, err := db.GetArt(model.ID); err != nil {
-		return nil, err
-	} else if err := db.db.Model(&model).Updates(&model).Error; err != nil {
+	var updated Art
+
+	err := db.transaction(ctx, func(tx *ArtDB) error {
+		var current Art
+		if err := tx.db.First(&Account{}, art.AuthorId).Error; err != nil {
+			return err
+		} else if err := tx.db.First(&current, model.ID).Error; err != nil {
+			return err
+		} else if err := tx.snapshotArt(ctx, current); err != nil {
+			return err
+		} else if err := tx.db.Model(&model).Updates(&model).Error; err != nil {
+			return err
+		} else {
+			// zero fields are left as they were
+			return tx.db.First(&updated, model.ID).Error
+		}
+	})
+	if err != nil {
 		return nil, err
 	} else {
-		model.AccountID = art.AuthorId
-		return model.ToDto(), err
+		return updated.ToDto(), nil
 	}
 }
 
-func (db *ArtDB) DeleteArt(id uint) (*dto.ArtDto, error) {
-	var artModel Art
-	var accModel Account
+// Takes quantity copies of an art out of its stock and returns the art as it is left.
+// The stock is checked and decreased by one statement, so that two sales can't both take the last copy.
+func (db *ArtDB) SellArt(ctx context.Context, id uint, quantity int) (*dto.ArtDto, error) {
+	if quantity <= 0 {
+		return nil, ErrInvalidSale
+	}
 
-	if err := db.db.First(&artModel, id).Error; err != nil {
-		return nil, err
-	} else if err := db.db.First(&accModel, artModel.AccountID).Error; err != nil {
-		return nil, err
-	} else if err := db.db.Model(&accModel).Association("Arts").Delete(&artModel); err != nil {
+	var art Art
+	err := db.transaction(ctx, func(tx *ArtDB) error {
+		result := tx.db.Model(&Art{}).
+			Where("id = ? AND quantity >= ?", id, quantity).
+			Update("quantity", gorm.Expr("quantity - ?", quantity))
+		if result.Error != nil {
+			return result.Error
+		} else if err := tx.db.First(&art, id).Error; err != nil {
+			return err
+		} else if result.RowsAffected == 0 {
+			return ErrOutOfStock
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
-	} else if err := db.db.Delete(&artModel, id).Error; err != nil {
+	}
+	return art.ToDto(), nil
+}
+
+// Deletes an existing art and detaches it from its author in one transaction.
+func (db *ArtDB) DeleteArt(ctx context.Context, id uint) (*dto.ArtDto, error) {
+	var artModel Art
+
+	err := db.transaction(ctx, func(tx *ArtDB) error {
+		var accModel Account
+		if err := tx.db.First(&artModel, id).Error; err != nil {
+			return err
+		} else if err := tx.db.First(&accModel, artModel.AccountID).Error; err != nil {
+			return err
+		} else if err := tx.db.Model(&accModel).Association("Arts").Delete(&artModel); err != nil {
+			return err
+		} else {
+			return tx.db.Delete(&artModel, id).Error
+		}
+	})
+	if err != nil {
 		return nil, err
 	} else {
-		return artModel.ToDto(), err
+		return artModel.ToDto(), nil
+	}
+}
+
+// Narrows down the arts to export. Zero fields match every art.
+type ArtFilter struct {
+	AuthorId uint
+	// Part of the title, matched regardless of case.
+	Title string
+}
+
+// Calls each with every art matching filter, ordered by id, along with the username of its author and its like count.
+// The arts are read in pages of exportPageSize, each read in full before each is called, so that no read stays open
+// while the caller is slow and blocks the writers of the database. An error returned by each stops the export and is
+// returned.
+func (db *ArtDB) ExportArts(ctx context.Context, filter ArtFilter, each func(dto.ArtExportDto) error) error {
+	var after uint
+	for {
+		arts, err := db.exportPage(ctx, filter, after)
+		if err != nil {
+			return err
+		}
+
+		for _, art := range arts {
+			if err := each(art); err != nil {
+				return err
+			}
+			after = art.Id
+		}
+		if len(arts) < exportPageSize {
+			return nil
+		}
 	}
 }
+
+// Reads the page of the export following the art with id after.
+func (db *ArtDB) exportPage(ctx context.Context, filter ArtFilter, after uint) ([]dto.ArtExportDto, error) {
+	query := db.db.WithContext(ctx).Model(&Art{}).
+		Select("arts.id, arts.quantity, arts.title, arts.account_id, accounts.username, "+
+			"(SELECT COUNT(*) FROM likes WHERE likes.art_id = arts.id)").
+		Joins("LEFT JOIN accounts ON accounts.id = arts.account_id").
+		Where("arts.id > ?", after).
+		Order("arts.id").
+		Limit(exportPageSize)
+	if filter.AuthorId != 0 {
+		query = query.Where("arts.account_id = ?", filter.AuthorId)
+	}
+	if filter.Title != "" {
+		escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(filter.Title)
+		query = query.Where(`arts.title LIKE ? ESCAPE '\'`, "%"+escaped+"%")
+	}
+
+	rows, err := query.Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	arts := []dto.ArtExportDto{}
+	for rows.Next() {
+		var art dto.ArtExportDto
+		var accountId sql.NullInt64
+		var username sql.NullString
+		if err := rows.Scan(&art.Id, &art.Quantity, &art.Title, &accountId, &username, &art.LikeCount); err != nil {
+			return nil, err
+		}
+		art.AuthorId = uint(accountId.Int64)
+		art.AuthorUsername = username.String
+		arts = append(arts, art)
+	}
+	return arts, rows.Err()
+}
+
+// Counts the arts that are not deleted.
+func (db *ArtDB) CountArts(ctx context.Context) (int64, error) {
+	var count int64
+	err := db.db.WithContext(ctx).Model(&Art{}).Count(&count).Error
+	return count, err
+}
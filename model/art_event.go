@@ -0,0 +1,104 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"gorm.io/gorm"
+)
+
+type ArtEventDB struct {
+	db *gorm.DB
+}
+
+// A logged change to the catalog. Art holds the art as JSON, since the art may have changed or be gone since.
+type ArtEvent struct {
+	ID               uint `gorm:"primarykey"`
+	CreatedAt        time.Time
+	Type             string
+	ArtID            uint
+	Art              string
+	PreviousQuantity *int
+}
+
+func (model *ArtEvent) ToDto() (*dto.ArtEventDto, error) {
+	event := dto.ArtEventDto{
+		Id:               model.ID,
+		Type:             model.Type,
+		PreviousQuantity: model.PreviousQuantity,
+		CreatedAt:        model.CreatedAt,
+	}
+	if err := json.Unmarshal([]byte(model.Art), &event.Art); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// The tables are created by the migrations, see Migrator.
+func (db *ArtEventDB) Init(database *DB) error {
+	db.db = database.GormDB
+	return nil
+}
+
+// Appends an event to the log. Its id is greater than that of every event appended before it.
+func (db *ArtEventDB) AppendArtEvent(ctx context.Context, event dto.ArtEventDto) (*dto.ArtEventDto, error) {
+	art, err := json.Marshal(event.Art)
+	if err != nil {
+		return nil, err
+	}
+
+	model := ArtEvent{
+		CreatedAt:        event.CreatedAt,
+		Type:             event.Type,
+		ArtID:            event.Art.Id,
+		Art:              string(art),
+		PreviousQuantity: event.PreviousQuantity,
+	}
+	if err := db.db.WithContext(ctx).Create(&model).Error; err != nil {
+		return nil, err
+	}
+	return model.ToDto()
+}
+
+// Gets at most limit events whose id is greater than after, oldest first. A limit of 0 gets every one of them.
+func (db *ArtEventDB) GetArtEventsAfter(ctx context.Context, after uint, limit int) ([]dto.ArtEventDto, error) {
+	query := db.db.WithContext(ctx).Where("id > ?", after).Order("id")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var models []ArtEvent
+	if err := query.Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	events := []dto.ArtEventDto{}
+	for _, model := range models {
+		if event, err := model.ToDto(); err != nil {
+			return nil, err
+		} else {
+			events = append(events, *event)
+		}
+	}
+	return events, nil
+}
+
+// Gets the ids of the oldest and newest events of the log, both 0 if it is empty.
+func (db *ArtEventDB) GetArtEventBounds(ctx context.Context) (uint, uint, error) {
+	var bounds struct {
+		First uint
+		Last  uint
+	}
+	err := db.db.WithContext(ctx).Model(&ArtEvent{}).
+		Select("COALESCE(MIN(id), 0) AS first, COALESCE(MAX(id), 0) AS last").
+		Scan(&bounds).Error
+	return bounds.First, bounds.Last, err
+}
+
+// Deletes the events logged before a time and returns how many there were.
+func (db *ArtEventDB) PruneArtEvents(ctx context.Context, before time.Time) (int64, error) {
+	result := db.db.WithContext(ctx).Where("created_at < ?", before).Delete(&ArtEvent{})
+	return result.RowsAffected, result.Error
+}
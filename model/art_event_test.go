@@ -0,0 +1,84 @@
+package model_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtEvents(t *testing.T) {
+	_, gormDB := AccountDBInit(t)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+	db := model.DB{GormDB: gormDB}
+	var artEventDB model.ArtEventDB
+	require.NoError(t, artEventDB.Init(&db))
+	ctx := context.Background()
+
+	first, last, err := artEventDB.GetArtEventBounds(ctx)
+	if assert.NoError(t, err) {
+		assert.Zero(t, first)
+		assert.Zero(t, last)
+	}
+
+	old := time.Now().Add(-48 * time.Hour)
+	previous := 1
+	art := dto.PublishedArtDto{Id: 7, Title: "title", Quantity: 3, AuthorId: 1}
+	for _, event := range []dto.ArtEventDto{
+		{Type: "art.created", Art: dto.PublishedArtDto{Id: 7, Title: "title", Quantity: 1, AuthorId: 1}, CreatedAt: old},
+		{Type: "art.updated", Art: art},
+		{Type: dto.ArtQuantityChanged, Art: art, PreviousQuantity: &previous},
+	} {
+		_, err := artEventDB.AppendArtEvent(ctx, event)
+		require.NoError(t, err)
+	}
+
+	t.Run("Read", func(t *testing.T) {
+		events, err := artEventDB.GetArtEventsAfter(ctx, 0, 0)
+		if assert.NoError(t, err) && assert.Len(t, events, 3) {
+			assert.Equal(t, "art.created", events[0].Type)
+			assert.Equal(t, art, events[1].Art)
+			assert.Nil(t, events[1].PreviousQuantity)
+			if assert.NotNil(t, events[2].PreviousQuantity) {
+				assert.Equal(t, 1, *events[2].PreviousQuantity)
+			}
+		}
+
+		events, err = artEventDB.GetArtEventsAfter(ctx, 1, 1)
+		if assert.NoError(t, err) && assert.Len(t, events, 1) {
+			assert.Equal(t, uint(2), events[0].Id)
+		}
+
+		first, last, err := artEventDB.GetArtEventBounds(ctx)
+		if assert.NoError(t, err) {
+			assert.Equal(t, uint(1), first)
+			assert.Equal(t, uint(3), last)
+		}
+	})
+
+	t.Run("Prune", func(t *testing.T) {
+		pruned, err := artEventDB.PruneArtEvents(ctx, time.Now().Add(-24*time.Hour))
+		if assert.NoError(t, err) {
+			assert.Equal(t, int64(1), pruned)
+		}
+		first, _, err := artEventDB.GetArtEventBounds(ctx)
+		if assert.NoError(t, err) {
+			assert.Equal(t, uint(2), first)
+		}
+
+		// ids aren't reused once every event is pruned
+		_, err = artEventDB.PruneArtEvents(ctx, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		event, err := artEventDB.AppendArtEvent(ctx, dto.ArtEventDto{Type: "art.deleted", Art: art})
+		if assert.NoError(t, err) {
+			assert.Equal(t, uint(4), event.Id)
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package model_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/nafiz1001/gallery-go/dto"
@@ -23,7 +24,7 @@ func ArtDBInit(t *testing.T, gormDB *gorm.DB) model.ArtDB {
 }
 
 func CreateArt(t *testing.T, artDB model.ArtDB, artDto dto.ArtDto) dto.ArtDto {
-	dto, err := artDB.CreateArt(artDto)
+	dto, err := artDB.CreateArt(context.Background(), artDto)
 	require.NoError(t, err)
 	require.NotNil(t, dto)
 	assert.Equal(t, dto.Quantity, artDto.Quantity)
@@ -46,7 +47,7 @@ func createUserAndArt(t *testing.T, accountDB model.AccountDB, artDB model.ArtDB
 }
 
 func TestArtDBInit(t *testing.T) {
-	gormDB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{
+	gormDB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared&_foreign_keys=true"), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
 	})
 	if err != nil {
@@ -89,7 +90,7 @@ func TestCreateArt(t *testing.T) {
 	assert.NotEqual(t, dto1.Id, dto2.Id)
 
 	// fail to create art for non-existent account
-	dto3, err := artDB.CreateArt(dto.ArtDto{
+	dto3, err := artDB.CreateArt(context.Background(), dto.ArtDto{
 		Id:       0,
 		Quantity: 2,
 		Title:    "title",
@@ -111,28 +112,28 @@ func TestGetArt(t *testing.T) {
 		otherDb.Close()
 	}()
 
-	dto, err := artDB.GetArt(0)
+	dto, err := artDB.GetArt(context.Background(), 0)
 	assert.Error(t, err)
 	assert.Nil(t, dto)
 
 	_, artDto := createUserAndArt(t, accountDB, artDB, "username")
 
 	// get art successfully
-	dto, err = artDB.GetArt(artDto.Id)
+	dto, err = artDB.GetArt(context.Background(), artDto.Id)
 	if assert.NoError(t, err) && assert.NotNil(t, dto) {
 		assert.Equal(t, *dto, artDto)
 	}
 
 	// get art successfully from another account
 	_, artDto2 := createUserAndArt(t, accountDB, artDB, "username2")
-	dto, err = artDB.GetArt(artDto2.Id)
+	dto, err = artDB.GetArt(context.Background(), artDto2.Id)
 	if assert.NoError(t, err) && assert.NotNil(t, dto) {
 		assert.Equal(t, *dto, artDto2)
 		assert.NotEqual(t, dto.AuthorId, artDto.AuthorId)
 	}
 
 	// don't get art
-	dto, err = artDB.GetArt(420)
+	dto, err = artDB.GetArt(context.Background(), 420)
 	assert.Error(t, err)
 	assert.Nil(t, dto)
 }
@@ -146,13 +147,13 @@ func TestGetArts(t *testing.T) {
 	}()
 
 	// there should be zero arts present
-	artDtos, err := artDB.GetArts()
+	artDtos, err := artDB.GetArts(context.Background())
 	assert.NoError(t, err)
 	assert.Equal(t, len(artDtos), 0)
 
 	// there should be only 1 art present
 	accountDto, artDto := createUserAndArt(t, accountDB, artDB, "username")
-	artDtos, err = artDB.GetArts()
+	artDtos, err = artDB.GetArts(context.Background())
 	if assert.NoError(t, err) && assert.Equal(t, len(artDtos), 1) {
 		if assert.NotNil(t, artDtos[0]) {
 			assert.Equal(t, artDtos[0], artDto)
@@ -166,7 +167,7 @@ func TestGetArts(t *testing.T) {
 		Title:    "title2",
 		AuthorId: accountDto.Id,
 	})
-	artDtos, err = artDB.GetArts()
+	artDtos, err = artDB.GetArts(context.Background())
 	if assert.NoError(t, err) {
 		assert.Equal(t, len(artDtos), 2)
 		artFound1 := false
@@ -187,6 +188,116 @@ func TestGetArts(t *testing.T) {
 	}
 }
 
+func TestGetArtsAfter(t *testing.T) {
+	accountDB, gormDB := AccountDBInit(t)
+	artDB := ArtDBInit(t, gormDB)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+
+	accountDto, artDto1 := createUserAndArt(t, accountDB, artDB, "username")
+	artDto2 := CreateArt(t, artDB, dto.ArtDto{Quantity: 2, Title: "title2", AuthorId: accountDto.Id})
+	artDto3 := CreateArt(t, artDB, dto.ArtDto{Quantity: 3, Title: "title3", AuthorId: accountDto.Id})
+
+	// first page
+	artDtos, err := artDB.GetArtsAfter(context.Background(), 0, 2)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []dto.ArtDto{artDto1, artDto2}, artDtos)
+	}
+
+	// next page, shorter than the limit
+	artDtos, err = artDB.GetArtsAfter(context.Background(), artDto2.Id, 2)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []dto.ArtDto{artDto3}, artDtos)
+	}
+
+	// past the last art
+	artDtos, err = artDB.GetArtsAfter(context.Background(), artDto3.Id, 2)
+	if assert.NoError(t, err) {
+		assert.Empty(t, artDtos)
+	}
+
+	// no limit
+	artDtos, err = artDB.GetArtsAfter(context.Background(), artDto1.Id, 0)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []dto.ArtDto{artDto2, artDto3}, artDtos)
+	}
+}
+
+func TestExportArts(t *testing.T) {
+	accountDB, gormDB := AccountDBInit(t)
+	artDB := ArtDBInit(t, gormDB)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+
+	accountDto1, artDto1 := createUserAndArt(t, accountDB, artDB, "username1")
+	accountDto2, artDto2 := createUserAndArt(t, accountDB, artDB, "username2")
+	artDto3 := CreateArt(t, artDB, dto.ArtDto{Quantity: 3, Title: "100%_Other", AuthorId: accountDto1.Id})
+
+	export := func(filter model.ArtFilter) []dto.ArtExportDto {
+		arts := []dto.ArtExportDto{}
+		err := artDB.ExportArts(context.Background(), filter, func(art dto.ArtExportDto) error {
+			arts = append(arts, art)
+			return nil
+		})
+		assert.NoError(t, err)
+		return arts
+	}
+
+	// every art, with the username of its author
+	assert.Equal(t, []dto.ArtExportDto{
+		{ArtDto: artDto1, AuthorUsername: accountDto1.Username},
+		{ArtDto: artDto2, AuthorUsername: accountDto2.Username},
+		{ArtDto: artDto3, AuthorUsername: accountDto1.Username},
+	}, export(model.ArtFilter{}))
+
+	// by author
+	assert.Equal(t, []dto.ArtExportDto{
+		{ArtDto: artDto2, AuthorUsername: accountDto2.Username},
+	}, export(model.ArtFilter{AuthorId: accountDto2.Id}))
+
+	// by part of the title, with wildcards taken literally
+	assert.Equal(t, []dto.ArtExportDto{
+		{ArtDto: artDto3, AuthorUsername: accountDto1.Username},
+	}, export(model.ArtFilter{Title: "0%_o"}))
+	assert.Empty(t, export(model.ArtFilter{Title: "_x"}))
+
+	// deleted arts are left out
+	_, err := artDB.DeleteArt(context.Background(), artDto1.Id)
+	assert.NoError(t, err)
+	assert.Len(t, export(model.ArtFilter{AuthorId: accountDto1.Id}), 1)
+
+	// an error from each stops the export
+	calls := 0
+	err = artDB.ExportArts(context.Background(), model.ArtFilter{}, func(art dto.ArtExportDto) error {
+		calls++
+		return errInjected
+	})
+	assert.ErrorIs(t, err, errInjected)
+	assert.Equal(t, 1, calls)
+
+	// arts are read a page at a time, so the database can be written to while they are exported
+	arts := []model.Art{}
+	for i := 0; i < 600; i++ {
+		arts = append(arts, model.Art{Quantity: 1, Title: "bulk", AccountID: accountDto2.Id})
+	}
+	require.NoError(t, gormDB.Create(&arts).Error)
+	exported := 0
+	err = artDB.ExportArts(context.Background(), model.ArtFilter{Title: "bulk"}, func(art dto.ArtExportDto) error {
+		if exported++; exported == 1 {
+			_, err := artDB.CreateArt(context.Background(), dto.ArtDto{Quantity: 1, Title: "bulk", AuthorId: accountDto2.Id})
+			return err
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	// the art created during the export comes after the others, and is exported as well
+	assert.Equal(t, 601, exported)
+}
+
 func TestUpdateArt(t *testing.T) {
 	accountDB, gormDB := AccountDBInit(t)
 	artDB := ArtDBInit(t, gormDB)
@@ -197,7 +308,7 @@ func TestUpdateArt(t *testing.T) {
 	_, artDto := createUserAndArt(t, accountDB, artDB, "username")
 
 	// successfully update art
-	artDto2, err := artDB.UpdateArt(dto.ArtDto{
+	artDto2, err := artDB.UpdateArt(context.Background(), dto.ArtDto{
 		Title:    "new_title",
 		Quantity: 2,
 		AuthorId: artDto.AuthorId,
@@ -209,14 +320,25 @@ func TestUpdateArt(t *testing.T) {
 		assert.Equal(t, artDto.Id, artDto2.Id)
 		assert.Equal(t, artDto2.AuthorId, artDto.AuthorId)
 
-		artDto22, err := artDB.GetArt(artDto.Id)
+		artDto22, err := artDB.GetArt(context.Background(), artDto.Id)
 		if assert.NoError(t, err) && assert.NotNil(t, artDto22) {
 			assert.Equal(t, artDto2, artDto22)
 		}
 	}
 
+	// fields left out are kept as they were
+	artDto21, err := artDB.UpdateArt(context.Background(), dto.ArtDto{
+		Title:    "renamed",
+		AuthorId: artDto.AuthorId,
+		Id:       artDto.Id,
+	})
+	if assert.NoError(t, err) && assert.NotNil(t, artDto21) {
+		assert.Equal(t, "renamed", artDto21.Title)
+		assert.Equal(t, 2, artDto21.Quantity)
+	}
+
 	// don't transfer ownership to an account that does not exist
-	artDto3, err := artDB.UpdateArt(dto.ArtDto{
+	artDto3, err := artDB.UpdateArt(context.Background(), dto.ArtDto{
 		Title:    "new_title",
 		Quantity: 2,
 		AuthorId: 420,
@@ -227,7 +349,7 @@ func TestUpdateArt(t *testing.T) {
 
 	// transfer ownership of art to an existing account
 	accountDto2 := CreateAccount(t, accountDB, "username2", "password2")
-	artDto4, err := artDB.UpdateArt(dto.ArtDto{
+	artDto4, err := artDB.UpdateArt(context.Background(), dto.ArtDto{
 		Title:    "new_title",
 		Quantity: 2,
 		AuthorId: accountDto2.Id,
@@ -236,14 +358,14 @@ func TestUpdateArt(t *testing.T) {
 	if assert.NoError(t, err) && assert.NotNil(t, artDto4) {
 		assert.Equal(t, artDto.Id, artDto4.Id)
 		assert.Equal(t, accountDto2.Id, artDto4.AuthorId)
-		artDto42, err := artDB.GetArt(artDto.Id)
+		artDto42, err := artDB.GetArt(context.Background(), artDto.Id)
 		if assert.NoError(t, err) && assert.NotNil(t, artDto42) {
 			assert.Equal(t, artDto4, artDto42)
 		}
 	}
 
 	// don't update art that does not exist
-	artDto5, err := artDB.UpdateArt(dto.ArtDto{
+	artDto5, err := artDB.UpdateArt(context.Background(), dto.ArtDto{
 		Title:    "new_title",
 		Quantity: 2,
 		AuthorId: accountDto2.Id,
@@ -253,6 +375,44 @@ func TestUpdateArt(t *testing.T) {
 	assert.Nil(t, artDto5)
 }
 
+func TestSellArt(t *testing.T) {
+	accountDB, gormDB := AccountDBInit(t)
+	artDB := ArtDBInit(t, gormDB)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+	accountDto := CreateAccount(t, accountDB, "username", "password")
+	artDto := CreateArt(t, artDB, dto.ArtDto{Quantity: 3, Title: "title", AuthorId: accountDto.Id})
+
+	// sell some of the copies
+	artDto2, err := artDB.SellArt(context.Background(), artDto.Id, 2)
+	if assert.NoError(t, err) && assert.NotNil(t, artDto2) {
+		assert.Equal(t, 1, artDto2.Quantity)
+		assert.Equal(t, "title", artDto2.Title)
+	}
+
+	// don't sell more copies than are left, nor none
+	_, err = artDB.SellArt(context.Background(), artDto.Id, 2)
+	assert.ErrorIs(t, err, model.ErrOutOfStock)
+	_, err = artDB.SellArt(context.Background(), artDto.Id, 0)
+	assert.ErrorIs(t, err, model.ErrInvalidSale)
+
+	// sell the last copy
+	artDto3, err := artDB.SellArt(context.Background(), artDto.Id, 1)
+	if assert.NoError(t, err) && assert.NotNil(t, artDto3) {
+		assert.Equal(t, 0, artDto3.Quantity)
+		artDto32, err := artDB.GetArt(context.Background(), artDto.Id)
+		if assert.NoError(t, err) {
+			assert.Equal(t, artDto3, artDto32)
+		}
+	}
+
+	// don't sell art that does not exist
+	_, err = artDB.SellArt(context.Background(), 420, 1)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
 func TestDeleteArt(t *testing.T) {
 	accountDB, gormDB := AccountDBInit(t)
 	artDB := ArtDBInit(t, gormDB)
@@ -263,15 +423,42 @@ func TestDeleteArt(t *testing.T) {
 	_, artDto := createUserAndArt(t, accountDB, artDB, "username")
 
 	// successful delete art
-	artDtoTemp, err := artDB.DeleteArt(artDto.Id)
+	artDtoTemp, err := artDB.DeleteArt(context.Background(), artDto.Id)
 	if assert.NoError(t, err) && assert.Equal(t, artDto, *artDtoTemp) {
-		artDto, err := artDB.GetArt(artDto.Id)
+		artDto, err := artDB.GetArt(context.Background(), artDto.Id)
 		assert.Error(t, err)
 		assert.Nil(t, artDto)
 	}
 
 	// can't delete non-existent art
-	artDto2, err := artDB.DeleteArt(420)
+	artDto2, err := artDB.DeleteArt(context.Background(), 420)
 	assert.Error(t, err)
 	assert.Nil(t, artDto2)
 }
+
+func TestCancelledContext(t *testing.T) {
+	accountDB, gormDB := AccountDBInit(t)
+	artDB := ArtDBInit(t, gormDB)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+	accountDto := CreateAccount(t, accountDB, "username", "password")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// queries don't run once the context is cancelled
+	arts, err := artDB.GetArts(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Len(t, arts, 0)
+
+	art, err := artDB.CreateArt(ctx, dto.ArtDto{Quantity: 1, Title: "title", AuthorId: accountDto.Id})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, art)
+
+	arts, err = artDB.GetArts(context.Background())
+	if assert.NoError(t, err) {
+		assert.Len(t, arts, 0)
+	}
+}
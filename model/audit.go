@@ -0,0 +1,123 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"gorm.io/gorm"
+)
+
+type AuditDB struct {
+	db *gorm.DB
+}
+
+// An append-only record of a mutation.
+// Before and After hold the JSON representation of the entity, Diff holds the changed fields as JSON.
+type AuditEntry struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	ActorID   uint
+	Action    string
+	Entity    string `gorm:"index:idx_audit_entity"`
+	EntityID  uint   `gorm:"index:idx_audit_entity"`
+	Before    string
+	After     string
+	Diff      string
+	RequestID string
+}
+
+// Converts AuditEntry to AuditEntryDto.
+func (model *AuditEntry) ToDto() (*dto.AuditEntryDto, error) {
+	var diff map[string]dto.FieldChange
+	if err := json.Unmarshal([]byte(model.Diff), &diff); err != nil {
+		return nil, err
+	}
+
+	return &dto.AuditEntryDto{
+		Id:        model.ID,
+		ActorId:   model.ActorID,
+		Action:    model.Action,
+		Entity:    model.Entity,
+		EntityId:  model.EntityID,
+		Before:    json.RawMessage(model.Before),
+		After:     json.RawMessage(model.After),
+		Diff:      diff,
+		Timestamp: model.CreatedAt,
+		RequestId: model.RequestID,
+	}, nil
+}
+
+// The tables are created by the migrations, see Migrator.
+func (db *AuditDB) Init(database *DB) error {
+	db.db = database.GormDB
+	return nil
+}
+
+// Appends an entry to the audit trail.
+// The diff is computed from the before and after documents, and the id and timestamp are assigned by the database.
+func (db *AuditDB) Record(ctx context.Context, entry dto.AuditEntryDto) (*dto.AuditEntryDto, error) {
+	before := nullIfEmpty(entry.Before)
+	after := nullIfEmpty(entry.After)
+
+	diff, err := dto.DiffJSON(before, after)
+	if err != nil {
+		return nil, err
+	}
+	diffJSON, err := json.Marshal(diff)
+	if err != nil {
+		return nil, err
+	}
+
+	model := AuditEntry{
+		ActorID:   entry.ActorId,
+		Action:    entry.Action,
+		Entity:    entry.Entity,
+		EntityID:  entry.EntityId,
+		Before:    string(before),
+		After:     string(after),
+		Diff:      string(diffJSON),
+		RequestID: entry.RequestId,
+	}
+	if err := db.db.WithContext(ctx).Create(&model).Error; err != nil {
+		return nil, err
+	} else {
+		return model.ToDto()
+	}
+}
+
+// Gets audit entries in the order they were recorded.
+// An empty entity or a zero id matches every entity or id respectively.
+func (db *AuditDB) GetEntries(ctx context.Context, entity string, id uint) ([]dto.AuditEntryDto, error) {
+	var models []AuditEntry
+
+	query := db.db.WithContext(ctx).Order("id")
+	if entity != "" {
+		query = query.Where("entity = ?", entity)
+	}
+	if id != 0 {
+		query = query.Where("entity_id = ?", id)
+	}
+
+	if err := query.Find(&models).Error; err != nil {
+		return []dto.AuditEntryDto{}, err
+	}
+
+	entries := []dto.AuditEntryDto{}
+	for _, m := range models {
+		if entry, err := m.ToDto(); err != nil {
+			return []dto.AuditEntryDto{}, err
+		} else {
+			entries = append(entries, *entry)
+		}
+	}
+	return entries, nil
+}
+
+func nullIfEmpty(data json.RawMessage) json.RawMessage {
+	if len(data) == 0 {
+		return json.RawMessage("null")
+	}
+	return data
+}
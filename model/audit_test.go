@@ -0,0 +1,111 @@
+package model_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func AuditDBInit(t *testing.T, gormDB *gorm.DB) model.AuditDB {
+	db := model.DB{GormDB: gormDB}
+
+	var auditDB model.AuditDB
+	err := auditDB.Init(&db)
+	require.NoError(t, err)
+
+	return auditDB
+}
+
+func TestRecordAudit(t *testing.T) {
+	_, gormDB := AccountDBInit(t)
+	auditDB := AuditDBInit(t, gormDB)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+
+	// record creation without a before document
+	entry, err := auditDB.Record(context.Background(), dto.AuditEntryDto{
+		ActorId:   1,
+		Action:    "create",
+		Entity:    "art",
+		EntityId:  2,
+		After:     json.RawMessage(`{"id":2,"title":"title","quantity":1}`),
+		RequestId: "request",
+	})
+	if assert.NoError(t, err) && assert.NotNil(t, entry) {
+		assert.NotZero(t, entry.Id)
+		assert.NotZero(t, entry.Timestamp)
+		assert.Equal(t, "request", entry.RequestId)
+		assert.JSONEq(t, "null", string(entry.Before))
+		assert.Len(t, entry.Diff, 3)
+	}
+
+	// record update with only the changed fields in the diff
+	entry, err = auditDB.Record(context.Background(), dto.AuditEntryDto{
+		ActorId:  1,
+		Action:   "update",
+		Entity:   "art",
+		EntityId: 2,
+		Before:   json.RawMessage(`{"id":2,"title":"title","quantity":1}`),
+		After:    json.RawMessage(`{"id":2,"title":"title2","quantity":1}`),
+	})
+	if assert.NoError(t, err) && assert.NotNil(t, entry) {
+		assert.Equal(t, map[string]dto.FieldChange{
+			"title": {Before: "title", After: "title2"},
+		}, entry.Diff)
+	}
+
+	// fail to record malformed documents
+	entry, err = auditDB.Record(context.Background(), dto.AuditEntryDto{
+		Action: "update",
+		Entity: "art",
+		Before: json.RawMessage(`{`),
+	})
+	assert.Error(t, err)
+	assert.Nil(t, entry)
+}
+
+func TestGetAuditEntries(t *testing.T) {
+	_, gormDB := AccountDBInit(t)
+	auditDB := AuditDBInit(t, gormDB)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+
+	for _, e := range []dto.AuditEntryDto{
+		{Action: "create", Entity: "art", EntityId: 1},
+		{Action: "create", Entity: "account", EntityId: 1},
+		{Action: "update", Entity: "art", EntityId: 1},
+		{Action: "create", Entity: "art", EntityId: 2},
+	} {
+		_, err := auditDB.Record(context.Background(), e)
+		require.NoError(t, err)
+	}
+
+	// filter by entity and id in recording order
+	entries, err := auditDB.GetEntries(context.Background(), "art", 1)
+	if assert.NoError(t, err) && assert.Len(t, entries, 2) {
+		assert.Equal(t, "create", entries[0].Action)
+		assert.Equal(t, "update", entries[1].Action)
+	}
+
+	// filter by entity only
+	entries, err = auditDB.GetEntries(context.Background(), "art", 0)
+	if assert.NoError(t, err) {
+		assert.Len(t, entries, 3)
+	}
+
+	// no filter
+	entries, err = auditDB.GetEntries(context.Background(), "", 0)
+	if assert.NoError(t, err) {
+		assert.Len(t, entries, 4)
+	}
+}
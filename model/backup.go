@@ -0,0 +1,134 @@
+package model
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+const (
+	// Pages copied by each step of a backup.
+	backupStepPages = 1000
+	// Pause between the steps of a backup, which lets writers in.
+	backupStepDelay = 10 * time.Millisecond
+)
+
+// Copies the database to a new SQLite file at path with SQLite's online backup API.
+// The database is copied a few pages at a time and the copy starts over whenever the database is written to meanwhile,
+// so the backup is consistent even while the gallery is being served. The file only appears at path once complete.
+func (db *DB) Backup(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+
+	tmp := path + ".tmp"
+	os.Remove(tmp)
+	defer os.Remove(tmp)
+
+	err := db.rawSQLite(ctx, func(src *sqlite3.SQLiteConn) error {
+		dst, err := openSQLite(tmp)
+		if err != nil {
+			return err
+		}
+		defer dst.Close()
+
+		return copySQLite(ctx, dst, src)
+	})
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Replaces the content of the database with the SQLite file at path, such as one written by DB.Backup.
+// Nothing else should use the database meanwhile: it is overwritten page by page.
+func (db *DB) RestoreBackup(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+
+	src, err := openSQLite("file:" + path + "?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	var check string
+	if rows, err := src.Query("PRAGMA quick_check", nil); err != nil {
+		return fmt.Errorf("%s is not a SQLite database: %w", path, err)
+	} else {
+		values := []driver.Value{nil}
+		err := rows.Next(values)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+		check, _ = values[0].(string)
+	}
+	if check != "ok" {
+		return fmt.Errorf("%s is corrupt: %s", path, check)
+	}
+
+	return db.rawSQLite(ctx, func(dst *sqlite3.SQLiteConn) error {
+		return copySQLite(ctx, dst, src)
+	})
+}
+
+// Calls f with a connection of the database's SQLite driver.
+func (db *DB) rawSQLite(ctx context.Context, f func(conn *sqlite3.SQLiteConn) error) error {
+	sqlDB, err := db.GormDB.DB()
+	if err != nil {
+		return err
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn interface{}) error {
+		if sqliteConn, ok := driverConn.(*sqlite3.SQLiteConn); !ok {
+			return errors.New("backups need a SQLite database")
+		} else {
+			return f(sqliteConn)
+		}
+	})
+}
+
+func openSQLite(dsn string) (*sqlite3.SQLiteConn, error) {
+	conn, err := (&sqlite3.SQLiteDriver{}).Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return conn.(*sqlite3.SQLiteConn), nil
+}
+
+// Copies the main database of src to dst with SQLite's online backup API.
+func copySQLite(ctx context.Context, dst *sqlite3.SQLiteConn, src *sqlite3.SQLiteConn) error {
+	backup, err := dst.Backup("main", src, "main")
+	if err != nil {
+		return err
+	}
+
+	for {
+		if done, err := backup.Step(backupStepPages); err != nil {
+			backup.Finish()
+			return err
+		} else if done {
+			return backup.Finish()
+		}
+
+		select {
+		case <-ctx.Done():
+			backup.Finish()
+			return ctx.Err()
+		case <-time.After(backupStepDelay):
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package model_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Opens the SQLite file at path, creating it if needed, and closes it at the end of the test.
+func FileDBInit(t *testing.T, path string, migrate bool) *model.DB {
+	gormDB, err := gorm.Open(sqlite.Open("file:"+path+"?_foreign_keys=true"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	})
+
+	db := &model.DB{GormDB: gormDB}
+	if migrate {
+		Migrate(t, db)
+	}
+	return db
+}
+
+// Fills db with an account, two arts of which one is deleted, a revision, a like, a comment with a reply and an audit entry.
+func fillDB(t *testing.T, db *model.DB) model.Repositories {
+	repos, err := db.Repositories()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	account, err := repos.Accounts.CreateAccount(ctx, dto.AccountDto{Username: "username", Password: "password"})
+	require.NoError(t, err)
+	art, err := repos.Arts.CreateArt(ctx, dto.ArtDto{Title: "title", Quantity: 1, AuthorId: account.Id})
+	require.NoError(t, err)
+	_, err = repos.Arts.UpdateArt(ctx, dto.ArtDto{Id: art.Id, Title: "renamed", AuthorId: account.Id})
+	require.NoError(t, err)
+	deleted, err := repos.Arts.CreateArt(ctx, dto.ArtDto{Title: "deleted", Quantity: 2, AuthorId: account.Id})
+	require.NoError(t, err)
+	_, err = repos.Arts.DeleteArt(ctx, deleted.Id)
+	require.NoError(t, err)
+	_, err = repos.Likes.LikeArt(ctx, account.Id, art.Id)
+	require.NoError(t, err)
+	comment, err := repos.Comments.CreateComment(ctx, dto.CommentDto{ArtId: art.Id, AuthorId: account.Id, Body: "comment"})
+	require.NoError(t, err)
+	_, err = repos.Comments.CreateComment(ctx, dto.CommentDto{ArtId: art.Id, AuthorId: account.Id, ParentId: comment.Id, Body: "reply"})
+	require.NoError(t, err)
+	_, err = repos.Notifications.CreateNotification(ctx, dto.NotificationDto{AccountId: account.Id, ActorId: account.Id, Type: dto.NotificationLike, ArtId: art.Id})
+	require.NoError(t, err)
+	_, err = repos.Notifications.CreateNotification(ctx, dto.NotificationDto{AccountId: account.Id, ActorId: account.Id, Type: dto.NotificationComment, ArtId: art.Id, CommentId: comment.Id})
+	require.NoError(t, err)
+	webhook, err := repos.Webhooks.CreateWebhook(ctx, dto.WebhookDto{URL: "http://localhost/hook", Events: []string{"art.created"}, Secret: "secret"})
+	require.NoError(t, err)
+	_, err = repos.Webhooks.EnqueueDeliveries(ctx, "art.created", []byte(`{"type": "art.created"}`))
+	require.NoError(t, err)
+	_, err = repos.Webhooks.ReplayDelivery(ctx, webhook.Id, 1)
+	require.NoError(t, err)
+	_, err = repos.ArtEvents.AppendArtEvent(ctx, dto.ArtEventDto{Type: "art.created", Art: dto.PublishedArt(*art)})
+	require.NoError(t, err)
+	previous := 1
+	_, err = repos.ArtEvents.AppendArtEvent(ctx, dto.ArtEventDto{Type: dto.ArtQuantityChanged, Art: dto.PublishedArtDto{Id: art.Id, Title: "renamed"}, PreviousQuantity: &previous})
+	require.NoError(t, err)
+	_, err = repos.Audit.Record(ctx, dto.AuditEntryDto{ActorId: account.Id, Action: "create", Entity: "art", EntityId: art.Id})
+	require.NoError(t, err)
+
+	return repos
+}
+
+// Checks that db holds what fillDB put in it.
+func assertFilled(t *testing.T, db *model.DB) {
+	repos, err := db.Repositories()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	account, err := repos.Accounts.GetAccountByUsername(ctx, "username")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "password", account.Password)
+	}
+	arts, err := repos.Arts.GetArts(ctx)
+	if assert.NoError(t, err) && assert.Len(t, arts, 1) {
+		assert.Equal(t, "renamed", arts[0].Title)
+
+		revisions, err := repos.Arts.GetArtRevisions(ctx, arts[0].Id)
+		assert.NoError(t, err)
+		assert.Len(t, revisions, 1)
+
+		counts, liked, err := repos.Likes.GetLikes(ctx, account.Id, []uint{arts[0].Id})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), counts[arts[0].Id])
+		assert.True(t, liked[arts[0].Id])
+
+		comments, err := repos.Comments.GetComments(ctx, model.CommentFilter{ArtId: arts[0].Id}, 0, 0)
+		if assert.NoError(t, err) && assert.Len(t, comments, 2) {
+			assert.Zero(t, comments[0].ParentId)
+			assert.Equal(t, comments[0].Id, comments[1].ParentId)
+		}
+	}
+	notifications, err := repos.Notifications.GetNotifications(ctx, account.Id, true, 0, 0)
+	if assert.NoError(t, err) && assert.Len(t, notifications, 2) {
+		assert.NotZero(t, notifications[0].CommentId)
+		assert.Zero(t, notifications[1].CommentId)
+	}
+	webhooks, err := repos.Webhooks.GetWebhooks(ctx)
+	if assert.NoError(t, err) && assert.Len(t, webhooks, 1) {
+		assert.Equal(t, "secret", webhooks[0].Secret)
+		assert.Equal(t, []string{"art.created"}, webhooks[0].Events)
+
+		deliveries, err := repos.Webhooks.GetDeliveries(ctx, webhooks[0].Id, 0, 0)
+		if assert.NoError(t, err) && assert.Len(t, deliveries, 2) {
+			assert.Equal(t, deliveries[1].Id, deliveries[0].ReplayOf)
+			assert.JSONEq(t, `{"type": "art.created"}`, string(deliveries[1].Payload))
+		}
+	}
+	artEvents, err := repos.ArtEvents.GetArtEventsAfter(ctx, 0, 0)
+	if assert.NoError(t, err) && assert.Len(t, artEvents, 2) {
+		assert.Equal(t, "title", artEvents[0].Art.Title)
+		assert.Nil(t, artEvents[0].PreviousQuantity)
+		if assert.NotNil(t, artEvents[1].PreviousQuantity) {
+			assert.Equal(t, 1, *artEvents[1].PreviousQuantity)
+		}
+	}
+	entries, err := repos.Audit.GetEntries(ctx, "art", 0)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// the deleted art is kept as it was
+	var deleted int64
+	assert.NoError(t, db.GormDB.Table("arts").Where("deleted_at IS NOT NULL").Count(&deleted).Error)
+	assert.Equal(t, int64(1), deleted)
+}
+
+func TestBackup(t *testing.T) {
+	dir := t.TempDir()
+	db := FileDBInit(t, filepath.Join(dir, "gallery.db"), true)
+	fillDB(t, db)
+
+	path := filepath.Join(dir, "backup.db")
+	require.NoError(t, db.Backup(context.Background(), path))
+	assertFilled(t, FileDBInit(t, path, false))
+	assert.NoFileExists(t, path+".tmp")
+
+	// existing files are left alone
+	assert.Error(t, db.Backup(context.Background(), path))
+
+	// restoring replaces whatever the database held
+	restored := FileDBInit(t, filepath.Join(dir, "restored.db"), true)
+	repos, err := restored.Repositories()
+	require.NoError(t, err)
+	_, err = repos.Accounts.CreateAccount(context.Background(), dto.AccountDto{Username: "other", Password: "password"})
+	require.NoError(t, err)
+
+	require.NoError(t, restored.RestoreBackup(context.Background(), path))
+	assertFilled(t, restored)
+	_, err = repos.Accounts.GetAccountByUsername(context.Background(), "other")
+	assert.Error(t, err)
+
+	// only SQLite files can be restored
+	notSQLite := filepath.Join(dir, "backup.json")
+	require.NoError(t, os.WriteFile(notSQLite, []byte(strings.Repeat("not a database\n", 100)), 0600))
+	assert.Error(t, restored.RestoreBackup(context.Background(), notSQLite))
+	assert.Error(t, restored.RestoreBackup(context.Background(), filepath.Join(dir, "missing.db")))
+	assertFilled(t, restored)
+}
+
+func TestDump(t *testing.T) {
+	dir := t.TempDir()
+	db := FileDBInit(t, filepath.Join(dir, "gallery.db"), true)
+	fillDB(t, db)
+
+	var dump bytes.Buffer
+	require.NoError(t, db.Dump(context.Background(), &dump))
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(dump.Bytes(), &decoded), dump.String())
+	assert.Equal(t, float64(model.DumpVersion), decoded["version"])
+	assert.Len(t, decoded["arts"], 2)
+
+	// loading replaces whatever the database held
+	loaded := FileDBInit(t, filepath.Join(dir, "loaded.db"), true)
+	repos, err := loaded.Repositories()
+	require.NoError(t, err)
+	_, err = repos.Accounts.CreateAccount(context.Background(), dto.AccountDto{Username: "other", Password: "password"})
+	require.NoError(t, err)
+
+	require.NoError(t, loaded.Load(context.Background(), bytes.NewReader(dump.Bytes())))
+	assertFilled(t, loaded)
+	_, err = repos.Accounts.GetAccountByUsername(context.Background(), "other")
+	assert.Error(t, err)
+
+	// the ids carry over, so new rows don't collide with loaded ones
+	account, err := repos.Accounts.GetAccountByUsername(context.Background(), "username")
+	require.NoError(t, err)
+	_, err = repos.Arts.CreateArt(context.Background(), dto.ArtDto{Title: "new", AuthorId: account.Id})
+	assert.NoError(t, err)
+
+	// a failed load leaves the database alone
+	for _, bad := range []string{
+		strings.Replace(dump.String(), `"schema_version":`, `"schema_version":1`, 1),
+		strings.Replace(dump.String(), `"arts"`, `"paintings"`, 1),
+		strings.Replace(dump.String(), `"audit_entries"`, `"accounts"`, 1),
+		dump.String()[:dump.Len()/2],
+	} {
+		assert.Error(t, loaded.Load(context.Background(), strings.NewReader(bad)))
+		count, err := repos.Arts.CountArts(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+	}
+
+	// the schema must be current
+	empty := FileDBInit(t, filepath.Join(dir, "empty.db"), false)
+	assert.Error(t, empty.Dump(context.Background(), &bytes.Buffer{}))
+	assert.Error(t, empty.Load(context.Background(), bytes.NewReader(dump.Bytes())))
+}
@@ -0,0 +1,189 @@
+package model
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"gorm.io/gorm"
+)
+
+// Longest comment body, in characters.
+const MaxCommentLength = 2000
+
+type CommentDB struct {
+	db *gorm.DB
+}
+
+// A comment on an art. ParentID is zero, and NULL in the database, for comments that aren't replies.
+type Comment struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	ArtID     uint
+	AccountID uint
+	ParentID  uint
+	Body      string
+	Status    string
+}
+
+// Which comments CommentDB.GetComments gets, zero fields match every comment.
+type CommentFilter struct {
+	ArtId    uint
+	Statuses []string
+}
+
+func (model *Comment) ToDto() *dto.CommentDto {
+	return &dto.CommentDto{
+		Id:        model.ID,
+		ArtId:     model.ArtID,
+		AuthorId:  model.AccountID,
+		ParentId:  model.ParentID,
+		Body:      model.Body,
+		Status:    model.Status,
+		CreatedAt: model.CreatedAt,
+		UpdatedAt: model.UpdatedAt,
+	}
+}
+
+// The tables are created by the migrations, see Migrator.
+func (db *CommentDB) Init(database *DB) error {
+	db.db = database.GormDB
+	return nil
+}
+
+// Checks that a comment body is neither blank nor longer than MaxCommentLength.
+func ValidateCommentBody(body string) error {
+	if strings.TrimSpace(body) == "" || utf8.RuneCountInString(body) > MaxCommentLength {
+		return ErrInvalidComment
+	}
+	return nil
+}
+
+// Creates a visible comment on an existing art, replying to the comment with ParentId unless it is zero.
+func (db *CommentDB) CreateComment(ctx context.Context, comment dto.CommentDto) (*dto.CommentDto, error) {
+	if err := ValidateCommentBody(comment.Body); err != nil {
+		return nil, err
+	}
+
+	model := Comment{
+		ArtID:     comment.ArtId,
+		AccountID: comment.AuthorId,
+		ParentID:  comment.ParentId,
+		Body:      comment.Body,
+		Status:    dto.CommentVisible,
+	}
+
+	tx := db.db.WithContext(ctx)
+	if err := tx.Select("id").First(&Art{}, comment.ArtId).Error; err != nil {
+		return nil, err
+	} else if err := tx.Select("id").First(&Account{}, comment.AuthorId).Error; err == gorm.ErrRecordNotFound {
+		return nil, ErrAccountNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	query := tx
+	if comment.ParentId == 0 {
+		query = tx.Omit("ParentID")
+	} else {
+		var parent Comment
+		if err := tx.Select("art_id").First(&parent, comment.ParentId).Error; err == gorm.ErrRecordNotFound {
+			return nil, ErrInvalidParent
+		} else if err != nil {
+			return nil, err
+		} else if parent.ArtID != comment.ArtId {
+			return nil, ErrInvalidParent
+		}
+	}
+
+	if err := query.Create(&model).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return model.ToDto(), nil
+}
+
+func (db *CommentDB) GetComment(ctx context.Context, id uint) (*dto.CommentDto, error) {
+	var model Comment
+	if err := db.db.WithContext(ctx).First(&model, id).Error; err != nil {
+		return nil, err
+	} else {
+		return model.ToDto(), nil
+	}
+}
+
+// Gets at most limit comments matching filter whose id is greater than after, ordered by id.
+// A limit of 0 gets every one of them.
+func (db *CommentDB) GetComments(ctx context.Context, filter CommentFilter, after uint, limit int) ([]dto.CommentDto, error) {
+	query := db.db.WithContext(ctx).Where("id > ?", after).Order("id")
+	if filter.ArtId != 0 {
+		query = query.Where("art_id = ?", filter.ArtId)
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var models []Comment
+	if err := query.Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	comments := []dto.CommentDto{}
+	for _, model := range models {
+		comments = append(comments, *model.ToDto())
+	}
+	return comments, nil
+}
+
+// Replaces the body of a comment.
+func (db *CommentDB) UpdateComment(ctx context.Context, id uint, body string) (*dto.CommentDto, error) {
+	if err := ValidateCommentBody(body); err != nil {
+		return nil, err
+	}
+
+	result := db.db.WithContext(ctx).Model(&Comment{}).Where("id = ?", id).Updates(Comment{Body: body})
+	if result.Error != nil {
+		return nil, result.Error
+	} else if result.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return db.GetComment(ctx, id)
+}
+
+// Flags a visible comment for moderators. Reporting a comment that is already reported or hidden changes nothing.
+func (db *CommentDB) ReportComment(ctx context.Context, id uint) (*dto.CommentDto, error) {
+	err := db.db.WithContext(ctx).Model(&Comment{}).
+		Where("id = ? AND status = ?", id, dto.CommentVisible).
+		Update("status", dto.CommentReported).Error
+	if err != nil {
+		return nil, err
+	}
+	return db.GetComment(ctx, id)
+}
+
+// Sets the moderation state of a comment, one of dto.CommentVisible, dto.CommentReported and dto.CommentHidden.
+func (db *CommentDB) SetCommentStatus(ctx context.Context, id uint, status string) (*dto.CommentDto, error) {
+	result := db.db.WithContext(ctx).Model(&Comment{}).Where("id = ?", id).Update("status", status)
+	if result.Error != nil {
+		return nil, result.Error
+	} else if result.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return db.GetComment(ctx, id)
+}
+
+// Deletes a comment along with the replies to it.
+func (db *CommentDB) DeleteComment(ctx context.Context, id uint) (*dto.CommentDto, error) {
+	comment, err := db.GetComment(ctx, id)
+	if err != nil {
+		return nil, err
+	} else if err := db.db.WithContext(ctx).Delete(&Comment{}, id).Error; err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
@@ -0,0 +1,125 @@
+package model_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComments(t *testing.T) {
+	accountDB, gormDB := AccountDBInit(t)
+	artDB := ArtDBInit(t, gormDB)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+	db := model.DB{GormDB: gormDB}
+	var commentDB model.CommentDB
+	require.NoError(t, commentDB.Init(&db))
+	ctx := context.Background()
+
+	account1, art1 := createUserAndArt(t, accountDB, artDB, "username1")
+	account2, art2 := createUserAndArt(t, accountDB, artDB, "username2")
+
+	comment, err := commentDB.CreateComment(ctx, dto.CommentDto{ArtId: art1.Id, AuthorId: account2.Id, Body: "nice"})
+	require.NoError(t, err)
+	assert.Equal(t, dto.CommentVisible, comment.Status)
+	assert.Zero(t, comment.ParentId)
+
+	reply, err := commentDB.CreateComment(ctx, dto.CommentDto{ArtId: art1.Id, AuthorId: account1.Id, ParentId: comment.Id, Body: "thanks"})
+	require.NoError(t, err)
+	other, err := commentDB.CreateComment(ctx, dto.CommentDto{ArtId: art2.Id, AuthorId: account1.Id, Body: "mine"})
+	require.NoError(t, err)
+
+	t.Run("Invalid comments", func(t *testing.T) {
+		for _, test := range []struct {
+			comment dto.CommentDto
+			err     error
+		}{
+			{dto.CommentDto{ArtId: art1.Id, AuthorId: account1.Id, Body: " "}, model.ErrInvalidComment},
+			{dto.CommentDto{ArtId: art1.Id, AuthorId: account1.Id, Body: strings.Repeat("é", model.MaxCommentLength+1)}, model.ErrInvalidComment},
+			{dto.CommentDto{ArtId: 1000, AuthorId: account1.Id, Body: "body"}, model.ErrNotFound},
+			{dto.CommentDto{ArtId: art1.Id, AuthorId: 1000, Body: "body"}, model.ErrAccountNotFound},
+			{dto.CommentDto{ArtId: art1.Id, AuthorId: account1.Id, ParentId: other.Id, Body: "body"}, model.ErrInvalidParent},
+			{dto.CommentDto{ArtId: art1.Id, AuthorId: account1.Id, ParentId: 1000, Body: "body"}, model.ErrInvalidParent},
+		} {
+			_, err := commentDB.CreateComment(ctx, test.comment)
+			assert.ErrorIs(t, err, test.err, test.comment)
+		}
+
+		_, err := commentDB.CreateComment(ctx, dto.CommentDto{ArtId: art1.Id, AuthorId: account1.Id, Body: strings.Repeat("é", model.MaxCommentLength)})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Pages", func(t *testing.T) {
+		comments, err := commentDB.GetComments(ctx, model.CommentFilter{ArtId: art1.Id}, 0, 2)
+		if assert.NoError(t, err) && assert.Len(t, comments, 2) {
+			assert.Equal(t, []uint{comment.Id, reply.Id}, []uint{comments[0].Id, comments[1].Id})
+			assert.Equal(t, comment.Id, comments[1].ParentId)
+		}
+
+		comments, err = commentDB.GetComments(ctx, model.CommentFilter{}, reply.Id, 0)
+		if assert.NoError(t, err) && assert.Len(t, comments, 2) {
+			assert.Equal(t, other.Id, comments[0].Id)
+		}
+	})
+
+	t.Run("Edit", func(t *testing.T) {
+		edited, err := commentDB.UpdateComment(ctx, reply.Id, "thank you")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "thank you", edited.Body)
+			assert.Equal(t, account1.Id, edited.AuthorId)
+		}
+		_, err = commentDB.UpdateComment(ctx, reply.Id, "")
+		assert.ErrorIs(t, err, model.ErrInvalidComment)
+		_, err = commentDB.UpdateComment(ctx, 1000, "body")
+		assert.ErrorIs(t, err, model.ErrNotFound)
+	})
+
+	t.Run("Moderation", func(t *testing.T) {
+		reported, err := commentDB.ReportComment(ctx, other.Id)
+		if assert.NoError(t, err) {
+			assert.Equal(t, dto.CommentReported, reported.Status)
+		}
+
+		comments, err := commentDB.GetComments(ctx, model.CommentFilter{Statuses: []string{dto.CommentReported}}, 0, 0)
+		if assert.NoError(t, err) && assert.Len(t, comments, 1) {
+			assert.Equal(t, other.Id, comments[0].Id)
+		}
+
+		hidden, err := commentDB.SetCommentStatus(ctx, other.Id, dto.CommentHidden)
+		if assert.NoError(t, err) {
+			assert.Equal(t, dto.CommentHidden, hidden.Status)
+		}
+
+		// reporting doesn't bring hidden comments back
+		reported, err = commentDB.ReportComment(ctx, other.Id)
+		if assert.NoError(t, err) {
+			assert.Equal(t, dto.CommentHidden, reported.Status)
+		}
+
+		_, err = commentDB.ReportComment(ctx, 1000)
+		assert.ErrorIs(t, err, model.ErrNotFound)
+		_, err = commentDB.SetCommentStatus(ctx, 1000, dto.CommentHidden)
+		assert.ErrorIs(t, err, model.ErrNotFound)
+	})
+
+	t.Run("Deleting a comment deletes its replies", func(t *testing.T) {
+		deleted, err := commentDB.DeleteComment(ctx, comment.Id)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "nice", deleted.Body)
+		}
+		_, err = commentDB.GetComment(ctx, reply.Id)
+		assert.ErrorIs(t, err, model.ErrNotFound)
+		_, err = commentDB.DeleteComment(ctx, comment.Id)
+		assert.ErrorIs(t, err, model.ErrNotFound)
+
+		_, err = commentDB.GetComment(ctx, other.Id)
+		assert.NoError(t, err)
+	})
+}
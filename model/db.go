@@ -1,9 +1,43 @@
 package model
 
 import (
+	"context"
+	"errors"
+
 	"gorm.io/gorm"
 )
 
 type DB struct {
 	GormDB *gorm.DB
 }
+
+// Checks that the database can be reached.
+func (db *DB) Ping(ctx context.Context) error {
+	if sqlDB, err := db.GormDB.DB(); err != nil {
+		return err
+	} else {
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// Checks that every migration is applied.
+func (db *DB) CheckSchema(ctx context.Context) error {
+	migrator := Migrator{}
+	if err := migrator.Init(db); err != nil {
+		return err
+	}
+	return migrator.CheckCurrent(ctx)
+}
+
+// Checks that SQLite enforces the foreign keys of the schema.
+// SQLite only does so when the connection asks for it, with _foreign_keys=true in the DSN.
+func (db *DB) CheckForeignKeys(ctx context.Context) error {
+	var enabled bool
+	if err := db.GormDB.WithContext(ctx).Raw("PRAGMA foreign_keys").Scan(&enabled).Error; err != nil {
+		return err
+	} else if !enabled {
+		return errors.New("foreign keys are not enforced")
+	} else {
+		return nil
+	}
+}
@@ -0,0 +1,45 @@
+package model_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nafiz1001/gallery-go/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestCheckSchema(t *testing.T) {
+	gormDB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared&_foreign_keys=true"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+	db := model.DB{GormDB: gormDB}
+
+	assert.NoError(t, db.Ping(context.Background()))
+
+	// no migration is applied yet
+	assert.Error(t, db.CheckSchema(context.Background()))
+
+	Migrate(t, &db)
+	assert.NoError(t, db.CheckSchema(context.Background()))
+	assert.NoError(t, db.CheckForeignKeys(context.Background()))
+
+	// the connection doesn't ask for foreign keys
+	withoutForeignKeys, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	defer func() {
+		otherDb, _ := withoutForeignKeys.DB()
+		otherDb.Close()
+	}()
+	assert.Error(t, (&model.DB{GormDB: withoutForeignKeys}).CheckForeignKeys(context.Background()))
+}
@@ -0,0 +1,259 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Version of the format written by DB.Dump.
+const DumpVersion = 1
+
+// Rows read or written at a time by DB.Dump and DB.Load.
+const dumpBatchSize = 500
+
+// Tables of a dump, parents first so that the rows can be loaded in order.
+// Every table of the schema must be listed here for dumps to be complete.
+var dumpTables = []struct {
+	name string
+	// Pointer to an empty slice of the model of the table.
+	rows func() interface{}
+	// Field of a nullable foreign key, which the model reads as zero when NULL.
+	nullable string
+}{
+	{"accounts", func() interface{} { return &[]Account{} }, ""},
+	{"arts", func() interface{} { return &[]Art{} }, "AccountID"},
+	{"art_revisions", func() interface{} { return &[]ArtRevision{} }, ""},
+	{"likes", func() interface{} { return &[]Like{} }, ""},
+	{"comments", func() interface{} { return &[]Comment{} }, "ParentID"},
+	{"follows", func() interface{} { return &[]Follow{} }, ""},
+	{"notifications", func() interface{} { return &[]Notification{} }, "CommentID"},
+	{"webhooks", func() interface{} { return &[]Webhook{} }, ""},
+	{"webhook_deliveries", func() interface{} { return &[]WebhookDelivery{} }, ""},
+	{"art_events", func() interface{} { return &[]ArtEvent{} }, ""},
+	{"audit_entries", func() interface{} { return &[]AuditEntry{} }, ""},
+}
+
+// Header of a dump, followed by the rows of every table.
+type dumpHeader struct {
+	Version int `json:"version"`
+	// Latest migration applied to the database the dump was taken from.
+	SchemaVersion int       `json:"schema_version"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Writes every row of the database, soft-deleted ones included, as a single JSON object.
+// Unlike DB.Backup, a dump doesn't depend on SQLite and can be loaded into any database gorm supports.
+// The rows are read from one transaction, so the dump is consistent, and written as they are read.
+func (db *DB) Dump(ctx context.Context, w io.Writer) error {
+	schemaVersion, err := db.schemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.GormDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		header, err := json.Marshal(dumpHeader{Version: DumpVersion, SchemaVersion: schemaVersion, CreatedAt: time.Now().UTC()})
+		if err != nil {
+			return err
+		}
+		// the header fields are written first, without their closing brace
+		if _, err := w.Write(header[:len(header)-1]); err != nil {
+			return err
+		}
+
+		for _, table := range dumpTables {
+			if _, err := fmt.Fprintf(w, ",\n%q: [", table.name); err != nil {
+				return err
+			}
+
+			count := 0
+			rows := table.rows()
+			err := tx.Unscoped().FindInBatches(rows, dumpBatchSize, func(batch *gorm.DB, _ int) error {
+				slice := reflect.ValueOf(rows).Elem()
+				for i := 0; i < slice.Len(); i++ {
+					row, err := json.Marshal(slice.Index(i).Interface())
+					if err != nil {
+						return err
+					}
+
+					separator := ",\n"
+					if count == 0 {
+						separator = "\n"
+					}
+					count++
+					if _, err := io.WriteString(w, separator); err != nil {
+						return err
+					} else if _, err := w.Write(row); err != nil {
+						return err
+					}
+				}
+				return nil
+			}).Error
+			if err != nil {
+				return err
+			}
+
+			if _, err := io.WriteString(w, "]"); err != nil {
+				return err
+			}
+		}
+
+		_, err = io.WriteString(w, "\n}\n")
+		return err
+	})
+}
+
+// Replaces every row of the database with the rows of a dump written by DB.Dump, in one transaction.
+// The database must be migrated to the schema version the dump was taken from.
+func (db *DB) Load(ctx context.Context, r io.Reader) error {
+	schemaVersion, err := db.schemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	return translateError(db.GormDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// children first, so that no foreign key is left dangling
+		for i := len(dumpTables) - 1; i >= 0; i-- {
+			if err := tx.Exec("DELETE FROM " + dumpTables[i].name).Error; err != nil {
+				return err
+			}
+		}
+
+		decoder := json.NewDecoder(r)
+		if err := expectDelim(decoder, '{'); err != nil {
+			return err
+		}
+
+		var header dumpHeader
+		loaded := map[string]bool{}
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			key, _ := token.(string)
+
+			switch key {
+			case "version":
+				err = decoder.Decode(&header.Version)
+			case "schema_version":
+				err = decoder.Decode(&header.SchemaVersion)
+			case "created_at":
+				err = decoder.Decode(&header.CreatedAt)
+			default:
+				// the header comes first, so it is known by the time rows are
+				if header.Version != DumpVersion {
+					return fmt.Errorf("dump version %d is not supported, expected %d", header.Version, DumpVersion)
+				} else if header.SchemaVersion != schemaVersion {
+					return fmt.Errorf("the dump was taken at schema version %d but the database is at %d", header.SchemaVersion, schemaVersion)
+				} else if loaded[key] {
+					return fmt.Errorf("table %s appears twice in the dump", key)
+				}
+				loaded[key] = true
+				err = loadTable(tx, decoder, key)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, table := range dumpTables {
+			if !loaded[table.name] {
+				return fmt.Errorf("table %s is missing from the dump", table.name)
+			}
+		}
+		return expectDelim(decoder, '}')
+	}))
+}
+
+// Inserts the rows of the table named name, read from a JSON array.
+func loadTable(tx *gorm.DB, decoder *json.Decoder, name string) error {
+	var newRows func() interface{}
+	var nullable string
+	for _, table := range dumpTables {
+		if table.name == name {
+			newRows = table.rows
+			nullable = table.nullable
+		}
+	}
+	if newRows == nil {
+		return fmt.Errorf("unknown table %s in the dump", name)
+	}
+
+	if err := expectDelim(decoder, '['); err != nil {
+		return err
+	}
+
+	insert := func(rows interface{}) error {
+		slice := reflect.ValueOf(rows).Elem()
+		if nullable == "" {
+			if slice.Len() == 0 {
+				return nil
+			}
+			return tx.Omit("Arts").Create(rows).Error
+		}
+
+		// rows whose foreign key is zero are inserted without it, leaving it NULL
+		for i := 0; i < slice.Len(); i++ {
+			row := slice.Index(i).Addr().Interface()
+			query := tx
+			if slice.Index(i).FieldByName(nullable).IsZero() {
+				query = tx.Omit(nullable)
+			}
+			if err := query.Create(row).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	rows := newRows()
+	slice := reflect.ValueOf(rows).Elem()
+	for decoder.More() {
+		row := reflect.New(slice.Type().Elem())
+		if err := decoder.Decode(row.Interface()); err != nil {
+			return fmt.Errorf("table %s: %w", name, err)
+		}
+		slice.Set(reflect.Append(slice, row.Elem()))
+
+		if slice.Len() == dumpBatchSize {
+			if err := insert(rows); err != nil {
+				return err
+			}
+			rows = newRows()
+			slice = reflect.ValueOf(rows).Elem()
+		}
+	}
+	if err := insert(rows); err != nil {
+		return err
+	}
+
+	return expectDelim(decoder, ']')
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	if token, err := decoder.Token(); err != nil {
+		return err
+	} else if token != delim {
+		return fmt.Errorf("expected %s in the dump, found %v", delim, token)
+	} else {
+		return nil
+	}
+}
+
+// The latest migration, which must be applied.
+func (db *DB) schemaVersion(ctx context.Context) (int, error) {
+	migrator := Migrator{}
+	if err := migrator.Init(db); err != nil {
+		return 0, err
+	} else if err := migrator.CheckCurrent(ctx); err != nil {
+		return 0, err
+	} else {
+		return migrator.Latest(), nil
+	}
+}
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+)
+
+// Returned when a record doesn't exist.
+var ErrNotFound = gorm.ErrRecordNotFound
+
+// Returned when an art refers to an account that doesn't exist.
+var ErrAccountNotFound = fmt.Errorf("account %w", ErrNotFound)
+
+// Returned when a record refers to another one that doesn't exist, such as a reply to a comment deleted meanwhile.
+// SQLite doesn't tell which foreign key failed, so callers that know return a more specific error instead.
+var ErrReferenceNotFound = fmt.Errorf("referenced record %w", ErrNotFound)
+
+// Returned when another account already has the username.
+var ErrUsernameTaken = errors.New("username already exists")
+
+// Returned when the quantity of an art is negative.
+var ErrInvalidQuantity = errors.New("quantity must not be negative")
+
+// Returned when a sale is for fewer than one copy of an art.
+var ErrInvalidSale = errors.New("quantity sold must be positive")
+
+// Returned when an art has fewer copies left than a sale is for.
+var ErrOutOfStock = errors.New("not enough copies of the art are left")
+
+// Returned when the body of a comment is empty or longer than MaxCommentLength.
+var ErrInvalidComment = fmt.Errorf("comment must have between 1 and %d characters", MaxCommentLength)
+
+// Returned when an account tries to follow itself.
+var ErrSelfFollow = errors.New("accounts can't follow themselves")
+
+// Returned when a feed cursor wasn't returned by the feed of the same repository.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Returned when a reply refers to a comment that isn't on the same art.
+var ErrInvalidParent = errors.New("parent comment is not on the art")
+
+// Returned when a webhook has no http or https URL, no secret or no known type of event.
+var ErrInvalidWebhook = errors.New("webhook must have an http or https URL, a secret and known event types")
+
+// Replaces a constraint violation reported by SQLite with the matching domain error.
+// Other errors are returned as they are.
+func translateError(err error) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+
+	switch sqliteErr.ExtendedCode {
+	case sqlite3.ErrConstraintUnique:
+		if strings.Contains(sqliteErr.Error(), "accounts.username") {
+			return ErrUsernameTaken
+		}
+	case sqlite3.ErrConstraintForeignKey:
+		return ErrReferenceNotFound
+	case sqlite3.ErrConstraintCheck:
+		if strings.Contains(sqliteErr.Error(), "chk_arts_quantity") {
+			return ErrInvalidQuantity
+		}
+	}
+	return err
+}
@@ -0,0 +1,43 @@
+package model_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstraints(t *testing.T) {
+	accountDB, gormDB := AccountDBInit(t)
+	artDB := ArtDBInit(t, gormDB)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+
+	account, art := createUserAndArt(t, accountDB, artDB, "username")
+
+	// negative quantities
+	_, err := artDB.CreateArt(context.Background(), dto.ArtDto{Quantity: -1, Title: "title", AuthorId: account.Id})
+	assert.ErrorIs(t, err, model.ErrInvalidQuantity)
+	_, err = artDB.UpdateArt(context.Background(), dto.ArtDto{Id: art.Id, Quantity: -1, AuthorId: account.Id})
+	assert.ErrorIs(t, err, model.ErrInvalidQuantity)
+
+	// arts of missing accounts
+	_, err = artDB.CreateArt(context.Background(), dto.ArtDto{Quantity: 1, Title: "title", AuthorId: account.Id + 1})
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	err = gormDB.Exec("UPDATE arts SET account_id = ? WHERE id = ?", account.Id+1, art.Id).Error
+	assert.Error(t, err)
+
+	// accounts that still have arts
+	err = gormDB.Exec("DELETE FROM accounts WHERE id = ?", account.Id).Error
+	assert.Error(t, err)
+
+	// failed statements change nothing
+	current, err := artDB.GetArt(context.Background(), art.Id)
+	if assert.NoError(t, err) {
+		assert.Equal(t, art, *current)
+	}
+}
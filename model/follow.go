@@ -0,0 +1,205 @@
+package model
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"gorm.io/gorm"
+)
+
+// Followed accounts whose newest arts are read by one query of a feed, each by a bounded query of its own.
+// SQLite allows at most 500 terms in a compound SELECT and 999 variables in a statement.
+const feedFolloweesPerQuery = 100
+
+type FollowDB struct {
+	db *gorm.DB
+}
+
+// An account following the arts of another.
+type Follow struct {
+	ID         uint `gorm:"primarykey"`
+	CreatedAt  time.Time
+	FollowerID uint
+	FolloweeID uint
+}
+
+// Position in a feed, right after the last art of a page.
+type feedCursor struct {
+	// Time the art was last updated, as SQLite stores it.
+	UpdatedAt string `json:"t"`
+	Id        uint   `json:"id"`
+}
+
+func (c feedCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeFeedCursor(s string) (feedCursor, error) {
+	var c feedCursor
+	if data, err := base64.RawURLEncoding.DecodeString(s); err != nil {
+		return c, ErrInvalidCursor
+	} else if err := json.Unmarshal(data, &c); err != nil || c.UpdatedAt == "" {
+		return c, ErrInvalidCursor
+	} else {
+		return c, nil
+	}
+}
+
+// The tables are created by the migrations, see Migrator.
+func (db *FollowDB) Init(database *DB) error {
+	db.db = database.GormDB
+	return nil
+}
+
+// Makes an account follow an existing account. Following an account again changes nothing.
+func (db *FollowDB) Follow(ctx context.Context, followerId uint, followeeId uint) error {
+	if followerId == followeeId {
+		return ErrSelfFollow
+	}
+
+	result := db.db.WithContext(ctx).Exec(
+		"INSERT INTO follows (created_at, follower_id, followee_id) SELECT ?, ?, id FROM accounts WHERE id = ? AND deleted_at IS NULL "+
+			"ON CONFLICT (follower_id, followee_id) DO NOTHING",
+		time.Now(), followerId, followeeId)
+	if err := translateError(result.Error); errors.Is(err, ErrReferenceNotFound) {
+		// the statement only inserts follows of existing accounts
+		return ErrAccountNotFound
+	} else if err != nil {
+		return err
+	} else if result.RowsAffected == 0 {
+		// either the account doesn't exist or it is already followed
+		return db.db.WithContext(ctx).Select("id").First(&Account{}, followeeId).Error
+	} else {
+		return nil
+	}
+}
+
+// Makes an account stop following another. Unfollowing an account that isn't followed changes nothing.
+func (db *FollowDB) Unfollow(ctx context.Context, followerId uint, followeeId uint) error {
+	return db.db.WithContext(ctx).Where("follower_id = ? AND followee_id = ?", followerId, followeeId).Delete(&Follow{}).Error
+}
+
+// Gets the accounts following an account, most recent followers first.
+func (db *FollowDB) GetFollowers(ctx context.Context, accountId uint) ([]dto.AccountDto, error) {
+	return db.getAccounts(ctx, "follows.follower_id", "follows.followee_id = ?", accountId)
+}
+
+// Gets the accounts an account follows, most recently followed first.
+func (db *FollowDB) GetFollowing(ctx context.Context, accountId uint) ([]dto.AccountDto, error) {
+	return db.getAccounts(ctx, "follows.followee_id", "follows.follower_id = ?", accountId)
+}
+
+func (db *FollowDB) getAccounts(ctx context.Context, joined string, where string, accountId uint) ([]dto.AccountDto, error) {
+	var models []Account
+	err := db.db.WithContext(ctx).
+		Joins("JOIN follows ON "+joined+" = accounts.id").
+		Where(where, accountId).
+		Order("follows.id DESC").
+		Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := []dto.AccountDto{}
+	for _, model := range models {
+		accounts = append(accounts, *model.ToDto())
+	}
+	return accounts, nil
+}
+
+// Gets at most limit arts of the accounts an account follows, most recently created or updated first.
+// The page starts after cursor, or at the newest art if it is empty, and the cursor of the next page is returned along with it.
+// The next cursor is empty once the feed has been read to the end.
+func (db *FollowDB) GetFeed(ctx context.Context, accountId uint, cursor string, limit int) ([]dto.ArtDto, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("feed limit must be positive, got %d", limit)
+	}
+
+	var after *feedCursor
+	if cursor != "" {
+		decoded, err := decodeFeedCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		after = &decoded
+	}
+
+	var followees []uint
+	if err := db.db.WithContext(ctx).Model(&Follow{}).Where("follower_id = ?", accountId).Order("followee_id").Pluck("followee_id", &followees).Error; err != nil {
+		return nil, "", err
+	}
+
+	// one more art than asked tells whether there is a next page
+	rows := []feedRow{}
+	for start := 0; start < len(followees); start += feedFolloweesPerQuery {
+		end := start + feedFolloweesPerQuery
+		if end > len(followees) {
+			end = len(followees)
+		}
+		page, err := db.feedPage(ctx, followees[start:end], after, limit+1)
+		if err != nil {
+			return nil, "", err
+		}
+		rows = append(rows, page...)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].FeedAt != rows[j].FeedAt {
+			return rows[i].FeedAt > rows[j].FeedAt
+		}
+		return rows[i].ID > rows[j].ID
+	})
+
+	arts := []dto.ArtDto{}
+	next := ""
+	for i, row := range rows {
+		if i == limit {
+			last := rows[i-1]
+			next = feedCursor{UpdatedAt: last.FeedAt, Id: last.ID}.encode()
+			break
+		}
+		arts = append(arts, *row.ToDto())
+	}
+	return arts, next, nil
+}
+
+// An art of a feed along with the time it was last updated, as SQLite stores it.
+type feedRow struct {
+	Art
+	FeedAt string
+}
+
+// Gets at most limit of the newest arts of followees after cursor, if any, newest first.
+// Each followee gets a query of its own, bounded by limit and read along idx_arts_account_id_updated_at, and the
+// queries are merged, so that only the arts that may be returned are read.
+func (db *FollowDB) feedPage(ctx context.Context, followees []uint, after *feedCursor, limit int) ([]feedRow, error) {
+	queries := []string{}
+	args := []interface{}{}
+	for _, followee := range followees {
+		query := "SELECT * FROM (SELECT arts.*, CAST(arts.updated_at AS TEXT) AS feed_at FROM arts " +
+			"WHERE arts.account_id = ? AND arts.deleted_at IS NULL"
+		args = append(args, followee)
+		if after != nil {
+			// compared as a row, so that the query starts at the cursor in the index rather than reading up to it
+			query += " AND (arts.updated_at, arts.id) < (?, ?)"
+			args = append(args, after.UpdatedAt, after.Id)
+		}
+		queries = append(queries, query+" ORDER BY arts.updated_at DESC, arts.id DESC LIMIT ?)")
+		args = append(args, limit)
+	}
+	args = append(args, limit)
+
+	var rows []feedRow
+	sql := strings.Join(queries, " UNION ALL ") + " ORDER BY updated_at DESC, id DESC LIMIT ?"
+	if err := db.db.WithContext(ctx).Raw(sql, args...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,131 @@
+package model_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFollows(t *testing.T) {
+	accountDB, gormDB := AccountDBInit(t)
+	artDB := ArtDBInit(t, gormDB)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+	db := model.DB{GormDB: gormDB}
+	var followDB model.FollowDB
+	require.NoError(t, followDB.Init(&db))
+	ctx := context.Background()
+
+	fan, _ := createUserAndArt(t, accountDB, artDB, "fan")
+	artist1, _ := createUserAndArt(t, accountDB, artDB, "artist1")
+	artist2, _ := createUserAndArt(t, accountDB, artDB, "artist2")
+
+	// following twice counts once
+	assert.NoError(t, followDB.Follow(ctx, fan.Id, artist1.Id))
+	assert.NoError(t, followDB.Follow(ctx, fan.Id, artist1.Id))
+	assert.NoError(t, followDB.Follow(ctx, fan.Id, artist2.Id))
+	assert.NoError(t, followDB.Follow(ctx, artist2.Id, artist1.Id))
+
+	following, err := followDB.GetFollowing(ctx, fan.Id)
+	if assert.NoError(t, err) && assert.Len(t, following, 2) {
+		assert.Equal(t, []string{"artist2", "artist1"}, []string{following[0].Username, following[1].Username})
+	}
+	followers, err := followDB.GetFollowers(ctx, artist1.Id)
+	if assert.NoError(t, err) && assert.Len(t, followers, 2) {
+		assert.Equal(t, []string{"artist2", "fan"}, []string{followers[0].Username, followers[1].Username})
+	}
+
+	assert.ErrorIs(t, followDB.Follow(ctx, fan.Id, fan.Id), model.ErrSelfFollow)
+	assert.ErrorIs(t, followDB.Follow(ctx, fan.Id, 1000), model.ErrNotFound)
+
+	// unfollowing twice is harmless
+	assert.NoError(t, followDB.Unfollow(ctx, artist2.Id, artist1.Id))
+	assert.NoError(t, followDB.Unfollow(ctx, artist2.Id, artist1.Id))
+	followers, err = followDB.GetFollowers(ctx, artist1.Id)
+	if assert.NoError(t, err) {
+		assert.Len(t, followers, 1)
+	}
+}
+
+func TestFeed(t *testing.T) {
+	accountDB, gormDB := AccountDBInit(t)
+	artDB := ArtDBInit(t, gormDB)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+	db := model.DB{GormDB: gormDB}
+	var followDB model.FollowDB
+	require.NoError(t, followDB.Init(&db))
+	ctx := context.Background()
+
+	fan, _ := createUserAndArt(t, accountDB, artDB, "fan")
+	artist1, art1 := createUserAndArt(t, accountDB, artDB, "artist1")
+	artist2, art2 := createUserAndArt(t, accountDB, artDB, "artist2")
+	_, unfollowed := createUserAndArt(t, accountDB, artDB, "unfollowed")
+	require.NoError(t, followDB.Follow(ctx, fan.Id, artist1.Id))
+	require.NoError(t, followDB.Follow(ctx, fan.Id, artist2.Id))
+
+	art3, err := artDB.CreateArt(ctx, dto.ArtDto{Title: "title3", Quantity: 1, AuthorId: artist2.Id})
+	require.NoError(t, err)
+	deleted, err := artDB.CreateArt(ctx, dto.ArtDto{Title: "deleted", Quantity: 1, AuthorId: artist2.Id})
+	require.NoError(t, err)
+	_, err = artDB.DeleteArt(ctx, deleted.Id)
+	require.NoError(t, err)
+	_, err = artDB.UpdateArt(ctx, dto.ArtDto{Id: unfollowed.Id, Title: "ignored", AuthorId: unfollowed.AuthorId})
+	require.NoError(t, err)
+	// updating an art brings it to the top of the feed
+	_, err = artDB.UpdateArt(ctx, dto.ArtDto{Id: art1.Id, Title: "updated", AuthorId: artist1.Id})
+	require.NoError(t, err)
+
+	readFeed := func() []uint {
+		ids := []uint{}
+		cursor := ""
+		for pages := 0; pages == 0 || cursor != ""; pages++ {
+			require.Less(t, pages, 3)
+
+			var arts []dto.ArtDto
+			arts, cursor, err = followDB.GetFeed(ctx, fan.Id, cursor, 2)
+			require.NoError(t, err)
+			for _, art := range arts {
+				ids = append(ids, art.Id)
+			}
+		}
+		return ids
+	}
+	assert.Equal(t, []uint{art1.Id, art3.Id, art2.Id}, readFeed())
+
+	// accounts following many others get the same feed
+	for i := 0; i < 100; i++ {
+		account, err := accountDB.CreateAccount(ctx, dto.AccountDto{Username: fmt.Sprint("other", i), Password: "password"})
+		require.NoError(t, err)
+		require.NoError(t, followDB.Follow(ctx, fan.Id, account.Id))
+	}
+	assert.Equal(t, []uint{art1.Id, art3.Id, art2.Id}, readFeed())
+
+	// a sparse feed, whose arts are few among many newer ones and split across the queries of the followees
+	late, lateArt := createUserAndArt(t, accountDB, artDB, "late")
+	require.NoError(t, followDB.Follow(ctx, fan.Id, late.Id))
+	for i := 0; i < 20; i++ {
+		_, err := artDB.CreateArt(ctx, dto.ArtDto{Title: fmt.Sprint("unfollowed", i), Quantity: 1, AuthorId: unfollowed.AuthorId})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []uint{lateArt.Id, art1.Id, art3.Id, art2.Id}, readFeed())
+
+	// an account following nobody has an empty feed
+	arts, next, err := followDB.GetFeed(ctx, artist1.Id, "", 10)
+	if assert.NoError(t, err) {
+		assert.Empty(t, arts)
+		assert.Empty(t, next)
+	}
+
+	_, _, err = followDB.GetFeed(ctx, fan.Id, "not a cursor", 10)
+	assert.ErrorIs(t, err, model.ErrInvalidCursor)
+}
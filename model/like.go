@@ -0,0 +1,104 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"gorm.io/gorm"
+)
+
+type LikeDB struct {
+	db *gorm.DB
+}
+
+// An art liked by an account.
+type Like struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	AccountID uint
+	ArtID     uint
+}
+
+// The tables are created by the migrations, see Migrator.
+func (db *LikeDB) Init(database *DB) error {
+	db.db = database.GormDB
+	return nil
+}
+
+// Likes an existing art on behalf of an account and reports whether it wasn't liked already. Liking an art again changes nothing.
+// The like is a single statement, so concurrent likes neither fail nor count twice.
+func (db *LikeDB) LikeArt(ctx context.Context, accountId uint, artId uint) (bool, error) {
+	result := db.db.WithContext(ctx).Exec(
+		"INSERT INTO likes (created_at, account_id, art_id) SELECT ?, ?, id FROM arts WHERE id = ? AND deleted_at IS NULL "+
+			"ON CONFLICT (account_id, art_id) DO NOTHING",
+		time.Now(), accountId, artId)
+	if err := translateError(result.Error); errors.Is(err, ErrReferenceNotFound) {
+		// the statement only inserts likes of existing arts
+		return false, ErrAccountNotFound
+	} else if err != nil {
+		return false, err
+	} else if result.RowsAffected == 0 {
+		// either the art doesn't exist or it is already liked
+		return false, db.db.WithContext(ctx).Select("id").First(&Art{}, artId).Error
+	} else {
+		return true, nil
+	}
+}
+
+// Takes back the like of an account. Unliking an art that isn't liked changes nothing.
+func (db *LikeDB) UnlikeArt(ctx context.Context, accountId uint, artId uint) error {
+	return db.db.WithContext(ctx).Where("account_id = ? AND art_id = ?", accountId, artId).Delete(&Like{}).Error
+}
+
+// Gets the number of likes of each art and whether the account liked it.
+// Arts without likes are left out, and so is whether they are liked if accountId is zero.
+func (db *LikeDB) GetLikes(ctx context.Context, accountId uint, artIds []uint) (map[uint]int64, map[uint]bool, error) {
+	counts := map[uint]int64{}
+	liked := map[uint]bool{}
+	if len(artIds) == 0 {
+		return counts, liked, nil
+	}
+
+	var rows []struct {
+		ArtID uint
+		Count int64
+		Liked bool
+	}
+	err := db.db.WithContext(ctx).Model(&Like{}).
+		Select("art_id, COUNT(*) AS count, MAX(account_id = ?) AS liked", accountId).
+		Where("art_id IN ?", artIds).
+		Group("art_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, row := range rows {
+		counts[row.ArtID] = row.Count
+		if row.Liked {
+			liked[row.ArtID] = true
+		}
+	}
+	return counts, liked, nil
+}
+
+// Gets the arts an account likes, most recently liked first. Deleted arts are left out.
+func (db *LikeDB) GetLikedArts(ctx context.Context, accountId uint) ([]dto.ArtDto, error) {
+	var models []Art
+	err := db.db.WithContext(ctx).
+		Joins("JOIN likes ON likes.art_id = arts.id").
+		Where("likes.account_id = ?", accountId).
+		Order("likes.id DESC").
+		Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+
+	arts := []dto.ArtDto{}
+	for _, model := range models {
+		arts = append(arts, *model.ToDto())
+	}
+	return arts, nil
+}
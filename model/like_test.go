@@ -0,0 +1,125 @@
+package model_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLikes(t *testing.T) {
+	accountDB, gormDB := AccountDBInit(t)
+	artDB := ArtDBInit(t, gormDB)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+	db := model.DB{GormDB: gormDB}
+	var likeDB model.LikeDB
+	require.NoError(t, likeDB.Init(&db))
+	ctx := context.Background()
+
+	account1, art1 := createUserAndArt(t, accountDB, artDB, "username1")
+	account2, art2 := createUserAndArt(t, accountDB, artDB, "username2")
+
+	// liking twice counts once
+	for _, like := range []struct {
+		accountId uint
+		artId     uint
+		new       bool
+	}{{account1.Id, art1.Id, true}, {account1.Id, art1.Id, false}, {account2.Id, art1.Id, true}, {account1.Id, art2.Id, true}} {
+		liked, err := likeDB.LikeArt(ctx, like.accountId, like.artId)
+		assert.NoError(t, err)
+		assert.Equal(t, like.new, liked)
+	}
+
+	counts, liked, err := likeDB.GetLikes(ctx, account2.Id, []uint{art1.Id, art2.Id})
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[uint]int64{art1.Id: 2, art2.Id: 1}, counts)
+		assert.Equal(t, map[uint]bool{art1.Id: true}, liked)
+	}
+
+	// most recently liked first
+	arts, err := likeDB.GetLikedArts(ctx, account1.Id)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []dto.ArtDto{art2, art1}, arts)
+	}
+
+	// unliking twice is harmless
+	assert.NoError(t, likeDB.UnlikeArt(ctx, account2.Id, art1.Id))
+	assert.NoError(t, likeDB.UnlikeArt(ctx, account2.Id, art1.Id))
+	counts, liked, err = likeDB.GetLikes(ctx, account2.Id, []uint{art1.Id})
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[uint]int64{art1.Id: 1}, counts)
+		assert.Empty(t, liked)
+	}
+
+	// missing and deleted arts can't be liked, and deleted arts are no longer listed
+	_, err = likeDB.LikeArt(ctx, account1.Id, 1000)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	_, err = artDB.DeleteArt(ctx, art2.Id)
+	require.NoError(t, err)
+	_, err = likeDB.LikeArt(ctx, account2.Id, art2.Id)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	arts, err = likeDB.GetLikedArts(ctx, account1.Id)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []dto.ArtDto{art1}, arts)
+	}
+}
+
+func TestConcurrentLikes(t *testing.T) {
+	db := FileDBInit(t, filepath.Join(t.TempDir(), "gallery.db"), true)
+	repos, err := db.Repositories()
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	accounts := []*dto.AccountDto{}
+	for i := 0; i < 10; i++ {
+		account, err := repos.Accounts.CreateAccount(ctx, dto.AccountDto{Username: fmt.Sprint("username", i), Password: "password"})
+		require.NoError(t, err)
+		accounts = append(accounts, account)
+	}
+	art, err := repos.Arts.CreateArt(ctx, dto.ArtDto{Title: "title", Quantity: 1, AuthorId: accounts[0].Id})
+	require.NoError(t, err)
+
+	// every account likes the art several times at once
+	var wg sync.WaitGroup
+	errs := make(chan error, len(accounts)*5)
+	news := make(chan bool, len(accounts)*5)
+	for _, account := range accounts {
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func(accountId uint) {
+				defer wg.Done()
+				liked, err := repos.Likes.LikeArt(ctx, accountId, art.Id)
+				errs <- err
+				news <- liked
+			}(account.Id)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	close(news)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	// only one of the likes of each account is new
+	newLikes := 0
+	for liked := range news {
+		if liked {
+			newLikes++
+		}
+	}
+	assert.Equal(t, len(accounts), newLikes)
+
+	counts, _, err := repos.Likes.GetLikes(ctx, 0, []uint{art.Id})
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(len(accounts)), counts[art.Id])
+	}
+}
@@ -0,0 +1,99 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type requestIDKey struct{}
+
+// Returns a copy of ctx carrying the id of the request it belongs to.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// Gets the id of the request ctx belongs to, or an empty string if there is none.
+func RequestID(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// A gorm logger writing one JSON object per line, tagged with the request id found in the statement context.
+// Errors are logged at logger.Error, slow queries at logger.Warn and every query at logger.Info.
+type Logger struct {
+	Writer        io.Writer
+	Level         logger.LogLevel
+	SlowThreshold time.Duration
+}
+
+type logEntry struct {
+	Time      time.Time `json:"time"`
+	Level     string    `json:"level"`
+	RequestID string    `json:"request_id,omitempty"`
+	Message   string    `json:"msg,omitempty"`
+	SQL       string    `json:"sql,omitempty"`
+	Rows      *int64    `json:"rows,omitempty"`
+	ElapsedMs *float64  `json:"elapsed_ms,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+func (l *Logger) LogMode(level logger.LogLevel) logger.Interface {
+	copy := *l
+	copy.Level = level
+	return &copy
+}
+
+func (l *Logger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.Level >= logger.Info {
+		l.write(logEntry{Level: "info", RequestID: RequestID(ctx), Message: fmt.Sprintf(msg, data...)})
+	}
+}
+
+func (l *Logger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.Level >= logger.Warn {
+		l.write(logEntry{Level: "warn", RequestID: RequestID(ctx), Message: fmt.Sprintf(msg, data...)})
+	}
+}
+
+func (l *Logger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.Level >= logger.Error {
+		l.write(logEntry{Level: "error", RequestID: RequestID(ctx), Message: fmt.Sprintf(msg, data...)})
+	}
+}
+
+func (l *Logger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.Level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	elapsedMs := float64(elapsed.Microseconds()) / 1000
+
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.Level >= logger.Error:
+		sql, rows := fc()
+		l.write(logEntry{Level: "error", RequestID: RequestID(ctx), SQL: sql, Rows: &rows, ElapsedMs: &elapsedMs, Error: err.Error()})
+	case l.SlowThreshold > 0 && elapsed > l.SlowThreshold && l.Level >= logger.Warn:
+		sql, rows := fc()
+		l.write(logEntry{Level: "warn", RequestID: RequestID(ctx), Message: "slow query", SQL: sql, Rows: &rows, ElapsedMs: &elapsedMs})
+	case l.Level >= logger.Info:
+		sql, rows := fc()
+		l.write(logEntry{Level: "info", RequestID: RequestID(ctx), SQL: sql, Rows: &rows, ElapsedMs: &elapsedMs})
+	}
+}
+
+func (l *Logger) write(entry logEntry) {
+	entry.Time = time.Now().UTC()
+	if b, err := json.Marshal(entry); err == nil {
+		l.Writer.Write(append(b, '\n'))
+	}
+}
@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+type AccountDB struct {
+	db *DB
+}
+
+var _ model.AccountRepository = &AccountDB{}
+
+func (db *AccountDB) Init(database *DB) error {
+	db.db = database
+	return nil
+}
+
+func (db *AccountDB) CreateAccount(ctx context.Context, account dto.AccountDto) (*dto.AccountDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for _, other := range db.db.accounts {
+		if other.Username == account.Username {
+			return nil, model.ErrUsernameTaken
+		}
+	}
+
+	db.db.lastAccountId++
+	account.Id = db.db.lastAccountId
+	account.Admin = false
+	db.db.accounts[account.Id] = account
+	return &account, nil
+}
+
+func (db *AccountDB) GetAccountById(ctx context.Context, id uint) (*dto.AccountDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if account, ok := db.db.accounts[id]; !ok {
+		return nil, model.ErrNotFound
+	} else {
+		return &account, nil
+	}
+}
+
+func (db *AccountDB) GetAccountByUsername(ctx context.Context, username string) (*dto.AccountDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for _, account := range db.db.accounts {
+		if account.Username == username {
+			return &account, nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+
+func (db *AccountDB) SetAdmin(ctx context.Context, id uint, admin bool) (*dto.AccountDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if account, ok := db.db.accounts[id]; !ok {
+		return nil, model.ErrNotFound
+	} else {
+		account.Admin = admin
+		db.db.accounts[id] = account
+		return &account, nil
+	}
+}
+
+func (db *AccountDB) CountAccounts(ctx context.Context) (int64, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	return int64(len(db.db.accounts)), ctx.Err()
+}
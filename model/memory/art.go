@@ -0,0 +1,292 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+type ArtDB struct {
+	db *DB
+}
+
+var _ model.ArtRepository = &ArtDB{}
+
+func (db *ArtDB) Init(database *DB) error {
+	db.db = database
+	return nil
+}
+
+func (db *ArtDB) CreateArt(ctx context.Context, art dto.ArtDto) (*dto.ArtDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if _, ok := db.db.accounts[art.AuthorId]; !ok {
+		return nil, model.ErrNotFound
+	} else if art.Quantity < 0 {
+		return nil, model.ErrInvalidQuantity
+	}
+
+	db.db.lastArtId++
+	art.Id = db.db.lastArtId
+	db.db.arts[art.Id] = art
+	db.touchArt(art.Id)
+	return &art, nil
+}
+
+func (db *ArtDB) GetArt(ctx context.Context, id uint) (*dto.ArtDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	return db.getArt(ctx, id)
+}
+
+func (db *ArtDB) GetArts(ctx context.Context) ([]dto.ArtDto, error) {
+	return db.GetArtsAfter(ctx, 0, 0)
+}
+
+func (db *ArtDB) GetArtsAfter(ctx context.Context, after uint, limit int) ([]dto.ArtDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return []dto.ArtDto{}, err
+	}
+
+	arts := []dto.ArtDto{}
+	for _, art := range db.db.arts {
+		if art.Id > after {
+			arts = append(arts, art)
+		}
+	}
+	sort.Slice(arts, func(i, j int) bool { return arts[i].Id < arts[j].Id })
+	if limit > 0 && len(arts) > limit {
+		arts = arts[:limit]
+	}
+	return arts, nil
+}
+
+// Calls each with a copy of the matching arts, taken before the first call.
+func (db *ArtDB) ExportArts(ctx context.Context, filter model.ArtFilter, each func(dto.ArtExportDto) error) error {
+	arts, err := db.exportArts(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	for _, art := range arts {
+		if err := ctx.Err(); err != nil {
+			return err
+		} else if err := each(art); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *ArtDB) exportArts(ctx context.Context, filter model.ArtFilter) ([]dto.ArtExportDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	arts := []dto.ArtExportDto{}
+	for _, art := range db.db.arts {
+		if filter.AuthorId != 0 && art.AuthorId != filter.AuthorId {
+			continue
+		} else if filter.Title != "" && !strings.Contains(strings.ToLower(art.Title), strings.ToLower(filter.Title)) {
+			continue
+		}
+		art.LikeCount = int64(len(db.db.likes[art.Id]))
+		arts = append(arts, dto.ArtExportDto{ArtDto: art, AuthorUsername: db.db.accounts[art.AuthorId].Username})
+	}
+	sort.Slice(arts, func(i, j int) bool { return arts[i].Id < arts[j].Id })
+	return arts, nil
+}
+
+// Updates an existing art after storing its current state as a revision.
+// Like the database-backed ArtDB, zero values in art leave the stored fields untouched.
+func (db *ArtDB) UpdateArt(ctx context.Context, art dto.ArtDto) (*dto.ArtDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if _, ok := db.db.accounts[art.AuthorId]; !ok {
+		return nil, model.ErrNotFound
+	} else if art.Quantity < 0 {
+		return nil, model.ErrInvalidQuantity
+	}
+	current, err := db.getArt(ctx, art.Id)
+	if err != nil {
+		return nil, err
+	}
+	db.snapshotArt(*current)
+
+	updated := *current
+	if art.Quantity != 0 {
+		updated.Quantity = art.Quantity
+	}
+	if art.Title != "" {
+		updated.Title = art.Title
+	}
+	updated.AuthorId = art.AuthorId
+	db.db.arts[art.Id] = updated
+	db.touchArt(art.Id)
+
+	return &updated, nil
+}
+
+// Takes quantity copies of an art out of its stock and returns the art as it is left.
+func (db *ArtDB) SellArt(ctx context.Context, id uint, quantity int) (*dto.ArtDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if quantity <= 0 {
+		return nil, model.ErrInvalidSale
+	}
+	art, err := db.getArt(ctx, id)
+	if err != nil {
+		return nil, err
+	} else if art.Quantity < quantity {
+		return nil, model.ErrOutOfStock
+	}
+
+	art.Quantity -= quantity
+	db.db.arts[id] = *art
+	db.touchArt(id)
+	return art, nil
+}
+
+func (db *ArtDB) DeleteArt(ctx context.Context, id uint) (*dto.ArtDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if art, err := db.getArt(ctx, id); err != nil {
+		return nil, err
+	} else {
+		delete(db.db.arts, id)
+		return art, nil
+	}
+}
+
+func (db *ArtDB) CountArts(ctx context.Context) (int64, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	return int64(len(db.db.arts)), ctx.Err()
+}
+
+func (db *ArtDB) GetArtRevisions(ctx context.Context, artId uint) ([]dto.ArtRevisionDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if _, err := db.getArt(ctx, artId); err != nil {
+		return []dto.ArtRevisionDto{}, err
+	}
+	return append([]dto.ArtRevisionDto{}, db.db.revisions[artId]...), nil
+}
+
+func (db *ArtDB) GetArtRevision(ctx context.Context, artId uint, number uint) (*dto.ArtRevisionDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	return db.getArtRevision(ctx, artId, number)
+}
+
+func (db *ArtDB) DiffArtRevisions(ctx context.Context, artId uint, from uint, to uint) (map[string]dto.FieldChange, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	fromArt, err := db.getArtRevisionOrCurrent(ctx, artId, from)
+	if err != nil {
+		return nil, err
+	}
+	toArt, err := db.getArtRevisionOrCurrent(ctx, artId, to)
+	if err != nil {
+		return nil, err
+	}
+
+	fromJSON, err := json.Marshal(fromArt)
+	if err != nil {
+		return nil, err
+	}
+	toJSON, err := json.Marshal(toArt)
+	if err != nil {
+		return nil, err
+	}
+
+	return dto.DiffJSON(fromJSON, toJSON)
+}
+
+func (db *ArtDB) RestoreArtRevision(ctx context.Context, artId uint, number uint) (*dto.ArtDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	revision, err := db.getArtRevision(ctx, artId, number)
+	if err != nil {
+		return nil, err
+	}
+
+	current := db.db.arts[artId]
+	db.snapshotArt(current)
+	current.Title = revision.Art.Title
+	current.Quantity = revision.Art.Quantity
+	db.db.arts[artId] = current
+	db.touchArt(artId)
+
+	return &current, nil
+}
+
+// Marks the art as the most recently created or updated one, like updated_at does in the database.
+func (db *ArtDB) touchArt(id uint) {
+	db.db.lastArtVersion++
+	db.db.artVersions[id] = db.db.lastArtVersion
+}
+
+func (db *ArtDB) getArt(ctx context.Context, id uint) (*dto.ArtDto, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if art, ok := db.db.arts[id]; !ok {
+		return nil, model.ErrNotFound
+	} else {
+		return &art, nil
+	}
+}
+
+func (db *ArtDB) getArtRevision(ctx context.Context, artId uint, number uint) (*dto.ArtRevisionDto, error) {
+	if _, err := db.getArt(ctx, artId); err != nil {
+		return nil, err
+	}
+	for _, revision := range db.db.revisions[artId] {
+		if revision.Number == number {
+			return &revision, nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+
+func (db *ArtDB) getArtRevisionOrCurrent(ctx context.Context, artId uint, number uint) (*dto.ArtDto, error) {
+	if number == 0 {
+		return db.getArt(ctx, artId)
+	} else if revision, err := db.getArtRevision(ctx, artId, number); err != nil {
+		return nil, err
+	} else {
+		return &revision.Art, nil
+	}
+}
+
+func (db *ArtDB) snapshotArt(art dto.ArtDto) {
+	revisions := db.db.revisions[art.Id]
+	db.db.revisions[art.Id] = append(revisions, dto.ArtRevisionDto{
+		Number:    uint(len(revisions) + 1),
+		CreatedAt: time.Now(),
+		Art:       art,
+	})
+}
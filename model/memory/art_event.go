@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+type ArtEventDB struct {
+	db *DB
+}
+
+var _ model.ArtEventRepository = &ArtEventDB{}
+
+func (db *ArtEventDB) Init(database *DB) error {
+	db.db = database
+	return nil
+}
+
+func (db *ArtEventDB) AppendArtEvent(ctx context.Context, event dto.ArtEventDto) (*dto.ArtEventDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.db.lastArtEventId++
+	event.Id = db.db.lastArtEventId
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	db.db.artEvents = append(db.db.artEvents, event)
+	return &event, nil
+}
+
+func (db *ArtEventDB) GetArtEventsAfter(ctx context.Context, after uint, limit int) ([]dto.ArtEventDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	events := []dto.ArtEventDto{}
+	for _, event := range db.db.artEvents {
+		if event.Id <= after {
+			continue
+		} else if limit > 0 && len(events) == limit {
+			break
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (db *ArtEventDB) GetArtEventBounds(ctx context.Context) (uint, uint, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return 0, 0, err
+	} else if len(db.db.artEvents) == 0 {
+		return 0, 0, nil
+	} else {
+		return db.db.artEvents[0].Id, db.db.artEvents[len(db.db.artEvents)-1].Id, nil
+	}
+}
+
+func (db *ArtEventDB) PruneArtEvents(ctx context.Context, before time.Time) (int64, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	kept := []dto.ArtEventDto{}
+	for _, event := range db.db.artEvents {
+		if !event.CreatedAt.Before(before) {
+			kept = append(kept, event)
+		}
+	}
+	pruned := int64(len(db.db.artEvents) - len(kept))
+	db.db.artEvents = kept
+	return pruned, nil
+}
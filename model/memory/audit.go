@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+type AuditDB struct {
+	db *DB
+}
+
+var _ model.AuditRepository = &AuditDB{}
+
+func (db *AuditDB) Init(database *DB) error {
+	db.db = database
+	return nil
+}
+
+func (db *AuditDB) Record(ctx context.Context, entry dto.AuditEntryDto) (*dto.AuditEntryDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(entry.Before) == 0 {
+		entry.Before = json.RawMessage("null")
+	}
+	if len(entry.After) == 0 {
+		entry.After = json.RawMessage("null")
+	}
+	diff, err := dto.DiffJSON(entry.Before, entry.After)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.Id = uint(len(db.db.auditEntries) + 1)
+	entry.Diff = diff
+	entry.Timestamp = time.Now()
+	db.db.auditEntries = append(db.db.auditEntries, entry)
+	return &entry, nil
+}
+
+func (db *AuditDB) GetEntries(ctx context.Context, entity string, id uint) ([]dto.AuditEntryDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return []dto.AuditEntryDto{}, err
+	}
+
+	entries := []dto.AuditEntryDto{}
+	for _, entry := range db.db.auditEntries {
+		if (entity == "" || entry.Entity == entity) && (id == 0 || entry.EntityId == id) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
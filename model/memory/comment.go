@@ -0,0 +1,163 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+type CommentDB struct {
+	db *DB
+}
+
+var _ model.CommentRepository = &CommentDB{}
+
+func (db *CommentDB) Init(database *DB) error {
+	db.db = database
+	return nil
+}
+
+func (db *CommentDB) CreateComment(ctx context.Context, comment dto.CommentDto) (*dto.CommentDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if err := model.ValidateCommentBody(comment.Body); err != nil {
+		return nil, err
+	} else if _, ok := db.db.arts[comment.ArtId]; !ok {
+		return nil, model.ErrNotFound
+	} else if _, ok := db.db.accounts[comment.AuthorId]; !ok {
+		return nil, model.ErrAccountNotFound
+	} else if parent, ok := db.db.comments[comment.ParentId]; comment.ParentId != 0 && (!ok || parent.ArtId != comment.ArtId) {
+		return nil, model.ErrInvalidParent
+	}
+
+	db.db.lastCommentId++
+	comment.Id = db.db.lastCommentId
+	comment.Status = dto.CommentVisible
+	comment.CreatedAt = time.Now()
+	comment.UpdatedAt = comment.CreatedAt
+	db.db.comments[comment.Id] = comment
+	return &comment, nil
+}
+
+func (db *CommentDB) GetComment(ctx context.Context, id uint) (*dto.CommentDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if comment, ok := db.db.comments[id]; !ok {
+		return nil, model.ErrNotFound
+	} else {
+		return &comment, nil
+	}
+}
+
+func (db *CommentDB) GetComments(ctx context.Context, filter model.CommentFilter, after uint, limit int) ([]dto.CommentDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	comments := []dto.CommentDto{}
+	for _, comment := range db.db.comments {
+		if comment.Id <= after || (filter.ArtId != 0 && comment.ArtId != filter.ArtId) {
+			continue
+		}
+		matches := len(filter.Statuses) == 0
+		for _, status := range filter.Statuses {
+			matches = matches || comment.Status == status
+		}
+		if matches {
+			comments = append(comments, comment)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool { return comments[i].Id < comments[j].Id })
+	if limit > 0 && len(comments) > limit {
+		comments = comments[:limit]
+	}
+	return comments, nil
+}
+
+func (db *CommentDB) UpdateComment(ctx context.Context, id uint, body string) (*dto.CommentDto, error) {
+	if err := model.ValidateCommentBody(body); err != nil {
+		return nil, err
+	}
+
+	return db.update(ctx, id, func(comment *dto.CommentDto) {
+		comment.Body = body
+	})
+}
+
+func (db *CommentDB) ReportComment(ctx context.Context, id uint) (*dto.CommentDto, error) {
+	return db.update(ctx, id, func(comment *dto.CommentDto) {
+		if comment.Status == dto.CommentVisible {
+			comment.Status = dto.CommentReported
+		}
+	})
+}
+
+func (db *CommentDB) SetCommentStatus(ctx context.Context, id uint, status string) (*dto.CommentDto, error) {
+	return db.update(ctx, id, func(comment *dto.CommentDto) {
+		comment.Status = status
+	})
+}
+
+// Applies f to the comment with id and stores the result.
+func (db *CommentDB) update(ctx context.Context, id uint, f func(comment *dto.CommentDto)) (*dto.CommentDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	comment, ok := db.db.comments[id]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	f(&comment)
+	comment.UpdatedAt = time.Now()
+	db.db.comments[id] = comment
+	return &comment, nil
+}
+
+// Deletes a comment along with the replies to it, like the cascading foreign key of the database does.
+func (db *CommentDB) DeleteComment(ctx context.Context, id uint) (*dto.CommentDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	comment, ok := db.db.comments[id]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+
+	deleted := []uint{id}
+	for len(deleted) > 0 {
+		parentId := deleted[0]
+		deleted = deleted[1:]
+		delete(db.db.comments, parentId)
+		for notificationId, notification := range db.db.notifications {
+			if notification.CommentId == parentId {
+				delete(db.db.notifications, notificationId)
+			}
+		}
+		for _, reply := range db.db.comments {
+			if reply.ParentId == parentId {
+				deleted = append(deleted, reply.Id)
+			}
+		}
+	}
+	return &comment, nil
+}
@@ -0,0 +1,258 @@
+// Package memory implements the model repositories in memory.
+// It behaves like the database-backed repositories and is meant for tests that shouldn't need SQLite.
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+// State shared by the in-memory repositories.
+// Every repository locks mu for the whole of an operation, which makes operations atomic.
+type DB struct {
+	mu sync.Mutex
+	// Serializes transactions, see Transaction.
+	txMu sync.Mutex
+
+	arts          map[uint]dto.ArtDto
+	revisions     map[uint][]dto.ArtRevisionDto
+	accounts      map[uint]dto.AccountDto
+	auditEntries  []dto.AuditEntryDto
+	lastArtId     uint
+	lastAccountId uint
+	// Order in which each art was liked by each account, keyed by art and then account.
+	likes         map[uint]map[uint]uint
+	lastLikeId    uint
+	comments      map[uint]dto.CommentDto
+	lastCommentId uint
+	// Order in which each account was followed by each follower, keyed by follower and then followee.
+	follows      map[uint]map[uint]uint
+	lastFollowId uint
+	// Order in which the arts were last created or updated, which orders the feeds.
+	artVersions        map[uint]uint
+	lastArtVersion     uint
+	notifications      map[uint]dto.NotificationDto
+	lastNotificationId uint
+	webhooks           map[uint]dto.WebhookDto
+	lastWebhookId      uint
+	deliveries         map[uint]dto.WebhookDeliveryDto
+	lastDeliveryId     uint
+	// Oldest first, ids are never reused once pruned.
+	artEvents      []dto.ArtEventDto
+	lastArtEventId uint
+}
+
+func (db *DB) Init() error {
+	db.arts = map[uint]dto.ArtDto{}
+	db.revisions = map[uint][]dto.ArtRevisionDto{}
+	db.accounts = map[uint]dto.AccountDto{}
+	db.auditEntries = []dto.AuditEntryDto{}
+	db.likes = map[uint]map[uint]uint{}
+	db.comments = map[uint]dto.CommentDto{}
+	db.follows = map[uint]map[uint]uint{}
+	db.artVersions = map[uint]uint{}
+	db.notifications = map[uint]dto.NotificationDto{}
+	db.webhooks = map[uint]dto.WebhookDto{}
+	db.deliveries = map[uint]dto.WebhookDeliveryDto{}
+	db.artEvents = []dto.ArtEventDto{}
+	return nil
+}
+
+// Creates the repositories backed by db.
+func (db *DB) Repositories() (model.Repositories, error) {
+	return db.repositories(db)
+}
+
+func (db *DB) repositories(transactor model.Transactor) (model.Repositories, error) {
+	artDB := &ArtDB{}
+	if err := artDB.Init(db); err != nil {
+		return model.Repositories{}, err
+	}
+
+	accountDB := &AccountDB{}
+	if err := accountDB.Init(db); err != nil {
+		return model.Repositories{}, err
+	}
+
+	likeDB := &LikeDB{}
+	if err := likeDB.Init(db); err != nil {
+		return model.Repositories{}, err
+	}
+
+	commentDB := &CommentDB{}
+	if err := commentDB.Init(db); err != nil {
+		return model.Repositories{}, err
+	}
+
+	followDB := &FollowDB{}
+	if err := followDB.Init(db); err != nil {
+		return model.Repositories{}, err
+	}
+
+	notificationDB := &NotificationDB{}
+	if err := notificationDB.Init(db); err != nil {
+		return model.Repositories{}, err
+	}
+
+	webhookDB := &WebhookDB{}
+	if err := webhookDB.Init(db); err != nil {
+		return model.Repositories{}, err
+	}
+
+	artEventDB := &ArtEventDB{}
+	if err := artEventDB.Init(db); err != nil {
+		return model.Repositories{}, err
+	}
+
+	auditDB := &AuditDB{}
+	if err := auditDB.Init(db); err != nil {
+		return model.Repositories{}, err
+	}
+
+	return model.Repositories{
+		Arts:          artDB,
+		Accounts:      accountDB,
+		Likes:         likeDB,
+		Comments:      commentDB,
+		Follows:       followDB,
+		Notifications: notificationDB,
+		Webhooks:      webhookDB,
+		ArtEvents:     artEventDB,
+		Audit:         auditDB,
+		Transactor:    transactor,
+	}, nil
+}
+
+var _ model.Transactor = &DB{}
+
+// Runs f with the repositories of db and brings db back to its prior state if f fails.
+// Transactions are serialized with each other but not isolated from operations made outside of one.
+func (db *DB) Transaction(ctx context.Context, f func(tx model.Repositories) error) error {
+	db.txMu.Lock()
+	defer db.txMu.Unlock()
+
+	return savepoint{db: db}.Transaction(ctx, f)
+}
+
+// Transactor of the repositories of a transaction, whose nested transactions only undo their own changes.
+type savepoint struct {
+	db *DB
+}
+
+func (s savepoint) Transaction(ctx context.Context, f func(tx model.Repositories) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repos, err := s.db.repositories(s)
+	if err != nil {
+		return err
+	}
+
+	saved := s.db.save()
+	if err := f(repos); err != nil {
+		s.db.restore(saved)
+		return err
+	}
+	return nil
+}
+
+// Copies the state of db.
+func (db *DB) save() *DB {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	saved := &DB{
+		arts:               map[uint]dto.ArtDto{},
+		revisions:          map[uint][]dto.ArtRevisionDto{},
+		accounts:           map[uint]dto.AccountDto{},
+		auditEntries:       append([]dto.AuditEntryDto{}, db.auditEntries...),
+		lastArtId:          db.lastArtId,
+		lastAccountId:      db.lastAccountId,
+		likes:              map[uint]map[uint]uint{},
+		lastLikeId:         db.lastLikeId,
+		comments:           map[uint]dto.CommentDto{},
+		lastCommentId:      db.lastCommentId,
+		follows:            map[uint]map[uint]uint{},
+		lastFollowId:       db.lastFollowId,
+		artVersions:        map[uint]uint{},
+		lastArtVersion:     db.lastArtVersion,
+		notifications:      map[uint]dto.NotificationDto{},
+		lastNotificationId: db.lastNotificationId,
+		webhooks:           map[uint]dto.WebhookDto{},
+		lastWebhookId:      db.lastWebhookId,
+		deliveries:         map[uint]dto.WebhookDeliveryDto{},
+		lastDeliveryId:     db.lastDeliveryId,
+		artEvents:          append([]dto.ArtEventDto{}, db.artEvents...),
+		lastArtEventId:     db.lastArtEventId,
+	}
+	for id, art := range db.arts {
+		saved.arts[id] = art
+	}
+	for id, revisions := range db.revisions {
+		saved.revisions[id] = append([]dto.ArtRevisionDto{}, revisions...)
+	}
+	for id, account := range db.accounts {
+		saved.accounts[id] = account
+	}
+	for artId, likes := range db.likes {
+		saved.likes[artId] = map[uint]uint{}
+		for accountId, likeId := range likes {
+			saved.likes[artId][accountId] = likeId
+		}
+	}
+	for id, comment := range db.comments {
+		saved.comments[id] = comment
+	}
+	for followerId, follows := range db.follows {
+		saved.follows[followerId] = map[uint]uint{}
+		for followeeId, followId := range follows {
+			saved.follows[followerId][followeeId] = followId
+		}
+	}
+	for id, version := range db.artVersions {
+		saved.artVersions[id] = version
+	}
+	for id, notification := range db.notifications {
+		saved.notifications[id] = notification
+	}
+	for id, webhook := range db.webhooks {
+		saved.webhooks[id] = webhook
+	}
+	for id, delivery := range db.deliveries {
+		saved.deliveries[id] = delivery
+	}
+	return saved
+}
+
+// Replaces the state of db with a copy made by save.
+func (db *DB) restore(saved *DB) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.arts = saved.arts
+	db.revisions = saved.revisions
+	db.accounts = saved.accounts
+	db.auditEntries = saved.auditEntries
+	db.lastArtId = saved.lastArtId
+	db.lastAccountId = saved.lastAccountId
+	db.likes = saved.likes
+	db.lastLikeId = saved.lastLikeId
+	db.comments = saved.comments
+	db.lastCommentId = saved.lastCommentId
+	db.follows = saved.follows
+	db.lastFollowId = saved.lastFollowId
+	db.artVersions = saved.artVersions
+	db.lastArtVersion = saved.lastArtVersion
+	db.notifications = saved.notifications
+	db.lastNotificationId = saved.lastNotificationId
+	db.webhooks = saved.webhooks
+	db.lastWebhookId = saved.lastWebhookId
+	db.deliveries = saved.deliveries
+	db.lastDeliveryId = saved.lastDeliveryId
+	db.artEvents = saved.artEvents
+	db.lastArtEventId = saved.lastArtEventId
+}
@@ -0,0 +1,136 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+type FollowDB struct {
+	db *DB
+}
+
+var _ model.FollowRepository = &FollowDB{}
+
+func (db *FollowDB) Init(database *DB) error {
+	db.db = database
+	return nil
+}
+
+func (db *FollowDB) Follow(ctx context.Context, followerId uint, followeeId uint) error {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	} else if followerId == followeeId {
+		return model.ErrSelfFollow
+	} else if _, ok := db.db.accounts[followeeId]; !ok {
+		return model.ErrNotFound
+	} else if _, ok := db.db.accounts[followerId]; !ok {
+		return model.ErrAccountNotFound
+	}
+
+	if db.db.follows[followerId] == nil {
+		db.db.follows[followerId] = map[uint]uint{}
+	}
+	if _, ok := db.db.follows[followerId][followeeId]; !ok {
+		db.db.lastFollowId++
+		db.db.follows[followerId][followeeId] = db.db.lastFollowId
+	}
+	return nil
+}
+
+func (db *FollowDB) Unfollow(ctx context.Context, followerId uint, followeeId uint) error {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	delete(db.db.follows[followerId], followeeId)
+	return nil
+}
+
+func (db *FollowDB) GetFollowers(ctx context.Context, accountId uint) ([]dto.AccountDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	followIds := map[uint]uint{}
+	for followerId, follows := range db.db.follows {
+		if followId, ok := follows[accountId]; ok {
+			followIds[followerId] = followId
+		}
+	}
+	return db.accounts(followIds), nil
+}
+
+func (db *FollowDB) GetFollowing(ctx context.Context, accountId uint) ([]dto.AccountDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return db.accounts(db.db.follows[accountId]), nil
+}
+
+// Gets the accounts whose ids are the keys of followIds, most recently followed first.
+func (db *FollowDB) accounts(followIds map[uint]uint) []dto.AccountDto {
+	accounts := []dto.AccountDto{}
+	for accountId := range followIds {
+		if account, ok := db.db.accounts[accountId]; ok {
+			accounts = append(accounts, account)
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool { return followIds[accounts[i].Id] > followIds[accounts[j].Id] })
+	return accounts
+}
+
+// Gets a page of the feed like the database-backed FollowDB.
+// The cursors hold the order in which the arts were last created or updated rather than a time.
+func (db *FollowDB) GetFeed(ctx context.Context, accountId uint, cursor string, limit int) ([]dto.ArtDto, string, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	} else if limit <= 0 {
+		return nil, "", fmt.Errorf("feed limit must be positive, got %d", limit)
+	}
+
+	var after uint64
+	if cursor != "" {
+		var err error
+		if after, err = strconv.ParseUint(cursor, 10, 64); err != nil {
+			return nil, "", model.ErrInvalidCursor
+		}
+	}
+
+	arts := []dto.ArtDto{}
+	for _, art := range db.db.arts {
+		if _, ok := db.db.follows[accountId][art.AuthorId]; !ok {
+			continue
+		} else if version := db.db.artVersions[art.Id]; after == 0 || uint64(version) < after {
+			arts = append(arts, art)
+		}
+	}
+	sort.Slice(arts, func(i, j int) bool { return db.db.artVersions[arts[i].Id] > db.db.artVersions[arts[j].Id] })
+
+	next := ""
+	if len(arts) > limit {
+		arts = arts[:limit]
+		next = strconv.FormatUint(uint64(db.db.artVersions[arts[limit-1].Id]), 10)
+	}
+	return arts, next, nil
+}
@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+type LikeDB struct {
+	db *DB
+}
+
+var _ model.LikeRepository = &LikeDB{}
+
+func (db *LikeDB) Init(database *DB) error {
+	db.db = database
+	return nil
+}
+
+func (db *LikeDB) LikeArt(ctx context.Context, accountId uint, artId uint) (bool, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return false, err
+	} else if _, ok := db.db.arts[artId]; !ok {
+		return false, model.ErrNotFound
+	} else if _, ok := db.db.accounts[accountId]; !ok {
+		return false, model.ErrAccountNotFound
+	}
+
+	if db.db.likes[artId] == nil {
+		db.db.likes[artId] = map[uint]uint{}
+	}
+	if _, ok := db.db.likes[artId][accountId]; ok {
+		return false, nil
+	}
+	db.db.lastLikeId++
+	db.db.likes[artId][accountId] = db.db.lastLikeId
+	return true, nil
+}
+
+func (db *LikeDB) UnlikeArt(ctx context.Context, accountId uint, artId uint) error {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	delete(db.db.likes[artId], accountId)
+	return nil
+}
+
+func (db *LikeDB) GetLikes(ctx context.Context, accountId uint, artIds []uint) (map[uint]int64, map[uint]bool, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	counts := map[uint]int64{}
+	liked := map[uint]bool{}
+	for _, artId := range artIds {
+		if likes := db.db.likes[artId]; len(likes) > 0 {
+			counts[artId] = int64(len(likes))
+			if _, ok := likes[accountId]; ok {
+				liked[artId] = true
+			}
+		}
+	}
+	return counts, liked, nil
+}
+
+func (db *LikeDB) GetLikedArts(ctx context.Context, accountId uint) ([]dto.ArtDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	likeIds := map[uint]uint{}
+	arts := []dto.ArtDto{}
+	for artId, likes := range db.db.likes {
+		if likeId, ok := likes[accountId]; ok {
+			if art, ok := db.db.arts[artId]; ok {
+				likeIds[artId] = likeId
+				arts = append(arts, art)
+			}
+		}
+	}
+	sort.Slice(arts, func(i, j int) bool { return likeIds[arts[i].Id] > likeIds[arts[j].Id] })
+	return arts, nil
+}
@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+type NotificationDB struct {
+	db *DB
+}
+
+var _ model.NotificationRepository = &NotificationDB{}
+
+func (db *NotificationDB) Init(database *DB) error {
+	db.db = database
+	return nil
+}
+
+func (db *NotificationDB) CreateNotification(ctx context.Context, notification dto.NotificationDto) (*dto.NotificationDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if _, ok := db.db.accounts[notification.AccountId]; !ok {
+		return nil, model.ErrReferenceNotFound
+	} else if _, ok := db.db.accounts[notification.ActorId]; !ok {
+		return nil, model.ErrReferenceNotFound
+	}
+
+	db.db.lastNotificationId++
+	notification.Id = db.db.lastNotificationId
+	notification.Read = false
+	notification.CreatedAt = time.Now()
+	db.db.notifications[notification.Id] = notification
+	return &notification, nil
+}
+
+func (db *NotificationDB) GetNotifications(ctx context.Context, accountId uint, unreadOnly bool, before uint, limit int) ([]dto.NotificationDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	notifications := []dto.NotificationDto{}
+	for _, notification := range db.db.notifications {
+		if notification.AccountId != accountId || (unreadOnly && notification.Read) || (before != 0 && notification.Id >= before) {
+			continue
+		}
+		notifications = append(notifications, notification)
+	}
+	sort.Slice(notifications, func(i, j int) bool { return notifications[i].Id > notifications[j].Id })
+	if limit > 0 && len(notifications) > limit {
+		notifications = notifications[:limit]
+	}
+	return notifications, nil
+}
+
+func (db *NotificationDB) MarkRead(ctx context.Context, accountId uint, id uint) (*dto.NotificationDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	notification, ok := db.db.notifications[id]
+	if !ok || notification.AccountId != accountId {
+		return nil, model.ErrNotFound
+	}
+	notification.Read = true
+	db.db.notifications[id] = notification
+	return &notification, nil
+}
+
+func (db *NotificationDB) MarkAllRead(ctx context.Context, accountId uint) (int64, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var marked int64
+	for id, notification := range db.db.notifications {
+		if notification.AccountId == accountId && !notification.Read {
+			notification.Read = true
+			db.db.notifications[id] = notification
+			marked++
+		}
+	}
+	return marked, nil
+}
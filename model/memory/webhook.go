@@ -0,0 +1,262 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+type WebhookDB struct {
+	db *DB
+}
+
+var _ model.WebhookRepository = &WebhookDB{}
+
+func (db *WebhookDB) Init(database *DB) error {
+	db.db = database
+	return nil
+}
+
+func (db *WebhookDB) CreateWebhook(ctx context.Context, webhook dto.WebhookDto) (*dto.WebhookDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if err := model.ValidateWebhook(webhook); err != nil {
+		return nil, err
+	}
+
+	db.db.lastWebhookId++
+	webhook.Id = db.db.lastWebhookId
+	webhook.Events = append([]string{}, webhook.Events...)
+	webhook.CreatedAt = time.Now()
+	webhook.UpdatedAt = webhook.CreatedAt
+	db.db.webhooks[webhook.Id] = webhook
+	return &webhook, nil
+}
+
+func (db *WebhookDB) GetWebhooks(ctx context.Context) ([]dto.WebhookDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	webhooks := []dto.WebhookDto{}
+	for _, webhook := range db.db.webhooks {
+		webhooks = append(webhooks, webhook)
+	}
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].Id < webhooks[j].Id })
+	return webhooks, nil
+}
+
+func (db *WebhookDB) GetWebhook(ctx context.Context, id uint) (*dto.WebhookDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if webhook, ok := db.db.webhooks[id]; !ok {
+		return nil, model.ErrNotFound
+	} else {
+		return &webhook, nil
+	}
+}
+
+func (db *WebhookDB) UpdateWebhook(ctx context.Context, webhook dto.WebhookDto) (*dto.WebhookDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	old, ok := db.db.webhooks[webhook.Id]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	if webhook.Secret == "" {
+		webhook.Secret = old.Secret
+	}
+	if err := model.ValidateWebhook(webhook); err != nil {
+		return nil, err
+	}
+
+	webhook.Events = append([]string{}, webhook.Events...)
+	webhook.CreatedAt = old.CreatedAt
+	webhook.UpdatedAt = time.Now()
+	db.db.webhooks[webhook.Id] = webhook
+	return &webhook, nil
+}
+
+func (db *WebhookDB) DeleteWebhook(ctx context.Context, id uint) (*dto.WebhookDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	webhook, ok := db.db.webhooks[id]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	delete(db.db.webhooks, id)
+	for deliveryId, delivery := range db.db.deliveries {
+		if delivery.WebhookId == id {
+			delete(db.db.deliveries, deliveryId)
+		}
+	}
+	return &webhook, nil
+}
+
+func (db *WebhookDB) EnqueueDeliveries(ctx context.Context, event string, payload []byte) ([]dto.WebhookDeliveryDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	webhooks := []dto.WebhookDto{}
+	for _, webhook := range db.db.webhooks {
+		webhooks = append(webhooks, webhook)
+	}
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].Id < webhooks[j].Id })
+
+	now := time.Now()
+	deliveries := []dto.WebhookDeliveryDto{}
+	for _, webhook := range webhooks {
+		if webhook.Disabled {
+			continue
+		}
+		for _, subscribed := range webhook.Events {
+			if subscribed == event {
+				db.db.lastDeliveryId++
+				delivery := dto.WebhookDeliveryDto{
+					Id:            db.db.lastDeliveryId,
+					WebhookId:     webhook.Id,
+					Event:         event,
+					Payload:       append([]byte{}, payload...),
+					Status:        dto.DeliveryPending,
+					NextAttemptAt: now,
+					CreatedAt:     now,
+				}
+				db.db.deliveries[delivery.Id] = delivery
+				deliveries = append(deliveries, delivery)
+				break
+			}
+		}
+	}
+	return deliveries, nil
+}
+
+func (db *WebhookDB) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]dto.WebhookDeliveryDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	deliveries := []dto.WebhookDeliveryDto{}
+	for _, delivery := range db.db.deliveries {
+		if delivery.Status == dto.DeliveryPending && !delivery.NextAttemptAt.After(now) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].NextAttemptAt.Equal(deliveries[j].NextAttemptAt) {
+			return deliveries[i].NextAttemptAt.Before(deliveries[j].NextAttemptAt)
+		}
+		return deliveries[i].Id < deliveries[j].Id
+	})
+	if limit > 0 && len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+
+	for i := range deliveries {
+		deliveries[i].NextAttemptAt = now.Add(lease)
+		db.db.deliveries[deliveries[i].Id] = deliveries[i]
+	}
+	return deliveries, nil
+}
+
+func (db *WebhookDB) RecordAttempt(ctx context.Context, delivery dto.WebhookDeliveryDto) (*dto.WebhookDeliveryDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	saved, ok := db.db.deliveries[delivery.Id]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	saved.Status = delivery.Status
+	saved.Attempts = delivery.Attempts
+	saved.NextAttemptAt = delivery.NextAttemptAt
+	saved.ResponseStatus = delivery.ResponseStatus
+	saved.Error = delivery.Error
+	saved.DeliveredAt = delivery.DeliveredAt
+	db.db.deliveries[saved.Id] = saved
+	return &saved, nil
+}
+
+func (db *WebhookDB) GetDeliveries(ctx context.Context, webhookId uint, before uint, limit int) ([]dto.WebhookDeliveryDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if _, ok := db.db.webhooks[webhookId]; !ok {
+		return nil, model.ErrNotFound
+	}
+
+	deliveries := []dto.WebhookDeliveryDto{}
+	for _, delivery := range db.db.deliveries {
+		if delivery.WebhookId == webhookId && (before == 0 || delivery.Id < before) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].Id > deliveries[j].Id })
+	if limit > 0 && len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+func (db *WebhookDB) ReplayDelivery(ctx context.Context, webhookId uint, id uint) (*dto.WebhookDeliveryDto, error) {
+	db.db.mu.Lock()
+	defer db.db.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	replayed, ok := db.db.deliveries[id]
+	if !ok || replayed.WebhookId != webhookId {
+		return nil, model.ErrNotFound
+	}
+
+	db.db.lastDeliveryId++
+	now := time.Now()
+	replay := dto.WebhookDeliveryDto{
+		Id:            db.db.lastDeliveryId,
+		WebhookId:     replayed.WebhookId,
+		Event:         replayed.Event,
+		Payload:       replayed.Payload,
+		Status:        dto.DeliveryPending,
+		NextAttemptAt: now,
+		ReplayOf:      replayed.Id,
+		CreatedAt:     now,
+	}
+	db.db.deliveries[replay.Id] = replay
+	return &replay, nil
+}
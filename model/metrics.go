@@ -0,0 +1,55 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const queryStartKey = "gallery:query_start"
+
+// A gorm plugin reporting how long every statement took.
+// Observe is called with the kind of statement (create, query, update, delete, row or raw) and the table it ran against.
+type QueryTimer struct {
+	Observe func(operation string, table string, elapsed time.Duration)
+}
+
+func (p QueryTimer) Name() string {
+	return "gallery:query_timer"
+}
+
+func (p QueryTimer) Initialize(db *gorm.DB) error {
+	start := func(db *gorm.DB) {
+		db.InstanceSet(queryStartKey, time.Now())
+	}
+	stop := func(operation string) func(*gorm.DB) {
+		return func(db *gorm.DB) {
+			if v, ok := db.InstanceGet(queryStartKey); ok {
+				if begin, ok := v.(time.Time); ok {
+					p.Observe(operation, db.Statement.Table, time.Since(begin))
+				}
+			}
+		}
+	}
+
+	c := db.Callback()
+	for _, err := range []error{
+		c.Create().Before("gorm:create").Register("gallery:start_create", start),
+		c.Create().After("gorm:create").Register("gallery:stop_create", stop("create")),
+		c.Query().Before("gorm:query").Register("gallery:start_query", start),
+		c.Query().After("gorm:query").Register("gallery:stop_query", stop("query")),
+		c.Update().Before("gorm:update").Register("gallery:start_update", start),
+		c.Update().After("gorm:update").Register("gallery:stop_update", stop("update")),
+		c.Delete().Before("gorm:delete").Register("gallery:start_delete", start),
+		c.Delete().After("gorm:delete").Register("gallery:stop_delete", stop("delete")),
+		c.Row().Before("gorm:row").Register("gallery:start_row", start),
+		c.Row().After("gorm:row").Register("gallery:stop_row", stop("row")),
+		c.Raw().Before("gorm:raw").Register("gallery:start_raw", start),
+		c.Raw().After("gorm:raw").Register("gallery:stop_raw", stop("raw")),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
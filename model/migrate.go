@@ -0,0 +1,298 @@
+package model
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/*.sql
+var sqlMigrations embed.FS
+
+// Migrations written in Go, for changes SQL alone can't express such as backfilling data.
+// They are applied in version order together with the SQL migrations in the migrations directory.
+var goMigrations = []Migration{}
+
+// A versioned change of the schema.
+// A migration is either given as SQL, read from migrations/<version>_<name>.up.sql and .down.sql, or as Go functions.
+type Migration struct {
+	Version int
+	Name    string
+
+	UpSQL   string
+	DownSQL string
+
+	Up   func(tx *gorm.DB) error
+	Down func(tx *gorm.DB) error
+}
+
+// A migration together with whether it is applied.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+type schemaMigration struct {
+	Version   int `gorm:"primarykey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+const createSchemaMigrations = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version integer,
+    name text,
+    applied_at datetime,
+    PRIMARY KEY (version)
+)`
+
+// Applies and reverts migrations, keeping track of the applied versions in the schema_migrations table.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+func (m *Migrator) Init(database *DB) error {
+	m.db = database.GormDB
+
+	migrations, err := loadSQLMigrations()
+	if err != nil {
+		return err
+	}
+	migrations = append(migrations, goMigrations...)
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return fmt.Errorf("migration version %d is used more than once", migrations[i].Version)
+		}
+	}
+	m.migrations = migrations
+
+	return nil
+}
+
+// Gets every known migration in version order with the time it was applied, if it was.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []MigrationStatus{}
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Migration: migration}
+		if a, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &a.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Gets the migrations that are not applied yet.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []Migration{}
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, status.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Version of the latest known migration.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Fails unless every migration is applied.
+func (m *Migrator) CheckCurrent(ctx context.Context) error {
+	if pending, err := m.Pending(ctx); err != nil {
+		return err
+	} else if len(pending) > 0 {
+		return fmt.Errorf("%d migration(s) pending, starting with %04d_%s", len(pending), pending[0].Version, pending[0].Name)
+	} else {
+		return nil
+	}
+}
+
+// Applies pending migrations up to and including version target, or all of them if target is zero.
+// Every migration runs in its own transaction. With dryRun, the migrations are only described on out.
+func (m *Migrator) Up(ctx context.Context, target int, dryRun bool, out io.Writer) ([]Migration, error) {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !dryRun {
+		if err := m.db.WithContext(ctx).Exec(createSchemaMigrations).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	applied := []Migration{}
+	for _, migration := range pending {
+		if target != 0 && migration.Version > target {
+			break
+		}
+
+		describe(out, migration, "up", migration.UpSQL, migration.Up != nil)
+		if !dryRun {
+			err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				if err := run(tx, migration.UpSQL, migration.Up); err != nil {
+					return err
+				}
+				return tx.Create(&schemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+			})
+			if err != nil {
+				return applied, fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+		}
+		applied = append(applied, migration)
+	}
+	return applied, nil
+}
+
+// Reverts the latest steps applied migrations, latest first.
+// Every migration runs in its own transaction. With dryRun, the migrations are only described on out.
+func (m *Migrator) Down(ctx context.Context, steps int, dryRun bool, out io.Writer) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reverted := []Migration{}
+	for i := len(statuses) - 1; i >= 0 && len(reverted) < steps; i-- {
+		migration := statuses[i].Migration
+		if statuses[i].AppliedAt == nil {
+			continue
+		} else if migration.DownSQL == "" && migration.Down == nil {
+			return reverted, fmt.Errorf("migration %04d_%s can't be reverted", migration.Version, migration.Name)
+		}
+
+		describe(out, migration, "down", migration.DownSQL, migration.Down != nil)
+		if !dryRun {
+			err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				if err := run(tx, migration.DownSQL, migration.Down); err != nil {
+					return err
+				}
+				return tx.Delete(&schemaMigration{}, migration.Version).Error
+			})
+			if err != nil {
+				return reverted, fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+		}
+		reverted = append(reverted, migration)
+	}
+	return reverted, nil
+}
+
+// Gets the applied migrations by version. A database without a schema_migrations table has none.
+func (m *Migrator) applied(ctx context.Context) (map[int]schemaMigration, error) {
+	db := m.db.WithContext(ctx)
+	applied := map[int]schemaMigration{}
+	if !db.Migrator().HasTable(&schemaMigration{}) {
+		return applied, nil
+	}
+
+	var rows []schemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+func run(tx *gorm.DB, sql string, f func(tx *gorm.DB) error) error {
+	if f != nil {
+		return f(tx)
+	}
+	return tx.Exec(sql).Error
+}
+
+func describe(out io.Writer, migration Migration, direction string, sql string, isGo bool) {
+	if out == nil {
+		return
+	}
+	if isGo {
+		fmt.Fprintf(out, "-- %04d_%s (%s, Go)\n", migration.Version, migration.Name, direction)
+	} else {
+		fmt.Fprintf(out, "-- %04d_%s (%s)\n%s\n", migration.Version, migration.Name, direction, strings.TrimSpace(sql))
+	}
+}
+
+func loadSQLMigrations() ([]Migration, error) {
+	names, err := fs.Glob(sqlMigrations, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, name := range names {
+		base := path.Base(name)
+
+		var direction string
+		if strings.HasSuffix(base, ".up.sql") {
+			direction = "up"
+		} else if strings.HasSuffix(base, ".down.sql") {
+			direction = "down"
+		} else {
+			return nil, fmt.Errorf("migration %s must end in .up.sql or .down.sql", base)
+		}
+
+		parts := strings.SplitN(strings.TrimSuffix(base, "."+direction+".sql"), "_", 2)
+		version, err := strconv.Atoi(parts[0])
+		if err != nil || len(parts) != 2 {
+			return nil, fmt.Errorf("migration %s must be named <version>_<name>.%s.sql", base, direction)
+		}
+
+		content, err := sqlMigrations.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: parts[1]}
+			byVersion[version] = migration
+		}
+		if direction == "up" {
+			migration.UpSQL = string(content)
+		} else {
+			migration.DownSQL = string(content)
+		}
+	}
+
+	migrations := []Migration{}
+	for _, migration := range byVersion {
+		if migration.UpSQL == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up.sql", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	return migrations, nil
+}
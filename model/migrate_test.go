@@ -0,0 +1,156 @@
+package model_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/nafiz1001/gallery-go/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Applies every migration to db.
+func Migrate(t *testing.T, db *model.DB) {
+	var migrator model.Migrator
+	require.NoError(t, migrator.Init(db))
+
+	_, err := migrator.Up(context.Background(), 0, false, nil)
+	require.NoError(t, err)
+}
+
+func MigratorInit(t *testing.T) (model.Migrator, *gorm.DB) {
+	gormDB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared&_foreign_keys=true"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+
+	var migrator model.Migrator
+	require.NoError(t, migrator.Init(&model.DB{GormDB: gormDB}))
+
+	return migrator, gormDB
+}
+
+func TestMigrateUp(t *testing.T) {
+	migrator, gormDB := MigratorInit(t)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+
+	pending, err := migrator.Pending(context.Background())
+	require.NoError(t, err)
+	require.NotEmpty(t, pending)
+	assert.Error(t, migrator.CheckCurrent(context.Background()))
+
+	// dry run only describes the migrations
+	var out bytes.Buffer
+	applied, err := migrator.Up(context.Background(), 0, true, &out)
+	if assert.NoError(t, err) {
+		assert.Len(t, applied, len(pending))
+		assert.Contains(t, out.String(), "CREATE TABLE accounts")
+		assert.False(t, gormDB.Migrator().HasTable("accounts"))
+	}
+
+	// apply the first migration only
+	applied, err = migrator.Up(context.Background(), pending[0].Version, false, nil)
+	if assert.NoError(t, err) && assert.Len(t, applied, 1) {
+		assert.True(t, gormDB.Migrator().HasTable("accounts"))
+	}
+
+	// apply the rest
+	_, err = migrator.Up(context.Background(), 0, false, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, migrator.CheckCurrent(context.Background()))
+
+	statuses, err := migrator.Status(context.Background())
+	if assert.NoError(t, err) {
+		for _, status := range statuses {
+			assert.NotNil(t, status.AppliedAt, "%04d_%s is not applied", status.Version, status.Name)
+		}
+	}
+
+	// nothing left to apply
+	applied, err = migrator.Up(context.Background(), 0, false, nil)
+	if assert.NoError(t, err) {
+		assert.Len(t, applied, 0)
+	}
+}
+
+func TestMigrateDown(t *testing.T) {
+	migrator, gormDB := MigratorInit(t)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+
+	all, err := migrator.Up(context.Background(), 0, false, nil)
+	require.NoError(t, err)
+
+	// dry run keeps the migrations applied
+	reverted, err := migrator.Down(context.Background(), 1, true, nil)
+	if assert.NoError(t, err) && assert.Len(t, reverted, 1) {
+		assert.Equal(t, all[len(all)-1].Version, reverted[0].Version)
+		assert.NoError(t, migrator.CheckCurrent(context.Background()))
+	}
+
+	// revert everything, latest first
+	reverted, err = migrator.Down(context.Background(), len(all), false, nil)
+	if assert.NoError(t, err) && assert.Len(t, reverted, len(all)) {
+		assert.Equal(t, all[0].Version, reverted[len(reverted)-1].Version)
+		assert.False(t, gormDB.Migrator().HasTable("accounts"))
+	}
+
+	pending, err := migrator.Pending(context.Background())
+	if assert.NoError(t, err) {
+		assert.Len(t, pending, len(all))
+	}
+
+	// migrations can be applied again
+	_, err = migrator.Up(context.Background(), 0, false, nil)
+	assert.NoError(t, err)
+}
+
+func TestMigrateConstraints(t *testing.T) {
+	migrator, gormDB := MigratorInit(t)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+
+	// data that breaks the constraints added by 0002
+	_, err := migrator.Up(context.Background(), 1, false, nil)
+	require.NoError(t, err)
+	require.NoError(t, gormDB.Exec("INSERT INTO accounts (id, username) VALUES (1, 'username')").Error)
+	require.NoError(t, gormDB.Exec("INSERT INTO arts (id, quantity, title, account_id) VALUES (1, -1, 'negative', 1), (2, 1, 'orphan', 2)").Error)
+	require.NoError(t, gormDB.Exec("INSERT INTO art_revisions (art_id, number, quantity, title, account_id) VALUES (1, 1, 1, 'title', 1), (3, 1, 1, 'title', 1)").Error)
+
+	_, err = migrator.Up(context.Background(), 0, false, nil)
+	require.NoError(t, err)
+
+	var arts []struct {
+		ID        uint
+		Quantity  int
+		AccountID *uint
+	}
+	require.NoError(t, gormDB.Raw("SELECT id, quantity, account_id FROM arts ORDER BY id").Scan(&arts).Error)
+	if assert.Len(t, arts, 2) {
+		assert.Equal(t, 0, arts[0].Quantity)
+		assert.Nil(t, arts[1].AccountID)
+	}
+
+	var revisions int64
+	require.NoError(t, gormDB.Table("art_revisions").Count(&revisions).Error)
+	assert.Equal(t, int64(1), revisions)
+
+	var violations []map[string]interface{}
+	require.NoError(t, gormDB.Raw("PRAGMA foreign_key_check").Scan(&violations).Error)
+	assert.Empty(t, violations)
+
+	// and back
+	_, err = migrator.Down(context.Background(), 1, false, nil)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,4 @@
+DROP TABLE audit_entries;
+DROP TABLE art_revisions;
+DROP TABLE arts;
+DROP TABLE accounts;
@@ -0,0 +1,50 @@
+CREATE TABLE accounts (
+    id integer,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    username text,
+    password text,
+    admin numeric,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_accounts_deleted_at ON accounts(deleted_at);
+
+CREATE TABLE arts (
+    id integer,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    quantity integer,
+    title text,
+    account_id integer,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_arts_deleted_at ON arts(deleted_at);
+
+CREATE TABLE art_revisions (
+    id integer,
+    created_at datetime,
+    art_id integer,
+    number integer,
+    quantity integer,
+    title text,
+    account_id integer,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX idx_art_revision ON art_revisions(art_id, number);
+
+CREATE TABLE audit_entries (
+    id integer,
+    created_at datetime,
+    actor_id integer,
+    action text,
+    entity text,
+    entity_id integer,
+    before text,
+    after text,
+    diff text,
+    request_id text,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_audit_entity ON audit_entries(entity, entity_id);
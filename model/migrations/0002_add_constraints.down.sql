@@ -0,0 +1,49 @@
+-- Children are rebuilt before their parents, which keeps every DROP TABLE free of foreign key checks.
+
+CREATE TABLE art_revisions_old (
+    id integer,
+    created_at datetime,
+    art_id integer,
+    number integer,
+    quantity integer,
+    title text,
+    account_id integer,
+    PRIMARY KEY (id)
+);
+INSERT INTO art_revisions_old (id, created_at, art_id, number, quantity, title, account_id)
+    SELECT id, created_at, art_id, number, quantity, title, account_id FROM art_revisions;
+DROP TABLE art_revisions;
+ALTER TABLE art_revisions_old RENAME TO art_revisions;
+CREATE UNIQUE INDEX idx_art_revision ON art_revisions(art_id, number);
+
+CREATE TABLE arts_old (
+    id integer,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    quantity integer,
+    title text,
+    account_id integer,
+    PRIMARY KEY (id)
+);
+INSERT INTO arts_old (id, created_at, updated_at, deleted_at, quantity, title, account_id)
+    SELECT id, created_at, updated_at, deleted_at, quantity, title, account_id FROM arts;
+DROP TABLE arts;
+ALTER TABLE arts_old RENAME TO arts;
+CREATE INDEX idx_arts_deleted_at ON arts(deleted_at);
+
+CREATE TABLE accounts_old (
+    id integer,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    username text,
+    password text,
+    admin numeric,
+    PRIMARY KEY (id)
+);
+INSERT INTO accounts_old (id, created_at, updated_at, deleted_at, username, password, admin)
+    SELECT id, created_at, updated_at, deleted_at, username, password, admin FROM accounts;
+DROP TABLE accounts;
+ALTER TABLE accounts_old RENAME TO accounts;
+CREATE INDEX idx_accounts_deleted_at ON accounts(deleted_at);
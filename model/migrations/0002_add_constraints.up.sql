@@ -0,0 +1,61 @@
+-- SQLite can't add constraints to existing tables, so the tables are rebuilt.
+-- Parents are rebuilt before their children, which keeps every DROP TABLE free of foreign key checks.
+-- Duplicate usernames make the migration fail and have to be resolved by hand.
+
+CREATE TABLE accounts_new (
+    id integer,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    username text NOT NULL,
+    password text,
+    admin numeric,
+    PRIMARY KEY (id)
+);
+INSERT INTO accounts_new (id, created_at, updated_at, deleted_at, username, password, admin)
+    SELECT id, created_at, updated_at, deleted_at, username, password, admin FROM accounts;
+DROP TABLE accounts;
+ALTER TABLE accounts_new RENAME TO accounts;
+CREATE INDEX idx_accounts_deleted_at ON accounts(deleted_at);
+CREATE UNIQUE INDEX idx_accounts_username ON accounts(username) WHERE deleted_at IS NULL;
+
+-- Arts of missing accounts lose their author and negative quantities become 0.
+CREATE TABLE arts_new (
+    id integer,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    quantity integer,
+    title text,
+    account_id integer,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_arts_account FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE RESTRICT,
+    CONSTRAINT chk_arts_quantity CHECK (quantity >= 0)
+);
+INSERT INTO arts_new (id, created_at, updated_at, deleted_at, quantity, title, account_id)
+    SELECT id, created_at, updated_at, deleted_at, MAX(quantity, 0), title,
+        CASE WHEN account_id IN (SELECT id FROM accounts) THEN account_id END
+    FROM arts;
+DROP TABLE arts;
+ALTER TABLE arts_new RENAME TO arts;
+CREATE INDEX idx_arts_deleted_at ON arts(deleted_at);
+CREATE INDEX idx_arts_account_id ON arts(account_id);
+
+-- Revisions of missing arts are dropped.
+CREATE TABLE art_revisions_new (
+    id integer,
+    created_at datetime,
+    art_id integer NOT NULL,
+    number integer,
+    quantity integer,
+    title text,
+    account_id integer,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_art_revisions_art FOREIGN KEY (art_id) REFERENCES arts(id) ON DELETE CASCADE
+);
+INSERT INTO art_revisions_new (id, created_at, art_id, number, quantity, title, account_id)
+    SELECT id, created_at, art_id, number, quantity, title, account_id FROM art_revisions
+    WHERE art_id IN (SELECT id FROM arts);
+DROP TABLE art_revisions;
+ALTER TABLE art_revisions_new RENAME TO art_revisions;
+CREATE UNIQUE INDEX idx_art_revision ON art_revisions(art_id, number);
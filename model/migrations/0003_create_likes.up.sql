@@ -0,0 +1,12 @@
+-- An account likes an art at most once, which keeps like counts right however many requests race.
+CREATE TABLE likes (
+    id integer,
+    created_at datetime,
+    account_id integer NOT NULL,
+    art_id integer NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_likes_account FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE,
+    CONSTRAINT fk_likes_art FOREIGN KEY (art_id) REFERENCES arts(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_likes_account_art ON likes(account_id, art_id);
+CREATE INDEX idx_likes_art_id ON likes(art_id);
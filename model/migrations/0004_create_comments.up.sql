@@ -0,0 +1,19 @@
+-- Replies point to their parent comment on the same art, and go away with it.
+CREATE TABLE comments (
+    id integer,
+    created_at datetime,
+    updated_at datetime,
+    art_id integer NOT NULL,
+    account_id integer NOT NULL,
+    parent_id integer,
+    body text NOT NULL,
+    status text NOT NULL DEFAULT 'visible',
+    PRIMARY KEY (id),
+    CONSTRAINT fk_comments_art FOREIGN KEY (art_id) REFERENCES arts(id) ON DELETE CASCADE,
+    CONSTRAINT fk_comments_account FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE,
+    CONSTRAINT fk_comments_parent FOREIGN KEY (parent_id) REFERENCES comments(id) ON DELETE CASCADE,
+    CONSTRAINT chk_comments_status CHECK (status IN ('visible', 'reported', 'hidden'))
+);
+CREATE INDEX idx_comments_art_id ON comments(art_id);
+CREATE INDEX idx_comments_parent_id ON comments(parent_id);
+CREATE INDEX idx_comments_status ON comments(status);
@@ -0,0 +1,2 @@
+DROP INDEX idx_arts_updated_at;
+DROP TABLE follows;
@@ -0,0 +1,17 @@
+-- An account follows another at most once, and never itself.
+CREATE TABLE follows (
+    id integer,
+    created_at datetime,
+    follower_id integer NOT NULL,
+    followee_id integer NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_follows_follower FOREIGN KEY (follower_id) REFERENCES accounts(id) ON DELETE CASCADE,
+    CONSTRAINT fk_follows_followee FOREIGN KEY (followee_id) REFERENCES accounts(id) ON DELETE CASCADE,
+    CONSTRAINT chk_follows_self CHECK (follower_id <> followee_id)
+);
+CREATE UNIQUE INDEX idx_follows_follower_followee ON follows(follower_id, followee_id);
+CREATE INDEX idx_follows_followee_id ON follows(followee_id);
+
+-- The feed walks the arts from the most recently updated, looking each author up in idx_follows_follower_followee,
+-- which stays cheap however many artists an account follows.
+CREATE INDEX idx_arts_updated_at ON arts(updated_at, id);
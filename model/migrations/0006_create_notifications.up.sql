@@ -0,0 +1,18 @@
+-- Notifications go away with the account they are for, the account that caused them and what they are about.
+CREATE TABLE notifications (
+    id integer,
+    created_at datetime,
+    account_id integer NOT NULL,
+    actor_id integer NOT NULL,
+    type text NOT NULL,
+    art_id integer NOT NULL,
+    comment_id integer,
+    read_at datetime,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_notifications_account FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE,
+    CONSTRAINT fk_notifications_actor FOREIGN KEY (actor_id) REFERENCES accounts(id) ON DELETE CASCADE,
+    CONSTRAINT fk_notifications_art FOREIGN KEY (art_id) REFERENCES arts(id) ON DELETE CASCADE,
+    CONSTRAINT fk_notifications_comment FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_notifications_account_id ON notifications(account_id, id);
+CREATE INDEX idx_notifications_unread ON notifications(account_id, id) WHERE read_at IS NULL;
@@ -0,0 +1,31 @@
+-- Deliveries are queued per webhook and go away with it. Pending deliveries are polled by next_attempt_at.
+CREATE TABLE webhooks (
+    id integer,
+    created_at datetime,
+    updated_at datetime,
+    url text NOT NULL,
+    events text NOT NULL,
+    secret text NOT NULL,
+    disabled numeric NOT NULL DEFAULT false,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE webhook_deliveries (
+    id integer,
+    created_at datetime,
+    webhook_id integer NOT NULL,
+    event text NOT NULL,
+    payload text NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at datetime NOT NULL,
+    response_status integer NOT NULL DEFAULT 0,
+    error text NOT NULL DEFAULT '',
+    delivered_at datetime,
+    replay_of integer NOT NULL DEFAULT 0,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_webhook_deliveries_webhook FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE,
+    CONSTRAINT chk_webhook_deliveries_status CHECK (status IN ('pending', 'delivered', 'failed'))
+);
+CREATE INDEX idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, id);
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1 @@
+DROP TABLE art_events;
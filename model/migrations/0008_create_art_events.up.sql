@@ -0,0 +1,11 @@
+-- The log of the changes to the catalog streamed by GET /events, pruned by age rather than along with the arts.
+-- Ids are never reused, even once every event is pruned, since clients resume the stream from them.
+CREATE TABLE art_events (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    type text NOT NULL,
+    art_id integer NOT NULL,
+    art text NOT NULL,
+    previous_quantity integer
+);
+CREATE INDEX idx_art_events_created_at ON art_events(created_at);
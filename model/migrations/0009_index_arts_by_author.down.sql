@@ -0,0 +1,2 @@
+CREATE INDEX idx_arts_updated_at ON arts(updated_at, id);
+DROP INDEX idx_arts_account_id_updated_at;
@@ -0,0 +1,4 @@
+-- The feed reads the newest arts of each followed account along this index and merges them, so that it only reads
+-- the arts it returns, however many accounts are followed and however few of the arts are theirs.
+CREATE INDEX idx_arts_account_id_updated_at ON arts(account_id, updated_at, id);
+DROP INDEX idx_arts_updated_at;
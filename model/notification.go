@@ -0,0 +1,115 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"gorm.io/gorm"
+)
+
+type NotificationDB struct {
+	db *gorm.DB
+}
+
+// A notification for an account. CommentID is zero, and NULL in the database, for notifications that aren't about a comment.
+type Notification struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	AccountID uint
+	ActorID   uint
+	Type      string
+	ArtID     uint
+	CommentID uint
+	// When the account read the notification, nil while unread.
+	ReadAt *time.Time
+}
+
+func (model *Notification) ToDto() *dto.NotificationDto {
+	return &dto.NotificationDto{
+		Id:        model.ID,
+		AccountId: model.AccountID,
+		ActorId:   model.ActorID,
+		Type:      model.Type,
+		ArtId:     model.ArtID,
+		CommentId: model.CommentID,
+		Read:      model.ReadAt != nil,
+		CreatedAt: model.CreatedAt,
+	}
+}
+
+// The tables are created by the migrations, see Migrator.
+func (db *NotificationDB) Init(database *DB) error {
+	db.db = database.GormDB
+	return nil
+}
+
+// Creates an unread notification.
+func (db *NotificationDB) CreateNotification(ctx context.Context, notification dto.NotificationDto) (*dto.NotificationDto, error) {
+	model := Notification{
+		AccountID: notification.AccountId,
+		ActorID:   notification.ActorId,
+		Type:      notification.Type,
+		ArtID:     notification.ArtId,
+		CommentID: notification.CommentId,
+	}
+
+	query := db.db.WithContext(ctx)
+	if model.CommentID == 0 {
+		query = query.Omit("CommentID")
+	}
+	if err := query.Create(&model).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return model.ToDto(), nil
+}
+
+// Gets at most limit notifications of an account whose id is lower than before, newest first.
+// A before of 0 starts from the newest notification and a limit of 0 gets every one of them.
+func (db *NotificationDB) GetNotifications(ctx context.Context, accountId uint, unreadOnly bool, before uint, limit int) ([]dto.NotificationDto, error) {
+	query := db.db.WithContext(ctx).Where("account_id = ?", accountId).Order("id DESC")
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	if before != 0 {
+		query = query.Where("id < ?", before)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var models []Notification
+	if err := query.Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	notifications := []dto.NotificationDto{}
+	for _, model := range models {
+		notifications = append(notifications, *model.ToDto())
+	}
+	return notifications, nil
+}
+
+// Marks a notification of an account as read. Notifications of other accounts are reported as missing.
+func (db *NotificationDB) MarkRead(ctx context.Context, accountId uint, id uint) (*dto.NotificationDto, error) {
+	err := db.db.WithContext(ctx).Model(&Notification{}).
+		Where("id = ? AND account_id = ? AND read_at IS NULL", id, accountId).
+		Update("read_at", time.Now()).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var model Notification
+	if err := db.db.WithContext(ctx).Where("account_id = ?", accountId).First(&model, id).Error; err != nil {
+		return nil, err
+	}
+	return model.ToDto(), nil
+}
+
+// Marks every unread notification of an account as read and returns how many there were.
+func (db *NotificationDB) MarkAllRead(ctx context.Context, accountId uint) (int64, error) {
+	result := db.db.WithContext(ctx).Model(&Notification{}).
+		Where("account_id = ? AND read_at IS NULL", accountId).
+		Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,100 @@
+package model_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifications(t *testing.T) {
+	accountDB, gormDB := AccountDBInit(t)
+	artDB := ArtDBInit(t, gormDB)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+	db := model.DB{GormDB: gormDB}
+	var notificationDB model.NotificationDB
+	require.NoError(t, notificationDB.Init(&db))
+	var commentDB model.CommentDB
+	require.NoError(t, commentDB.Init(&db))
+	ctx := context.Background()
+
+	author, art := createUserAndArt(t, accountDB, artDB, "author")
+	fan, _ := createUserAndArt(t, accountDB, artDB, "fan")
+	comment, err := commentDB.CreateComment(ctx, dto.CommentDto{ArtId: art.Id, AuthorId: fan.Id, Body: "body"})
+	require.NoError(t, err)
+
+	ids := []uint{}
+	for _, notification := range []dto.NotificationDto{
+		{AccountId: author.Id, ActorId: fan.Id, Type: dto.NotificationLike, ArtId: art.Id},
+		{AccountId: author.Id, ActorId: fan.Id, Type: dto.NotificationComment, ArtId: art.Id, CommentId: comment.Id},
+		{AccountId: fan.Id, ActorId: author.Id, Type: dto.NotificationReply, ArtId: art.Id, CommentId: comment.Id},
+		{AccountId: author.Id, ActorId: fan.Id, Type: dto.NotificationLike, ArtId: art.Id},
+	} {
+		created, err := notificationDB.CreateNotification(ctx, notification)
+		require.NoError(t, err)
+		assert.False(t, created.Read)
+		ids = append(ids, created.Id)
+	}
+
+	// SQLite doesn't tell which foreign key failed, here the one to the comment
+	_, err = notificationDB.CreateNotification(ctx, dto.NotificationDto{AccountId: author.Id, ActorId: fan.Id, Type: dto.NotificationComment, ArtId: art.Id, CommentId: 1000})
+	assert.ErrorIs(t, err, model.ErrReferenceNotFound)
+	assert.NotErrorIs(t, err, model.ErrAccountNotFound)
+
+	getIds := func(unreadOnly bool, before uint, limit int) []uint {
+		notifications, err := notificationDB.GetNotifications(ctx, author.Id, unreadOnly, before, limit)
+		require.NoError(t, err)
+		got := []uint{}
+		for _, notification := range notifications {
+			got = append(got, notification.Id)
+		}
+		return got
+	}
+	assert.Equal(t, []uint{ids[3], ids[1], ids[0]}, getIds(false, 0, 0))
+	assert.Equal(t, []uint{ids[3], ids[1]}, getIds(false, 0, 2))
+	assert.Equal(t, []uint{ids[0]}, getIds(false, ids[1], 2))
+
+	t.Run("MarkRead", func(t *testing.T) {
+		read, err := notificationDB.MarkRead(ctx, author.Id, ids[1])
+		if assert.NoError(t, err) {
+			assert.True(t, read.Read)
+			assert.Equal(t, comment.Id, read.CommentId)
+		}
+		// marking again keeps it read
+		_, err = notificationDB.MarkRead(ctx, author.Id, ids[1])
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{ids[3], ids[0]}, getIds(true, 0, 0))
+
+		// notifications of other accounts can't be marked
+		_, err = notificationDB.MarkRead(ctx, author.Id, ids[2])
+		assert.ErrorIs(t, err, model.ErrNotFound)
+		_, err = notificationDB.MarkRead(ctx, author.Id, 1000)
+		assert.ErrorIs(t, err, model.ErrNotFound)
+	})
+
+	t.Run("MarkAllRead", func(t *testing.T) {
+		marked, err := notificationDB.MarkAllRead(ctx, author.Id)
+		if assert.NoError(t, err) {
+			assert.Equal(t, int64(2), marked)
+		}
+		assert.Empty(t, getIds(true, 0, 0))
+
+		// the notifications of other accounts stay unread
+		unread, err := notificationDB.GetNotifications(ctx, fan.Id, true, 0, 0)
+		if assert.NoError(t, err) {
+			assert.Len(t, unread, 1)
+		}
+	})
+
+	t.Run("Cascade", func(t *testing.T) {
+		_, err := commentDB.DeleteComment(ctx, comment.Id)
+		require.NoError(t, err)
+		assert.Equal(t, []uint{ids[3], ids[0]}, getIds(false, 0, 0))
+	})
+}
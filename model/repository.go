@@ -0,0 +1,202 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/dto"
+)
+
+// Storage of arts and their revisions.
+type ArtRepository interface {
+	CreateArt(ctx context.Context, art dto.ArtDto) (*dto.ArtDto, error)
+	GetArt(ctx context.Context, id uint) (*dto.ArtDto, error)
+	GetArts(ctx context.Context) ([]dto.ArtDto, error)
+	GetArtsAfter(ctx context.Context, after uint, limit int) ([]dto.ArtDto, error)
+	ExportArts(ctx context.Context, filter ArtFilter, each func(dto.ArtExportDto) error) error
+	UpdateArt(ctx context.Context, art dto.ArtDto) (*dto.ArtDto, error)
+	SellArt(ctx context.Context, id uint, quantity int) (*dto.ArtDto, error)
+	DeleteArt(ctx context.Context, id uint) (*dto.ArtDto, error)
+	CountArts(ctx context.Context) (int64, error)
+
+	GetArtRevisions(ctx context.Context, artId uint) ([]dto.ArtRevisionDto, error)
+	GetArtRevision(ctx context.Context, artId uint, number uint) (*dto.ArtRevisionDto, error)
+	DiffArtRevisions(ctx context.Context, artId uint, from uint, to uint) (map[string]dto.FieldChange, error)
+	RestoreArtRevision(ctx context.Context, artId uint, number uint) (*dto.ArtDto, error)
+}
+
+// Storage of accounts.
+type AccountRepository interface {
+	CreateAccount(ctx context.Context, account dto.AccountDto) (*dto.AccountDto, error)
+	GetAccountById(ctx context.Context, id uint) (*dto.AccountDto, error)
+	GetAccountByUsername(ctx context.Context, username string) (*dto.AccountDto, error)
+	SetAdmin(ctx context.Context, id uint, admin bool) (*dto.AccountDto, error)
+	CountAccounts(ctx context.Context) (int64, error)
+}
+
+// Storage of the likes accounts give arts.
+type LikeRepository interface {
+	LikeArt(ctx context.Context, accountId uint, artId uint) (bool, error)
+	UnlikeArt(ctx context.Context, accountId uint, artId uint) error
+	GetLikes(ctx context.Context, accountId uint, artIds []uint) (map[uint]int64, map[uint]bool, error)
+	GetLikedArts(ctx context.Context, accountId uint) ([]dto.ArtDto, error)
+}
+
+// Storage of the accounts following each other, and of the feeds they make.
+type FollowRepository interface {
+	Follow(ctx context.Context, followerId uint, followeeId uint) error
+	Unfollow(ctx context.Context, followerId uint, followeeId uint) error
+	GetFollowers(ctx context.Context, accountId uint) ([]dto.AccountDto, error)
+	GetFollowing(ctx context.Context, accountId uint) ([]dto.AccountDto, error)
+	GetFeed(ctx context.Context, accountId uint, cursor string, limit int) ([]dto.ArtDto, string, error)
+}
+
+// Storage of the comments on arts.
+type CommentRepository interface {
+	CreateComment(ctx context.Context, comment dto.CommentDto) (*dto.CommentDto, error)
+	GetComment(ctx context.Context, id uint) (*dto.CommentDto, error)
+	GetComments(ctx context.Context, filter CommentFilter, after uint, limit int) ([]dto.CommentDto, error)
+	UpdateComment(ctx context.Context, id uint, body string) (*dto.CommentDto, error)
+	ReportComment(ctx context.Context, id uint) (*dto.CommentDto, error)
+	SetCommentStatus(ctx context.Context, id uint, status string) (*dto.CommentDto, error)
+	DeleteComment(ctx context.Context, id uint) (*dto.CommentDto, error)
+}
+
+// Storage of the notifications of accounts.
+type NotificationRepository interface {
+	CreateNotification(ctx context.Context, notification dto.NotificationDto) (*dto.NotificationDto, error)
+	GetNotifications(ctx context.Context, accountId uint, unreadOnly bool, before uint, limit int) ([]dto.NotificationDto, error)
+	MarkRead(ctx context.Context, accountId uint, id uint) (*dto.NotificationDto, error)
+	MarkAllRead(ctx context.Context, accountId uint) (int64, error)
+}
+
+// Storage of the webhooks and of the queue of their deliveries.
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, webhook dto.WebhookDto) (*dto.WebhookDto, error)
+	GetWebhooks(ctx context.Context) ([]dto.WebhookDto, error)
+	GetWebhook(ctx context.Context, id uint) (*dto.WebhookDto, error)
+	UpdateWebhook(ctx context.Context, webhook dto.WebhookDto) (*dto.WebhookDto, error)
+	DeleteWebhook(ctx context.Context, id uint) (*dto.WebhookDto, error)
+	EnqueueDeliveries(ctx context.Context, event string, payload []byte) ([]dto.WebhookDeliveryDto, error)
+	ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]dto.WebhookDeliveryDto, error)
+	RecordAttempt(ctx context.Context, delivery dto.WebhookDeliveryDto) (*dto.WebhookDeliveryDto, error)
+	GetDeliveries(ctx context.Context, webhookId uint, before uint, limit int) ([]dto.WebhookDeliveryDto, error)
+	ReplayDelivery(ctx context.Context, webhookId uint, id uint) (*dto.WebhookDeliveryDto, error)
+}
+
+// Log of the changes to the catalog, which clients of GET /events resume from.
+type ArtEventRepository interface {
+	AppendArtEvent(ctx context.Context, event dto.ArtEventDto) (*dto.ArtEventDto, error)
+	GetArtEventsAfter(ctx context.Context, after uint, limit int) ([]dto.ArtEventDto, error)
+	GetArtEventBounds(ctx context.Context) (uint, uint, error)
+	PruneArtEvents(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Append-only storage of the audit trail.
+type AuditRepository interface {
+	Record(ctx context.Context, entry dto.AuditEntryDto) (*dto.AuditEntryDto, error)
+	GetEntries(ctx context.Context, entity string, id uint) ([]dto.AuditEntryDto, error)
+}
+
+// Runs operations of several repositories as a single unit of work.
+type Transactor interface {
+	Transaction(ctx context.Context, f func(tx Repositories) error) error
+}
+
+var _ ArtRepository = &ArtDB{}
+var _ AccountRepository = &AccountDB{}
+var _ LikeRepository = &LikeDB{}
+var _ CommentRepository = &CommentDB{}
+var _ FollowRepository = &FollowDB{}
+var _ NotificationRepository = &NotificationDB{}
+var _ WebhookRepository = &WebhookDB{}
+var _ ArtEventRepository = &ArtEventDB{}
+var _ AuditRepository = &AuditDB{}
+var _ Transactor = &DB{}
+
+// Every repository the gallery is built on.
+type Repositories struct {
+	Arts          ArtRepository
+	Accounts      AccountRepository
+	Likes         LikeRepository
+	Comments      CommentRepository
+	Follows       FollowRepository
+	Notifications NotificationRepository
+	Webhooks      WebhookRepository
+	ArtEvents     ArtEventRepository
+	Audit         AuditRepository
+
+	// Runs operations of the repositories in one transaction, see Repositories.Transaction.
+	Transactor Transactor
+
+	// Database backing the repositories, nil if they aren't backed by one.
+	DB *DB
+}
+
+// Creates the repositories backed by db.
+func (db *DB) Repositories() (Repositories, error) {
+	artDB := &ArtDB{}
+	if err := artDB.Init(db); err != nil {
+		return Repositories{}, err
+	}
+
+	accountDB := &AccountDB{}
+	if err := accountDB.Init(db); err != nil {
+		return Repositories{}, err
+	}
+
+	likeDB := &LikeDB{}
+	if err := likeDB.Init(db); err != nil {
+		return Repositories{}, err
+	}
+
+	commentDB := &CommentDB{}
+	if err := commentDB.Init(db); err != nil {
+		return Repositories{}, err
+	}
+
+	followDB := &FollowDB{}
+	if err := followDB.Init(db); err != nil {
+		return Repositories{}, err
+	}
+
+	notificationDB := &NotificationDB{}
+	if err := notificationDB.Init(db); err != nil {
+		return Repositories{}, err
+	}
+
+	webhookDB := &WebhookDB{}
+	if err := webhookDB.Init(db); err != nil {
+		return Repositories{}, err
+	}
+
+	artEventDB := &ArtEventDB{}
+	if err := artEventDB.Init(db); err != nil {
+		return Repositories{}, err
+	}
+
+	auditDB := &AuditDB{}
+	if err := auditDB.Init(db); err != nil {
+		return Repositories{}, err
+	}
+
+	return Repositories{
+		Arts:          artDB,
+		Accounts:      accountDB,
+		Likes:         likeDB,
+		Comments:      commentDB,
+		Follows:       followDB,
+		Notifications: notificationDB,
+		Webhooks:      webhookDB,
+		ArtEvents:     artEventDB,
+		Audit:         auditDB,
+		Transactor:    db,
+		DB:            db,
+	}, nil
+}
+
+// Runs f with repositories that share one transaction.
+// The transaction is committed if f returns nil and rolled back otherwise.
+func (repos Repositories) Transaction(ctx context.Context, f func(tx Repositories) error) error {
+	return repos.Transactor.Transaction(ctx, f)
+}
@@ -0,0 +1,138 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/dto"
+)
+
+// A full snapshot of an Art taken right before it was updated.
+// Revisions of an art are numbered from 1 in the order they were taken.
+type ArtRevision struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	ArtID     uint `gorm:"uniqueIndex:idx_art_revision"`
+	Number    uint `gorm:"uniqueIndex:idx_art_revision"`
+	Quantity  int
+	Title     string
+	AccountID uint
+}
+
+func (model *ArtRevision) ToDto() *dto.ArtRevisionDto {
+	return &dto.ArtRevisionDto{
+		Number:    model.Number,
+		CreatedAt: model.CreatedAt,
+		Art: dto.ArtDto{
+			Id:       model.ArtID,
+			Quantity: model.Quantity,
+			Title:    model.Title,
+			AuthorId: model.AccountID,
+		},
+	}
+}
+
+// Stores the current state of art as its next revision.
+func (db *ArtDB) snapshotArt(ctx context.Context, art Art) error {
+	var latest ArtRevision
+	if err := db.db.WithContext(ctx).Where("art_id = ?", art.ID).Order("number desc").Limit(1).Find(&latest).Error; err != nil {
+		return err
+	}
+
+	revision := ArtRevision{
+		ArtID:     art.ID,
+		Number:    latest.Number + 1,
+		Quantity:  art.Quantity,
+		Title:     art.Title,
+		AccountID: art.AccountID,
+	}
+	return db.db.WithContext(ctx).Create(&revision).Error
+}
+
+// Gets every revision of an existing art, oldest first.
+func (db *ArtDB) GetArtRevisions(ctx context.Context, artId uint) ([]dto.ArtRevisionDto, error) {
+	var models []ArtRevision
+
+	if _, err := db.GetArt(ctx, artId); err != nil {
+		return []dto.ArtRevisionDto{}, err
+	} else if err := db.db.WithContext(ctx).Where("art_id = ?", artId).Order("number").Find(&models).Error; err != nil {
+		return []dto.ArtRevisionDto{}, err
+	} else {
+		revisions := []dto.ArtRevisionDto{}
+		for _, m := range models {
+			revisions = append(revisions, *m.ToDto())
+		}
+		return revisions, nil
+	}
+}
+
+// Gets a single revision of an existing art by its number.
+func (db *ArtDB) GetArtRevision(ctx context.Context, artId uint, number uint) (*dto.ArtRevisionDto, error) {
+	var model ArtRevision
+
+	if _, err := db.GetArt(ctx, artId); err != nil {
+		return nil, err
+	} else if err := db.db.WithContext(ctx).First(&model, "art_id = ? AND number = ?", artId, number).Error; err != nil {
+		return nil, err
+	} else {
+		return model.ToDto(), nil
+	}
+}
+
+// Compares two revisions of an art field by field.
+// A zero number stands for the current state of the art.
+func (db *ArtDB) DiffArtRevisions(ctx context.Context, artId uint, from uint, to uint) (map[string]dto.FieldChange, error) {
+	fromArt, err := db.getArtRevisionOrCurrent(ctx, artId, from)
+	if err != nil {
+		return nil, err
+	}
+	toArt, err := db.getArtRevisionOrCurrent(ctx, artId, to)
+	if err != nil {
+		return nil, err
+	}
+
+	fromJSON, err := json.Marshal(fromArt)
+	if err != nil {
+		return nil, err
+	}
+	toJSON, err := json.Marshal(toArt)
+	if err != nil {
+		return nil, err
+	}
+
+	return dto.DiffJSON(fromJSON, toJSON)
+}
+
+// Brings the title and quantity of an art back to those of one of its revisions.
+// The current author is kept and the state being replaced is stored as a new revision, so a restore can itself be undone.
+func (db *ArtDB) RestoreArtRevision(ctx context.Context, artId uint, number uint) (*dto.ArtDto, error) {
+	var restored *dto.ArtDto
+
+	err := db.transaction(ctx, func(tx *ArtDB) error {
+		var artModel Art
+		if revision, err := tx.GetArtRevision(ctx, artId, number); err != nil {
+			return err
+		} else if err := tx.db.First(&artModel, artId).Error; err != nil {
+			return err
+		} else if err := tx.snapshotArt(ctx, artModel); err != nil {
+			return err
+		} else if err := tx.db.Model(&artModel).Select("Quantity", "Title").Updates(Art{Quantity: revision.Art.Quantity, Title: revision.Art.Title}).Error; err != nil {
+			return err
+		} else {
+			restored, err = tx.GetArt(ctx, artId)
+			return err
+		}
+	})
+	return restored, err
+}
+
+func (db *ArtDB) getArtRevisionOrCurrent(ctx context.Context, artId uint, number uint) (*dto.ArtDto, error) {
+	if number == 0 {
+		return db.GetArt(ctx, artId)
+	} else if revision, err := db.GetArtRevision(ctx, artId, number); err != nil {
+		return nil, err
+	} else {
+		return &revision.Art, nil
+	}
+}
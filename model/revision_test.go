@@ -0,0 +1,131 @@
+package model_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtRevisions(t *testing.T) {
+	accountDB, gormDB := AccountDBInit(t)
+	artDB := ArtDBInit(t, gormDB)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+	_, artDto := createUserAndArt(t, accountDB, artDB, "username")
+
+	// no revisions before the first update
+	revisions, err := artDB.GetArtRevisions(context.Background(), artDto.Id)
+	if assert.NoError(t, err) {
+		assert.Len(t, revisions, 0)
+	}
+
+	// every update stores the previous state
+	for _, title := range []string{"title2", "title3"} {
+		_, err := artDB.UpdateArt(context.Background(), dto.ArtDto{
+			Id:       artDto.Id,
+			Quantity: 2,
+			Title:    title,
+			AuthorId: artDto.AuthorId,
+		})
+		require.NoError(t, err)
+	}
+	revisions, err = artDB.GetArtRevisions(context.Background(), artDto.Id)
+	if assert.NoError(t, err) && assert.Len(t, revisions, 2) {
+		assert.Equal(t, uint(1), revisions[0].Number)
+		assert.Equal(t, artDto, revisions[0].Art)
+		assert.Equal(t, uint(2), revisions[1].Number)
+		assert.Equal(t, "title2", revisions[1].Art.Title)
+	}
+
+	// get a single revision
+	revision, err := artDB.GetArtRevision(context.Background(), artDto.Id, 1)
+	if assert.NoError(t, err) && assert.NotNil(t, revision) {
+		assert.Equal(t, artDto, revision.Art)
+	}
+
+	// don't get non-existent revision
+	revision, err = artDB.GetArtRevision(context.Background(), artDto.Id, 420)
+	assert.Error(t, err)
+	assert.Nil(t, revision)
+
+	// don't get revisions of non-existent art
+	_, err = artDB.GetArtRevisions(context.Background(), 420)
+	assert.Error(t, err)
+}
+
+func TestDiffArtRevisions(t *testing.T) {
+	accountDB, gormDB := AccountDBInit(t)
+	artDB := ArtDBInit(t, gormDB)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+	_, artDto := createUserAndArt(t, accountDB, artDB, "username")
+
+	_, err := artDB.UpdateArt(context.Background(), dto.ArtDto{
+		Id:       artDto.Id,
+		Quantity: 2,
+		Title:    "title2",
+		AuthorId: artDto.AuthorId,
+	})
+	require.NoError(t, err)
+
+	// diff between the first revision and the current art
+	diff, err := artDB.DiffArtRevisions(context.Background(), artDto.Id, 1, 0)
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]dto.FieldChange{
+			"title":    {Before: "title", After: "title2"},
+			"quantity": {Before: float64(1), After: float64(2)},
+		}, diff)
+	}
+
+	// a revision doesn't differ from itself
+	diff, err = artDB.DiffArtRevisions(context.Background(), artDto.Id, 1, 1)
+	if assert.NoError(t, err) {
+		assert.Len(t, diff, 0)
+	}
+
+	// can't diff against non-existent revision
+	_, err = artDB.DiffArtRevisions(context.Background(), artDto.Id, 1, 420)
+	assert.Error(t, err)
+}
+
+func TestRestoreArtRevision(t *testing.T) {
+	accountDB, gormDB := AccountDBInit(t)
+	artDB := ArtDBInit(t, gormDB)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+	_, artDto := createUserAndArt(t, accountDB, artDB, "username")
+
+	_, err := artDB.UpdateArt(context.Background(), dto.ArtDto{
+		Id:       artDto.Id,
+		Quantity: 2,
+		Title:    "title2",
+		AuthorId: artDto.AuthorId,
+	})
+	require.NoError(t, err)
+
+	// successfully restore the original art
+	restored, err := artDB.RestoreArtRevision(context.Background(), artDto.Id, 1)
+	if assert.NoError(t, err) && assert.NotNil(t, restored) {
+		assert.Equal(t, artDto, *restored)
+	}
+
+	// the replaced state is kept as a revision
+	revisions, err := artDB.GetArtRevisions(context.Background(), artDto.Id)
+	if assert.NoError(t, err) && assert.Len(t, revisions, 2) {
+		assert.Equal(t, "title2", revisions[1].Art.Title)
+	}
+
+	// can't restore non-existent revision
+	restored, err = artDB.RestoreArtRevision(context.Background(), artDto.Id, 420)
+	assert.Error(t, err)
+	assert.Nil(t, restored)
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Runs f as a single unit of work, with repositories that share one transaction.
+// The transaction is committed if f returns nil and rolled back otherwise.
+// Called on repositories that are already in a transaction, it uses a savepoint instead.
+// Constraint violations are reported as domain errors, see ErrUsernameTaken.
+func (db *DB) Transaction(ctx context.Context, f func(tx Repositories) error) error {
+	return translateError(db.GormDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if repos, err := (&DB{GormDB: tx}).Repositories(); err != nil {
+			return err
+		} else {
+			return f(repos)
+		}
+	}))
+}
+
+// Runs the steps of a multi-step art operation in one transaction, see DB.Transaction.
+func (db *ArtDB) transaction(ctx context.Context, f func(tx *ArtDB) error) error {
+	return translateError(db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return f(&ArtDB{db: tx})
+	}))
+}
+
+// Runs the steps of a multi-step account operation in one transaction, see DB.Transaction.
+func (db *AccountDB) transaction(ctx context.Context, f func(tx *AccountDB) error) error {
+	return translateError(db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return f(&AccountDB{db: tx})
+	}))
+}
+
+// Runs the steps of a multi-step webhook operation in one transaction, see DB.Transaction.
+func (db *WebhookDB) transaction(ctx context.Context, f func(tx *WebhookDB) error) error {
+	return translateError(db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return f(&WebhookDB{db: tx})
+	}))
+}
@@ -0,0 +1,219 @@
+package model_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var errInjected = errors.New("injected failure")
+
+// Makes every create, update or delete statement on table fail right after it is executed, before its transaction ends, until the returned function is called.
+func injectFailure(t *testing.T, gormDB *gorm.DB, statement string, table string) func() {
+	fail := func(db *gorm.DB) {
+		if db.Statement.Table == table {
+			db.AddError(errInjected)
+		}
+	}
+
+	var err error
+	var remove func(name string) error
+	switch statement {
+	case "create":
+		err = gormDB.Callback().Create().After("gorm:create").Before("gorm:commit_or_rollback_transaction").Register("test:inject_failure", fail)
+		remove = gormDB.Callback().Create().Remove
+	case "update":
+		err = gormDB.Callback().Update().After("gorm:update").Before("gorm:commit_or_rollback_transaction").Register("test:inject_failure", fail)
+		remove = gormDB.Callback().Update().Remove
+	case "delete":
+		err = gormDB.Callback().Delete().After("gorm:delete").Before("gorm:commit_or_rollback_transaction").Register("test:inject_failure", fail)
+		remove = gormDB.Callback().Delete().Remove
+	default:
+		t.Fatalf("unknown statement %s", statement)
+	}
+	require.NoError(t, err)
+
+	return func() {
+		require.NoError(t, remove("test:inject_failure"))
+	}
+}
+
+func TestCreateArtRollback(t *testing.T) {
+	accountDB, gormDB := AccountDBInit(t)
+	artDB := ArtDBInit(t, gormDB)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+
+	account := CreateAccount(t, accountDB, "username", "password")
+
+	restore := injectFailure(t, gormDB, "create", "arts")
+	_, err := artDB.CreateArt(context.Background(), dto.ArtDto{Quantity: 1, Title: "title", AuthorId: account.Id})
+	restore()
+	assert.ErrorIs(t, err, errInjected)
+
+	// the inserted art is rolled back
+	count, err := artDB.CountArts(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+
+	// the next art is created as usual
+	CreateArt(t, artDB, dto.ArtDto{Quantity: 1, Title: "title", AuthorId: account.Id})
+}
+
+func TestUpdateArtRollback(t *testing.T) {
+	accountDB, gormDB := AccountDBInit(t)
+	artDB := ArtDBInit(t, gormDB)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+
+	account, art := createUserAndArt(t, accountDB, artDB, "username")
+
+	restore := injectFailure(t, gormDB, "update", "arts")
+	_, err := artDB.UpdateArt(context.Background(), dto.ArtDto{Id: art.Id, Quantity: 2, Title: "new title", AuthorId: account.Id})
+	restore()
+	assert.ErrorIs(t, err, errInjected)
+
+	// neither the update nor its revision is kept
+	current, err := artDB.GetArt(context.Background(), art.Id)
+	require.NoError(t, err)
+	assert.Equal(t, art, *current)
+
+	revisions, err := artDB.GetArtRevisions(context.Background(), art.Id)
+	require.NoError(t, err)
+	assert.Empty(t, revisions)
+}
+
+func TestDeleteArtRollback(t *testing.T) {
+	accountDB, gormDB := AccountDBInit(t)
+	artDB := ArtDBInit(t, gormDB)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+
+	_, art := createUserAndArt(t, accountDB, artDB, "username")
+
+	restore := injectFailure(t, gormDB, "delete", "arts")
+	_, err := artDB.DeleteArt(context.Background(), art.Id)
+	restore()
+	assert.ErrorIs(t, err, errInjected)
+
+	// the art is still there and still belongs to its author
+	current, err := artDB.GetArt(context.Background(), art.Id)
+	require.NoError(t, err)
+	assert.Equal(t, art, *current)
+}
+
+func TestRestoreArtRevisionRollback(t *testing.T) {
+	accountDB, gormDB := AccountDBInit(t)
+	artDB := ArtDBInit(t, gormDB)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+
+	account, art := createUserAndArt(t, accountDB, artDB, "username")
+	updated, err := artDB.UpdateArt(context.Background(), dto.ArtDto{Id: art.Id, Quantity: 2, Title: "new title", AuthorId: account.Id})
+	require.NoError(t, err)
+
+	restore := injectFailure(t, gormDB, "update", "arts")
+	_, err = artDB.RestoreArtRevision(context.Background(), art.Id, 1)
+	restore()
+	assert.ErrorIs(t, err, errInjected)
+
+	current, err := artDB.GetArt(context.Background(), art.Id)
+	require.NoError(t, err)
+	assert.Equal(t, *updated, *current)
+
+	revisions, err := artDB.GetArtRevisions(context.Background(), art.Id)
+	require.NoError(t, err)
+	assert.Len(t, revisions, 1)
+}
+
+func TestCreateAccountRollback(t *testing.T) {
+	accountDB, gormDB := AccountDBInit(t)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+
+	restore := injectFailure(t, gormDB, "create", "accounts")
+	_, err := accountDB.CreateAccount(context.Background(), dto.AccountDto{Username: "username", Password: "password"})
+	restore()
+	assert.ErrorIs(t, err, errInjected)
+
+	// the username is still free
+	_, err = accountDB.GetAccountByUsername(context.Background(), "username")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	CreateAccount(t, accountDB, "username", "password")
+}
+
+func TestTransaction(t *testing.T) {
+	_, gormDB := AccountDBInit(t)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+
+	db := model.DB{GormDB: gormDB}
+	repos, err := db.Repositories()
+	require.NoError(t, err)
+
+	createBoth := func(tx model.Repositories, username string) error {
+		if account, err := tx.Accounts.CreateAccount(context.Background(), dto.AccountDto{Username: username, Password: "password"}); err != nil {
+			return err
+		} else {
+			_, err := tx.Arts.CreateArt(context.Background(), dto.ArtDto{Quantity: 1, Title: "title", AuthorId: account.Id})
+			return err
+		}
+	}
+
+	// everything is rolled back when f fails
+	err = repos.Transaction(context.Background(), func(tx model.Repositories) error {
+		if err := createBoth(tx, "rolled back"); err != nil {
+			return err
+		}
+		return errInjected
+	})
+	assert.ErrorIs(t, err, errInjected)
+
+	_, err = repos.Accounts.GetAccountByUsername(context.Background(), "rolled back")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	count, err := repos.Arts.CountArts(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+
+	// everything is committed when f succeeds, and a failed nested transaction only undoes its own work
+	err = repos.Transaction(context.Background(), func(tx model.Repositories) error {
+		if err := createBoth(tx, "committed"); err != nil {
+			return err
+		}
+		err := tx.Transaction(context.Background(), func(nested model.Repositories) error {
+			if err := createBoth(nested, "nested"); err != nil {
+				return err
+			}
+			return errInjected
+		})
+		assert.ErrorIs(t, err, errInjected)
+		return nil
+	})
+	require.NoError(t, err)
+
+	_, err = repos.Accounts.GetAccountByUsername(context.Background(), "committed")
+	assert.NoError(t, err)
+	_, err = repos.Accounts.GetAccountByUsername(context.Background(), "nested")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	count, err = repos.Arts.CountArts(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
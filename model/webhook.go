@@ -0,0 +1,318 @@
+package model
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/events"
+	"gorm.io/gorm"
+)
+
+type WebhookDB struct {
+	db *gorm.DB
+}
+
+// A URL events are posted to. Events holds the types of the events, separated by commas.
+type Webhook struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	URL       string
+	Events    string
+	Secret    string
+	Disabled  bool
+}
+
+// An event queued for a webhook.
+type WebhookDelivery struct {
+	ID             uint `gorm:"primarykey"`
+	CreatedAt      time.Time
+	WebhookID      uint
+	Event          string
+	Payload        string
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	ResponseStatus int
+	Error          string
+	DeliveredAt    *time.Time
+	ReplayOf       uint
+}
+
+func (model *Webhook) ToDto() *dto.WebhookDto {
+	return &dto.WebhookDto{
+		Id:        model.ID,
+		URL:       model.URL,
+		Events:    strings.Split(model.Events, ","),
+		Secret:    model.Secret,
+		Disabled:  model.Disabled,
+		CreatedAt: model.CreatedAt,
+		UpdatedAt: model.UpdatedAt,
+	}
+}
+
+func (model *WebhookDelivery) ToDto() *dto.WebhookDeliveryDto {
+	return &dto.WebhookDeliveryDto{
+		Id:             model.ID,
+		WebhookId:      model.WebhookID,
+		Event:          model.Event,
+		Payload:        []byte(model.Payload),
+		Status:         model.Status,
+		Attempts:       model.Attempts,
+		NextAttemptAt:  model.NextAttemptAt,
+		ResponseStatus: model.ResponseStatus,
+		Error:          model.Error,
+		DeliveredAt:    model.DeliveredAt,
+		ReplayOf:       model.ReplayOf,
+		CreatedAt:      model.CreatedAt,
+	}
+}
+
+// The tables are created by the migrations, see Migrator.
+func (db *WebhookDB) Init(database *DB) error {
+	db.db = database.GormDB
+	return nil
+}
+
+// Checks that a webhook has an absolute http or https URL, a secret and at least one known type of event.
+func ValidateWebhook(webhook dto.WebhookDto) error {
+	if u, err := url.Parse(webhook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidWebhook
+	} else if webhook.Secret == "" || len(webhook.Events) == 0 {
+		return ErrInvalidWebhook
+	}
+	for _, event := range webhook.Events {
+		if !events.IsType(event) {
+			return ErrInvalidWebhook
+		}
+	}
+	return nil
+}
+
+func (db *WebhookDB) CreateWebhook(ctx context.Context, webhook dto.WebhookDto) (*dto.WebhookDto, error) {
+	if err := ValidateWebhook(webhook); err != nil {
+		return nil, err
+	}
+
+	model := Webhook{
+		URL:      webhook.URL,
+		Events:   strings.Join(webhook.Events, ","),
+		Secret:   webhook.Secret,
+		Disabled: webhook.Disabled,
+	}
+	if err := db.db.WithContext(ctx).Create(&model).Error; err != nil {
+		return nil, err
+	}
+	return model.ToDto(), nil
+}
+
+// Gets every webhook, oldest first.
+func (db *WebhookDB) GetWebhooks(ctx context.Context) ([]dto.WebhookDto, error) {
+	var models []Webhook
+	if err := db.db.WithContext(ctx).Order("id").Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	webhooks := []dto.WebhookDto{}
+	for _, model := range models {
+		webhooks = append(webhooks, *model.ToDto())
+	}
+	return webhooks, nil
+}
+
+func (db *WebhookDB) GetWebhook(ctx context.Context, id uint) (*dto.WebhookDto, error) {
+	var model Webhook
+	if err := db.db.WithContext(ctx).First(&model, id).Error; err != nil {
+		return nil, err
+	}
+	return model.ToDto(), nil
+}
+
+// Replaces the URL, events and state of a webhook. Its secret is only replaced if a new one is given.
+// Deliveries already queued are sent with the new URL and secret.
+func (db *WebhookDB) UpdateWebhook(ctx context.Context, webhook dto.WebhookDto) (*dto.WebhookDto, error) {
+	var model Webhook
+	err := db.transaction(ctx, func(tx *WebhookDB) error {
+		if err := tx.db.First(&model, webhook.Id).Error; err != nil {
+			return err
+		}
+		if webhook.Secret == "" {
+			webhook.Secret = model.Secret
+		}
+		if err := ValidateWebhook(webhook); err != nil {
+			return err
+		}
+
+		model.URL = webhook.URL
+		model.Events = strings.Join(webhook.Events, ",")
+		model.Secret = webhook.Secret
+		model.Disabled = webhook.Disabled
+		return tx.db.Save(&model).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return model.ToDto(), nil
+}
+
+// Deletes a webhook along with its deliveries.
+func (db *WebhookDB) DeleteWebhook(ctx context.Context, id uint) (*dto.WebhookDto, error) {
+	var model Webhook
+	err := db.transaction(ctx, func(tx *WebhookDB) error {
+		if err := tx.db.First(&model, id).Error; err != nil {
+			return err
+		}
+		return tx.db.Delete(&model).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return model.ToDto(), nil
+}
+
+// Queues payload for every enabled webhook subscribed to event, due right away.
+func (db *WebhookDB) EnqueueDeliveries(ctx context.Context, event string, payload []byte) ([]dto.WebhookDeliveryDto, error) {
+	var webhooks []Webhook
+	if err := db.db.WithContext(ctx).Where("disabled = ?", false).Order("id").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	models := []WebhookDelivery{}
+	for _, webhook := range webhooks {
+		for _, subscribed := range strings.Split(webhook.Events, ",") {
+			if subscribed == event {
+				models = append(models, WebhookDelivery{
+					WebhookID:     webhook.ID,
+					Event:         event,
+					Payload:       string(payload),
+					Status:        dto.DeliveryPending,
+					NextAttemptAt: now,
+				})
+				break
+			}
+		}
+	}
+
+	deliveries := []dto.WebhookDeliveryDto{}
+	if len(models) == 0 {
+		return deliveries, nil
+	}
+	if err := db.db.WithContext(ctx).Create(&models).Error; err != nil {
+		return nil, translateError(err)
+	}
+	for _, model := range models {
+		deliveries = append(deliveries, *model.ToDto())
+	}
+	return deliveries, nil
+}
+
+// Gets at most limit pending deliveries due by now, oldest first, and postpones them by lease.
+// A delivery claimed by a dispatcher that stopped before recording its attempt is thus retried once the lease is over,
+// and isn't claimed twice meanwhile.
+func (db *WebhookDB) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]dto.WebhookDeliveryDto, error) {
+	var models []WebhookDelivery
+	err := db.transaction(ctx, func(tx *WebhookDB) error {
+		err := tx.db.Where("status = ? AND next_attempt_at <= ?", dto.DeliveryPending, now).
+			Order("next_attempt_at, id").
+			Limit(limit).
+			Find(&models).Error
+		if err != nil || len(models) == 0 {
+			return err
+		}
+
+		ids := []uint{}
+		for i := range models {
+			ids = append(ids, models[i].ID)
+			models[i].NextAttemptAt = now.Add(lease)
+		}
+		return tx.db.Model(&WebhookDelivery{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := []dto.WebhookDeliveryDto{}
+	for _, model := range models {
+		deliveries = append(deliveries, *model.ToDto())
+	}
+	return deliveries, nil
+}
+
+// Saves the outcome of an attempt: the status, attempts, next attempt, response status, error and delivery time of delivery.
+func (db *WebhookDB) RecordAttempt(ctx context.Context, delivery dto.WebhookDeliveryDto) (*dto.WebhookDeliveryDto, error) {
+	var model WebhookDelivery
+	err := db.transaction(ctx, func(tx *WebhookDB) error {
+		if err := tx.db.First(&model, delivery.Id).Error; err != nil {
+			return err
+		}
+
+		model.Status = delivery.Status
+		model.Attempts = delivery.Attempts
+		model.NextAttemptAt = delivery.NextAttemptAt
+		model.ResponseStatus = delivery.ResponseStatus
+		model.Error = delivery.Error
+		model.DeliveredAt = delivery.DeliveredAt
+		return tx.db.Save(&model).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return model.ToDto(), nil
+}
+
+// Gets at most limit deliveries of a webhook whose id is lower than before, newest first.
+// A before of 0 starts from the newest delivery and a limit of 0 gets every one of them.
+func (db *WebhookDB) GetDeliveries(ctx context.Context, webhookId uint, before uint, limit int) ([]dto.WebhookDeliveryDto, error) {
+	if err := db.db.WithContext(ctx).Select("id").First(&Webhook{}, webhookId).Error; err != nil {
+		return nil, err
+	}
+
+	query := db.db.WithContext(ctx).Where("webhook_id = ?", webhookId).Order("id DESC")
+	if before != 0 {
+		query = query.Where("id < ?", before)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var models []WebhookDelivery
+	if err := query.Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	deliveries := []dto.WebhookDeliveryDto{}
+	for _, model := range models {
+		deliveries = append(deliveries, *model.ToDto())
+	}
+	return deliveries, nil
+}
+
+// Queues the payload of a delivery of a webhook again, as a new delivery due right away.
+// The replayed delivery is left as it was, so the log keeps every attempt.
+func (db *WebhookDB) ReplayDelivery(ctx context.Context, webhookId uint, id uint) (*dto.WebhookDeliveryDto, error) {
+	var replay WebhookDelivery
+	err := db.transaction(ctx, func(tx *WebhookDB) error {
+		var replayed WebhookDelivery
+		if err := tx.db.Where("webhook_id = ?", webhookId).First(&replayed, id).Error; err != nil {
+			return err
+		}
+
+		replay = WebhookDelivery{
+			WebhookID:     replayed.WebhookID,
+			Event:         replayed.Event,
+			Payload:       replayed.Payload,
+			Status:        dto.DeliveryPending,
+			NextAttemptAt: time.Now(),
+			ReplayOf:      replayed.ID,
+		}
+		return tx.db.Create(&replay).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return replay.ToDto(), nil
+}
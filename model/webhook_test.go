@@ -0,0 +1,160 @@
+package model_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhooks(t *testing.T) {
+	_, gormDB := AccountDBInit(t)
+	defer func() {
+		otherDb, _ := gormDB.DB()
+		otherDb.Close()
+	}()
+	db := model.DB{GormDB: gormDB}
+	var webhookDB model.WebhookDB
+	require.NoError(t, webhookDB.Init(&db))
+	ctx := context.Background()
+
+	for _, webhook := range []dto.WebhookDto{
+		{URL: "ftp://localhost/hook", Events: []string{"art.created"}, Secret: "secret"},
+		{URL: "/hook", Events: []string{"art.created"}, Secret: "secret"},
+		{URL: "http://localhost/hook", Events: []string{"art.sold"}, Secret: "secret"},
+		{URL: "http://localhost/hook", Events: []string{}, Secret: "secret"},
+		{URL: "http://localhost/hook", Events: []string{"art.created"}},
+	} {
+		_, err := webhookDB.CreateWebhook(ctx, webhook)
+		assert.ErrorIs(t, err, model.ErrInvalidWebhook, webhook)
+	}
+
+	arts, err := webhookDB.CreateWebhook(ctx, dto.WebhookDto{URL: "http://localhost/arts", Events: []string{"art.created", "art.deleted"}, Secret: "secret"})
+	require.NoError(t, err)
+	accounts, err := webhookDB.CreateWebhook(ctx, dto.WebhookDto{URL: "https://localhost/accounts", Events: []string{"account.created"}, Secret: "secret"})
+	require.NoError(t, err)
+
+	t.Run("Update", func(t *testing.T) {
+		// the secret is kept unless a new one is given
+		updated, err := webhookDB.UpdateWebhook(ctx, dto.WebhookDto{Id: accounts.Id, URL: accounts.URL, Events: []string{"account.created", "art.created"}, Disabled: true})
+		if assert.NoError(t, err) {
+			assert.Equal(t, "secret", updated.Secret)
+			assert.True(t, updated.Disabled)
+		}
+		_, err = webhookDB.UpdateWebhook(ctx, dto.WebhookDto{Id: accounts.Id, URL: "nonsense", Events: accounts.Events})
+		assert.ErrorIs(t, err, model.ErrInvalidWebhook)
+		_, err = webhookDB.UpdateWebhook(ctx, dto.WebhookDto{Id: 1000, URL: accounts.URL, Events: accounts.Events})
+		assert.ErrorIs(t, err, model.ErrNotFound)
+
+		webhooks, err := webhookDB.GetWebhooks(ctx)
+		if assert.NoError(t, err) && assert.Len(t, webhooks, 2) {
+			assert.Equal(t, []string{"account.created", "art.created"}, webhooks[1].Events)
+		}
+	})
+
+	t.Run("Queue", func(t *testing.T) {
+		// disabled webhooks and webhooks of other events get nothing
+		queued, err := webhookDB.EnqueueDeliveries(ctx, "art.created", []byte(`{"id": 1}`))
+		if assert.NoError(t, err) && assert.Len(t, queued, 1) {
+			assert.Equal(t, arts.Id, queued[0].WebhookId)
+			assert.Equal(t, dto.DeliveryPending, queued[0].Status)
+		}
+		queued, err = webhookDB.EnqueueDeliveries(ctx, "art.updated", []byte(`{"id": 1}`))
+		if assert.NoError(t, err) {
+			assert.Empty(t, queued)
+		}
+		_, err = webhookDB.EnqueueDeliveries(ctx, "art.deleted", []byte(`{"id": 2}`))
+		require.NoError(t, err)
+
+		// claimed deliveries aren't due again until the lease is over
+		now := time.Now()
+		claimed, err := webhookDB.ClaimDueDeliveries(ctx, now, time.Minute, 1)
+		if assert.NoError(t, err) && assert.Len(t, claimed, 1) {
+			assert.JSONEq(t, `{"id": 1}`, string(claimed[0].Payload))
+		}
+		claimed, err = webhookDB.ClaimDueDeliveries(ctx, now, time.Minute, 10)
+		if assert.NoError(t, err) && assert.Len(t, claimed, 1) {
+			assert.Equal(t, "art.deleted", claimed[0].Event)
+		}
+		claimed, err = webhookDB.ClaimDueDeliveries(ctx, now, time.Minute, 10)
+		if assert.NoError(t, err) {
+			assert.Empty(t, claimed)
+		}
+		claimed, err = webhookDB.ClaimDueDeliveries(ctx, now.Add(2*time.Minute), time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, claimed, 2)
+
+		failed := claimed[0]
+		failed.Status = dto.DeliveryFailed
+		failed.Attempts = 8
+		failed.ResponseStatus = 500
+		failed.Error = "webhook responded with 500 Internal Server Error"
+		_, err = webhookDB.RecordAttempt(ctx, failed)
+		require.NoError(t, err)
+
+		delivered := claimed[1]
+		deliveredAt := time.Now()
+		delivered.Status = dto.DeliveryDelivered
+		delivered.Attempts = 1
+		delivered.ResponseStatus = 204
+		delivered.DeliveredAt = &deliveredAt
+		_, err = webhookDB.RecordAttempt(ctx, delivered)
+		require.NoError(t, err)
+
+		claimed, err = webhookDB.ClaimDueDeliveries(ctx, now.Add(time.Hour), time.Minute, 10)
+		if assert.NoError(t, err) {
+			assert.Empty(t, claimed)
+		}
+
+		deliveries, err := webhookDB.GetDeliveries(ctx, arts.Id, 0, 0)
+		if assert.NoError(t, err) && assert.Len(t, deliveries, 2) {
+			assert.Equal(t, dto.DeliveryDelivered, deliveries[0].Status)
+			assert.NotNil(t, deliveries[0].DeliveredAt)
+			assert.Equal(t, dto.DeliveryFailed, deliveries[1].Status)
+			assert.Equal(t, 500, deliveries[1].ResponseStatus)
+			assert.Equal(t, failed.Error, deliveries[1].Error)
+		}
+		deliveries, err = webhookDB.GetDeliveries(ctx, arts.Id, delivered.Id, 1)
+		if assert.NoError(t, err) && assert.Len(t, deliveries, 1) {
+			assert.Equal(t, failed.Id, deliveries[0].Id)
+		}
+		_, err = webhookDB.GetDeliveries(ctx, 1000, 0, 0)
+		assert.ErrorIs(t, err, model.ErrNotFound)
+
+		// a replay is a new pending delivery
+		replay, err := webhookDB.ReplayDelivery(ctx, arts.Id, failed.Id)
+		if assert.NoError(t, err) {
+			assert.Equal(t, failed.Id, replay.ReplayOf)
+			assert.Equal(t, dto.DeliveryPending, replay.Status)
+			assert.Zero(t, replay.Attempts)
+			assert.JSONEq(t, string(failed.Payload), string(replay.Payload))
+		}
+		claimed, err = webhookDB.ClaimDueDeliveries(ctx, time.Now(), time.Minute, 10)
+		if assert.NoError(t, err) && assert.Len(t, claimed, 1) {
+			assert.Equal(t, replay.Id, claimed[0].Id)
+		}
+		_, err = webhookDB.ReplayDelivery(ctx, accounts.Id, failed.Id)
+		assert.ErrorIs(t, err, model.ErrNotFound)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		deleted, err := webhookDB.DeleteWebhook(ctx, arts.Id)
+		if assert.NoError(t, err) {
+			assert.Equal(t, arts.URL, deleted.URL)
+		}
+		_, err = webhookDB.GetWebhook(ctx, arts.Id)
+		assert.ErrorIs(t, err, model.ErrNotFound)
+		_, err = webhookDB.DeleteWebhook(ctx, arts.Id)
+		assert.ErrorIs(t, err, model.ErrNotFound)
+
+		// the deliveries went away with it
+		claimed, err := webhookDB.ClaimDueDeliveries(ctx, time.Now().Add(time.Hour), time.Minute, 10)
+		if assert.NoError(t, err) {
+			assert.Empty(t, claimed)
+		}
+	})
+}
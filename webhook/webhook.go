@@ -0,0 +1,250 @@
+// Package webhook posts the events of the gallery to the URLs subscribed to them.
+// Events are queued in a model.WebhookRepository in the transaction of the operation causing them, and a Dispatcher
+// sends the queued deliveries, signed with the secret of their webhook, retrying failed ones with an exponential backoff.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/events"
+	"github.com/nafiz1001/gallery-go/model"
+)
+
+// Headers of a delivery.
+const (
+	EventHeader     = "X-Gallery-Event"
+	DeliveryHeader  = "X-Gallery-Delivery"
+	SignatureHeader = "X-Gallery-Signature"
+)
+
+const (
+	// Deliveries claimed from the queue at a time.
+	batchSize = 20
+	// Longest wait between two attempts of a delivery.
+	maxRetryDelay = time.Hour
+	// Bytes of a response read before the connection is dropped.
+	maxResponseBytes = 64 << 10
+)
+
+// Body posted to a webhook.
+type Payload struct {
+	Type    string               `json:"type"`
+	Time    time.Time            `json:"time"`
+	ActorId uint                 `json:"actor_id"`
+	Art     *dto.PublishedArtDto `json:"art,omitempty"`
+	Comment *dto.CommentDto      `json:"comment,omitempty"`
+	Account *dto.AccountDto      `json:"account,omitempty"`
+}
+
+// The signature of body sent in SignatureHeader: the hex-encoded HMAC-SHA256 of body keyed by secret, prefixed with "sha256=".
+// Receivers should compute it themselves and compare both with hmac.Equal.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Queues the events of the gallery and sends them to their webhooks.
+type Dispatcher struct {
+	// Client the deliveries are posted with, one with a 10 second timeout if nil.
+	Client *http.Client
+	// Interval between two polls of the queue, 1 second if zero.
+	PollInterval time.Duration
+	// Attempts after which a delivery is given up, 8 if zero.
+	MaxAttempts int
+	// Wait before the first retry, doubled for every later one up to an hour, 30 seconds if zero.
+	RetryDelay time.Duration
+
+	webhookDB model.WebhookRepository
+	// Signals Run that deliveries were queued.
+	wake chan struct{}
+}
+
+func (d *Dispatcher) Init(webhookDB model.WebhookRepository) error {
+	d.webhookDB = webhookDB
+
+	if d.Client == nil {
+		d.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if d.PollInterval == 0 {
+		d.PollInterval = time.Second
+	}
+	if d.MaxAttempts == 0 {
+		d.MaxAttempts = 8
+	}
+	if d.RetryDelay == 0 {
+		d.RetryDelay = 30 * time.Second
+	}
+	d.wake = make(chan struct{}, 1)
+
+	return nil
+}
+
+// Queues event in tx for every webhook subscribed to it, so that its deliveries are queued if and only if the operation
+// causing it is committed. It is meant to be a recorder of the outbox of the gallery, see Notify.
+func (d *Dispatcher) Enqueue(ctx context.Context, tx model.Repositories, event events.Event) error {
+	payload := Payload{
+		Type:    event.Type,
+		Time:    event.Time,
+		ActorId: event.ActorId,
+		Comment: event.Comment,
+	}
+	if event.Art != nil {
+		art := dto.PublishedArt(*event.Art)
+		payload.Art = &art
+	}
+	if event.Account != nil {
+		account := *event.Account
+		account.Password = ""
+		payload.Account = &account
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Webhooks.EnqueueDeliveries(ctx, event.Type, body)
+	return err
+}
+
+// Wakes Run up once an event is committed, since its deliveries may be due. It is meant to be subscribed to the
+// events.Bus of the gallery, along with Enqueue recording the events.
+func (d *Dispatcher) Notify(ctx context.Context, event events.Event) {
+	d.Wake()
+}
+
+// Makes Run look for due deliveries without waiting for the next poll.
+func (d *Dispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Sends the due deliveries until ctx is done, polling the queue every PollInterval.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			attempted, err := d.DeliverDue(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("webhook: %s", err)
+			}
+			if err != nil || attempted < batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// Attempts a batch of the deliveries that are due and returns how many were attempted.
+// A delivery is done once its webhook responds with a 2xx status, and is otherwise retried until MaxAttempts.
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	// long enough for the whole batch to time out before another dispatcher may claim it again
+	lease := time.Minute + batchSize*d.Client.Timeout
+	deliveries, err := d.webhookDB.ClaimDueDeliveries(ctx, time.Now(), lease, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	webhooks := map[uint]*dto.WebhookDto{}
+	for i, delivery := range deliveries {
+		webhook, ok := webhooks[delivery.WebhookId]
+		if !ok {
+			if webhook, err = d.webhookDB.GetWebhook(ctx, delivery.WebhookId); errors.Is(err, model.ErrNotFound) {
+				// deleted along with its deliveries since they were claimed
+				continue
+			} else if err != nil {
+				return i, err
+			}
+			webhooks[delivery.WebhookId] = webhook
+		}
+
+		if _, err := d.webhookDB.RecordAttempt(ctx, d.attempt(ctx, *webhook, delivery)); err != nil && !errors.Is(err, model.ErrNotFound) {
+			return i + 1, err
+		}
+	}
+	return len(deliveries), nil
+}
+
+// Posts delivery to webhook and returns it with the outcome of the attempt.
+func (d *Dispatcher) attempt(ctx context.Context, webhook dto.WebhookDto, delivery dto.WebhookDeliveryDto) dto.WebhookDeliveryDto {
+	delivery.Attempts++
+	delivery.ResponseStatus = 0
+	delivery.Error = ""
+
+	if status, err := d.post(ctx, webhook, delivery); err != nil {
+		delivery.ResponseStatus = status
+		delivery.Error = err.Error()
+		if delivery.Attempts >= d.MaxAttempts {
+			delivery.Status = dto.DeliveryFailed
+		} else {
+			delivery.NextAttemptAt = time.Now().Add(d.retryDelay(delivery.Attempts))
+		}
+	} else {
+		now := time.Now()
+		delivery.Status = dto.DeliveryDelivered
+		delivery.ResponseStatus = status
+		delivery.DeliveredAt = &now
+	}
+	return delivery
+}
+
+// Posts the payload of delivery to webhook and returns the status of the response, if any.
+func (d *Dispatcher) post(ctx context.Context, webhook dto.WebhookDto, delivery dto.WebhookDeliveryDto) (int, error) {
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("User-Agent", "gallery-webhook")
+	r.Header.Set(EventHeader, delivery.Event)
+	r.Header.Set(DeliveryHeader, strconv.FormatUint(uint64(delivery.Id), 10))
+	r.Header.Set(SignatureHeader, Sign(webhook.Secret, delivery.Payload))
+
+	resp, err := d.Client.Do(r)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Wait before the attempt following the given number of failed attempts.
+func (d *Dispatcher) retryDelay(attempts int) time.Duration {
+	delay := d.RetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
@@ -0,0 +1,245 @@
+package webhook_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nafiz1001/gallery-go/dto"
+	"github.com/nafiz1001/gallery-go/events"
+	"github.com/nafiz1001/gallery-go/model"
+	"github.com/nafiz1001/gallery-go/model/memory"
+	"github.com/nafiz1001/gallery-go/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A webhook receiver that records the deliveries it gets and responds with the next of its statuses.
+type receiver struct {
+	mu         sync.Mutex
+	statuses   []int
+	deliveries []*http.Request
+	bodies     [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	rc.deliveries = append(rc.deliveries, r)
+	rc.bodies = append(rc.bodies, body)
+
+	status := http.StatusNoContent
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func newDispatcher(t *testing.T, dispatcher *webhook.Dispatcher) model.Repositories {
+	memoryDB := &memory.DB{}
+	require.NoError(t, memoryDB.Init())
+	repos, err := memoryDB.Repositories()
+	require.NoError(t, err)
+	require.NoError(t, dispatcher.Init(repos.Webhooks))
+	return repos
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '{"type":"art.created"}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "sha256=62584859b2366095a7c5cc887bc95b8db67662c69b6ad02edc55e56960dcefa1", webhook.Sign("secret", []byte(`{"type":"art.created"}`)))
+}
+
+func TestDelivery(t *testing.T) {
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	dispatcher := &webhook.Dispatcher{}
+	repos := newDispatcher(t, dispatcher)
+	ctx := context.Background()
+	hook, err := repos.Webhooks.CreateWebhook(ctx, dto.WebhookDto{URL: server.URL, Events: []string{events.ArtCreated, events.AccountCreated}, Secret: "secret"})
+	require.NoError(t, err)
+
+	// likes depend on who asks, so they are left out of the payloads
+	art := &dto.ArtDto{Id: 3, Title: "title", Quantity: 2, AuthorId: 1, LikeCount: 5, LikedByMe: true}
+	require.NoError(t, dispatcher.Enqueue(ctx, repos, events.Event{Type: events.ArtCreated, ActorId: 1, Art: art, Time: time.Now()}))
+	// the webhook isn't subscribed to updates
+	require.NoError(t, dispatcher.Enqueue(ctx, repos, events.Event{Type: events.ArtUpdated, ActorId: 1, Art: art, Time: time.Now()}))
+	require.NoError(t, dispatcher.Enqueue(ctx, repos, events.Event{Type: events.AccountCreated, ActorId: 2, Account: &dto.AccountDto{Id: 2, Username: "user", Password: "password"}, Time: time.Now()}))
+	// and operations that are rolled back queue nothing
+	err = repos.Transaction(ctx, func(tx model.Repositories) error {
+		require.NoError(t, dispatcher.Enqueue(ctx, tx, events.Event{Type: events.ArtCreated, ActorId: 1, Art: art, Time: time.Now()}))
+		return errors.New("rolled back")
+	})
+	require.Error(t, err)
+
+	attempted, err := dispatcher.DeliverDue(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, attempted)
+	require.Len(t, rc.deliveries, 2)
+
+	var payload webhook.Payload
+	r, body := rc.deliveries[0], rc.bodies[0]
+	assert.Equal(t, http.MethodPost, r.Method)
+	assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+	assert.Equal(t, events.ArtCreated, r.Header.Get(webhook.EventHeader))
+	assert.True(t, hmac.Equal([]byte(webhook.Sign("secret", body)), []byte(r.Header.Get(webhook.SignatureHeader))))
+	if assert.NoError(t, json.Unmarshal(body, &payload)) && assert.NotNil(t, payload.Art) {
+		assert.Equal(t, events.ArtCreated, payload.Type)
+		assert.Equal(t, dto.PublishedArt(*art), *payload.Art)
+		assert.NotContains(t, string(body), "liked_by_me")
+	}
+
+	// passwords are never sent
+	payload = webhook.Payload{}
+	if assert.NoError(t, json.Unmarshal(rc.bodies[1], &payload)) && assert.NotNil(t, payload.Account) {
+		assert.Equal(t, "user", payload.Account.Username)
+		assert.Empty(t, payload.Account.Password)
+	}
+
+	deliveries, err := repos.Webhooks.GetDeliveries(ctx, hook.Id, 0, 0)
+	if assert.NoError(t, err) && assert.Len(t, deliveries, 2) {
+		for _, delivery := range deliveries {
+			assert.Equal(t, dto.DeliveryDelivered, delivery.Status)
+			assert.Equal(t, 1, delivery.Attempts)
+			assert.Equal(t, http.StatusNoContent, delivery.ResponseStatus)
+		}
+		assert.Equal(t, fmt.Sprint(deliveries[1].Id), rc.deliveries[0].Header.Get(webhook.DeliveryHeader))
+	}
+
+	// nothing is due anymore
+	attempted, err = dispatcher.DeliverDue(ctx)
+	if assert.NoError(t, err) {
+		assert.Zero(t, attempted)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	rc := &receiver{statuses: []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK, http.StatusGone}}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	dispatcher := &webhook.Dispatcher{MaxAttempts: 3, RetryDelay: 10 * time.Millisecond}
+	repos := newDispatcher(t, dispatcher)
+	ctx := context.Background()
+	hook, err := repos.Webhooks.CreateWebhook(ctx, dto.WebhookDto{URL: server.URL, Events: []string{events.ArtDeleted}, Secret: "secret"})
+	require.NoError(t, err)
+
+	getDelivery := func(id uint) dto.WebhookDeliveryDto {
+		deliveries, err := repos.Webhooks.GetDeliveries(ctx, hook.Id, id+1, 1)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		return deliveries[0]
+	}
+
+	require.NoError(t, dispatcher.Enqueue(ctx, repos, events.Event{Type: events.ArtDeleted, ActorId: 1, Art: &dto.ArtDto{Id: 1}}))
+	_, err = dispatcher.DeliverDue(ctx)
+	require.NoError(t, err)
+	delivery := getDelivery(1)
+	assert.Equal(t, dto.DeliveryPending, delivery.Status)
+	assert.Equal(t, http.StatusInternalServerError, delivery.ResponseStatus)
+	assert.Contains(t, delivery.Error, "500")
+
+	// the retry isn't due right away, and the wait doubles after every attempt
+	attempted, err := dispatcher.DeliverDue(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, attempted)
+	time.Sleep(15 * time.Millisecond)
+	attemptedAt := time.Now()
+	_, err = dispatcher.DeliverDue(ctx)
+	require.NoError(t, err)
+	delivery = getDelivery(1)
+	assert.Equal(t, 2, delivery.Attempts)
+	assert.False(t, delivery.NextAttemptAt.Before(attemptedAt.Add(20*time.Millisecond)))
+
+	time.Sleep(25 * time.Millisecond)
+	_, err = dispatcher.DeliverDue(ctx)
+	require.NoError(t, err)
+	delivery = getDelivery(1)
+	assert.Equal(t, dto.DeliveryDelivered, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Empty(t, delivery.Error)
+
+	// a delivery is given up after MaxAttempts, unless it is replayed
+	rc.statuses = []int{http.StatusGone, http.StatusGone, http.StatusGone, http.StatusOK}
+	require.NoError(t, dispatcher.Enqueue(ctx, repos, events.Event{Type: events.ArtDeleted, ActorId: 1, Art: &dto.ArtDto{Id: 2}}))
+	for i := 0; i < 3; i++ {
+		_, err = dispatcher.DeliverDue(ctx)
+		require.NoError(t, err)
+		time.Sleep(45 * time.Millisecond)
+	}
+	delivery = getDelivery(2)
+	assert.Equal(t, dto.DeliveryFailed, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+
+	replay, err := repos.Webhooks.ReplayDelivery(ctx, hook.Id, delivery.Id)
+	require.NoError(t, err)
+	_, err = dispatcher.DeliverDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, dto.DeliveryDelivered, getDelivery(replay.Id).Status)
+	assert.Len(t, rc.deliveries, 7)
+}
+
+func TestUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	dispatcher := &webhook.Dispatcher{}
+	repos := newDispatcher(t, dispatcher)
+	ctx := context.Background()
+	hook, err := repos.Webhooks.CreateWebhook(ctx, dto.WebhookDto{URL: url, Events: []string{events.ArtCreated}, Secret: "secret"})
+	require.NoError(t, err)
+
+	require.NoError(t, dispatcher.Enqueue(ctx, repos, events.Event{Type: events.ArtCreated, ActorId: 1, Art: &dto.ArtDto{Id: 1}}))
+	_, err = dispatcher.DeliverDue(ctx)
+	require.NoError(t, err)
+
+	deliveries, err := repos.Webhooks.GetDeliveries(ctx, hook.Id, 0, 0)
+	if assert.NoError(t, err) && assert.Len(t, deliveries, 1) {
+		assert.Equal(t, dto.DeliveryPending, deliveries[0].Status)
+		assert.Zero(t, deliveries[0].ResponseStatus)
+		assert.NotEmpty(t, deliveries[0].Error)
+		assert.True(t, deliveries[0].NextAttemptAt.After(time.Now().Add(20*time.Second)))
+	}
+}
+
+func TestRun(t *testing.T) {
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	// the poll interval is long enough that only waking the dispatcher delivers in time
+	dispatcher := &webhook.Dispatcher{PollInterval: time.Hour}
+	repos := newDispatcher(t, dispatcher)
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := repos.Webhooks.CreateWebhook(ctx, dto.WebhookDto{URL: server.URL, Events: []string{events.ArtCreated}, Secret: "secret"})
+	require.NoError(t, err)
+
+	done := make(chan error)
+	go func() { done <- dispatcher.Run(ctx) }()
+
+	bus := &events.Bus{}
+	bus.Subscribe(dispatcher.Notify)
+	event := events.Event{Type: events.ArtCreated, ActorId: 1, Art: &dto.ArtDto{Id: 1}}
+	require.NoError(t, dispatcher.Enqueue(ctx, repos, event))
+	bus.Publish(ctx, event)
+	assert.Eventually(t, func() bool {
+		rc.mu.Lock()
+		defer rc.mu.Unlock()
+		return len(rc.deliveries) == 1
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}